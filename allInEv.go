@@ -0,0 +1,278 @@
+/*
+ * Copyright 2011 Colin Patrick McCabe
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, version 2.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+)
+
+/* When players are all-in before the river, what they go on to win depends
+ * on the cards still to come. All-in adjusted winnings replace what they
+ * actually won in those hands with what they would win on average: their
+ * equity in each pot at the moment they were all-in. Comparing the two
+ * separates the luck of the cards from the decisions made.
+ */
+
+/* A player in an all-in hand. Expected is what they would collect on
+ * average, and Equity is that as a fraction of the whole pot.
+ */
+type AllInPlayer struct {
+	Name string
+	Hole CardSlice
+	Invested int64
+	Collected int64
+	Expected float64
+	Equity float64
+}
+
+type AllInHand struct {
+	Hand *HandHistory
+	Board CardSlice
+	Pot int64
+	Players []*AllInPlayer
+}
+
+/* Returns each player's equity in a pot contested by the players in elig.
+ * The equities are worked out the same way as when poker-odds is given
+ * known opponents with -o.
+ */
+func allInEquities(hh *HandHistory, elig []int, board CardSlice,
+		deck *CardBag) ([]float64, error) {
+	ret := make([]float64, len(elig))
+	if (len(elig) == 1) {
+		ret[0] = 1.0
+		return ret, nil
+	}
+	for i := range(elig) {
+		sc := &Scenario { Game: GAME_HOLDEM, Hole: hh.Players[elig[i]].Hole,
+			Board: board }
+		for j := range(elig) {
+			if (j != i) {
+				sc.Opponents = append(sc.Opponents, hh.Players[elig[j]].Hole)
+			}
+		}
+		eq, err := CalcEquity(sc, 0, deck)
+		if (err != nil) {
+			return nil, err
+		}
+		ret[i] = eq.Value()
+	}
+	return ret, nil
+}
+
+/* Work out the all-in equities for a hand. Returns nil if the players
+ * weren't all-in before the river, or if we didn't see all of their cards.
+ *
+ * The pot is divided into a main pot and side pots, just as at showdown, and
+ * each player's equity in each pot is against the others who can win it.
+ * If the pot was raked, what each player expects to collect is scaled down
+ * to match.
+ */
+func CalcAllInEv(hh *HandHistory, deck *CardBag) (*AllInHand, error) {
+	if ((hh.AllInStreet == -1) || (hh.AllInBoard >= BOARD_MAX)) {
+		return nil, nil
+	}
+	res := &AllInHand { Hand: hh, Board: hh.Board[:hh.AllInBoard] }
+	sd := &showdownTable {}
+	collected := int64(0)
+	for i := range(hh.Players) {
+		p := hh.Players[i]
+		if ((!p.Folded) && (p.Hole == nil)) {
+			return nil, nil
+		}
+		sd.players = append(sd.players, ShowdownPlayer { Name: p.Name,
+			Contribution: p.Invested, Folded: p.Folded })
+		res.Players = append(res.Players, &AllInPlayer { Name: p.Name,
+			Hole: p.Hole, Invested: p.Invested, Collected: p.Collected })
+		res.Pot += p.Invested
+		collected += p.Collected
+	}
+	/* A hand where nobody collected anything must have been cut short. */
+	if ((res.Pot == 0) || (collected == 0)) {
+		return nil, nil
+	}
+	rake := float64(collected) / float64(res.Pot)
+	pots, eligible := sd.makePots()
+	for i := range(pots) {
+		eqs, err := allInEquities(hh, eligible[i], res.Board, deck)
+		if (err != nil) {
+			return nil, fmt.Errorf("hand #%s: %s", hh.Id, err.Error())
+		}
+		for j := range(eligible[i]) {
+			p := res.Players[eligible[i][j]]
+			p.Expected += eqs[j] * float64(pots[i].Amount) * rake
+		}
+	}
+	for i := range(res.Players) {
+		res.Players[i].Equity = res.Players[i].Expected / float64(res.Pot) /
+			rake
+	}
+	return res, nil
+}
+
+func (ah *AllInHand) String() string {
+	ret := fmt.Sprintf("hand #%s: all-in preflop", ah.Hand.Id)
+	if (len(ah.Board) > 0) {
+		ret = fmt.Sprintf("hand #%s: all-in on the %s, %s", ah.Hand.Id,
+			StreetToStr(ah.Hand.AllInStreet), ah.Board.ShortString())
+	}
+	ret += fmt.Sprintf(", pot %s\n", hhAmountToStr(float64(ah.Pot)))
+	for i := range(ah.Players) {
+		p := ah.Players[i]
+		if (p.Hole == nil) {
+			continue
+		}
+		ret += fmt.Sprintf("  %-16s %-6s equity %6.2f%%  won %10s  " +
+			"expected %10s\n", p.Name, p.Hole.ShortString(), p.Equity * 100.0,
+			hhAmountToStr(float64(p.Collected)), hhAmountToStr(p.Expected))
+	}
+	return ret
+}
+
+/* Everything one player did across a set of hands. Net is what they
+ * actually won, and AllInNet is their all-in adjusted winnings.
+ */
+type HHPlayerTotals struct {
+	Name string
+	Hands int
+	AllIns int
+	Net int64
+	AllInNet float64
+}
+
+type AllInReport struct {
+	Hands int
+	AllIns []*AllInHand
+	Players []*HHPlayerTotals
+}
+
+func NewAllInReport(hands []*HandHistory) (*AllInReport, error) {
+	rep := &AllInReport { Hands: len(hands) }
+	deck := Make52CardBag()
+	byName := make(map[string] *HHPlayerTotals)
+	for h := range(hands) {
+		hh := hands[h]
+		ah, err := CalcAllInEv(hh, deck)
+		if (err != nil) {
+			return nil, err
+		}
+		if (ah != nil) {
+			rep.AllIns = append(rep.AllIns, ah)
+		}
+		for i := range(hh.Players) {
+			p := hh.Players[i]
+			tot := byName[p.Name]
+			if (tot == nil) {
+				tot = &HHPlayerTotals { Name: p.Name }
+				byName[p.Name] = tot
+				rep.Players = append(rep.Players, tot)
+			}
+			tot.Hands++
+			tot.Net += p.Net()
+			if ((ah != nil) && (!p.Folded)) {
+				tot.AllIns++
+				tot.AllInNet += ah.Players[i].Expected -
+					float64(p.Invested)
+			} else {
+				tot.AllInNet += float64(p.Net())
+			}
+		}
+	}
+	return rep, nil
+}
+
+func (rep *AllInReport) String(verbose bool) string {
+	ret := ""
+	if (verbose) {
+		for i := range(rep.AllIns) {
+			ret += rep.AllIns[i].String()
+		}
+		ret += "\n"
+	}
+	ret += fmt.Sprintf("%d hands, %d all-in before the river\n", rep.Hands,
+		len(rep.AllIns))
+	ret += fmt.Sprintf("%-16s %6s %8s %12s %12s %12s\n", "player", "hands",
+		"all-ins", "won", "all-in ev", "luck")
+	for i := range(rep.Players) {
+		p := rep.Players[i]
+		ret += fmt.Sprintf("%-16s %6d %8d %12s %12s %12s\n", p.Name, p.Hands,
+			p.AllIns, hhAmountToStr(float64(p.Net)),
+			hhAmountToStr(p.AllInNet),
+			hhAmountToStr(float64(p.Net) - p.AllInNet))
+	}
+	return ret
+}
+
+/* Read and parse each hand history file. */
+func loadHandHistories(files []string) ([]*HandHistory, error) {
+	var ret []*HandHistory
+	for i := range(files) {
+		f, err := os.Open(files[i])
+		if (err != nil) {
+			return nil, err
+		}
+		hands, err := ParseHandHistories(f)
+		f.Close()
+		if (err != nil) {
+			return nil, fmt.Errorf("%s: %s", files[i], err.Error())
+		}
+		ret = append(ret, hands...)
+	}
+	return ret, nil
+}
+
+func historyUsage(fs *flag.FlagSet) func() {
+	return func() {
+		fmt.Fprintf(os.Stderr,
+`%s history: compare what players won with their all-in equity.
+
+Usage:
+%s history [options] [file] [file] ...
+
+Reads hand histories written by PokerStars. For each hand where players
+were all-in before the river, and showed their cards, their equity at the
+moment they were all-in is worked out. Each player's actual winnings are
+then shown next to their all-in adjusted winnings, which use that equity in
+place of what they won in those hands. The difference is luck.
+
+Options:
+`, os.Args[0], os.Args[0])
+		fs.PrintDefaults()
+	}
+}
+
+func historyMain(args []string) {
+	fs := flag.NewFlagSet("history", flag.ExitOnError)
+	fs.Usage = historyUsage(fs)
+	var verbose = fs.Bool("v", false, "show each all-in hand")
+	fs.Parse(args)
+	if (fs.NArg() < 1) {
+		fs.Usage()
+		os.Exit(1)
+	}
+	hands, err := loadHandHistories(fs.Args())
+	if (err != nil) {
+		die(err)
+	}
+	rep, err := NewAllInReport(hands)
+	if (err != nil) {
+		die(err)
+	}
+	fmt.Printf("%s", rep.String(*verbose))
+}
@@ -0,0 +1,244 @@
+/*
+ * Copyright 2011 Colin Patrick McCabe
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, version 2.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+/* Batch mode reads one scenario per line and writes one result per line, in
+ * the same order.
+ *
+ * A line is either a JSON OddsRequest, or text of the form
+ *     hole | board | opponent | opponent ...
+ * where everything after the hole cards is optional. For example:
+ *     KS QS | AS 3S 5S
+ *
+ * Text lines get text results, and JSON lines get JSON results. Blank lines
+ * and lines starting with '#' are copied through unchanged.
+ */
+
+type batchJob struct {
+	idx int
+	line string
+}
+
+type batchResult struct {
+	idx int
+	out string
+}
+
+type BatchResponse struct {
+	Line int `json:"line"`
+	Error string `json:"error,omitempty"`
+	*OddsResponse
+}
+
+func isBatchJson(line string) bool {
+	return strings.HasPrefix(line, "{")
+}
+
+func parseBatchText(line string) (*OddsRequest, error) {
+	fields := strings.Split(line, "|")
+	req := &OddsRequest { Hole: strings.TrimSpace(fields[0]) }
+	if (len(fields) > 1) {
+		req.Board = strings.TrimSpace(fields[1])
+	}
+	for i := 2; i < len(fields); i++ {
+		req.Opponents = append(req.Opponents, strings.TrimSpace(fields[i]))
+	}
+	if (req.Hole == "") {
+		return nil, fmt.Errorf("no hole cards given.")
+	}
+	return req, nil
+}
+
+func parseBatchLine(line string) (*OddsRequest, error) {
+	if (!isBatchJson(line)) {
+		return parseBatchText(line)
+	}
+	req := new(OddsRequest)
+	dec := json.NewDecoder(strings.NewReader(line))
+	dec.DisallowUnknownFields()
+	err := dec.Decode(req)
+	if (err != nil) {
+		return nil, fmt.Errorf("unable to parse request: %s", err.Error())
+	}
+	return req, nil
+}
+
+/* Format the results for one line of input, in the same style as the input.
+ */
+func formatBatchResult(line string, lineNo int, resp *OddsResponse,
+		err error) string {
+	if (isBatchJson(line)) {
+		bresp := &BatchResponse { Line: lineNo, OddsResponse: resp }
+		if (err != nil) {
+			bresp.Error = err.Error()
+		}
+		buf, _ := json.Marshal(bresp)
+		return string(buf)
+	}
+	if (err != nil) {
+		return fmt.Sprintf("%s => error: %s", line, err.Error())
+	}
+	ret := line + " =>"
+	sep := " "
+	for i := range(resp.Results) {
+		ret += fmt.Sprintf("%s%03.2f%% %s", sep, resp.Results[i].Percent,
+			resp.Results[i].Hand)
+		sep = ", "
+	}
+	return ret
+}
+
+type BatchRunner struct {
+	deck *CardBag
+	numWorkers int
+	cache *ResultCache
+}
+
+func NewBatchRunner(numWorkers int, cache *ResultCache) *BatchRunner {
+	if (numWorkers < 1) {
+		numWorkers = 1
+	}
+	return &BatchRunner { Make52CardBag(), numWorkers, cache }
+}
+
+func (br *BatchRunner) runLine(ctx context.Context, csp *CardSliceProcessor,
+		line string, lineNo int) string {
+	req, err := parseBatchLine(line)
+	if (err != nil) {
+		return formatBatchResult(line, lineNo, nil, err)
+	}
+	sc, err := req.Scenario()
+	if (err != nil) {
+		return formatBatchResult(line, lineNo, nil, err)
+	}
+	key := sc.CanonicalKey()
+	resp, ok := br.cache.Get(key)
+	if (!ok) {
+		var res *ResultSet
+		res, err = CalcOddsSerial(ctx, sc, br.deck, csp)
+		if (err != nil) {
+			return formatBatchResult(line, lineNo, nil, err)
+		}
+		resp = NewOddsResponse(sc, res)
+		err = br.cache.Put(key, resp)
+		if (err != nil) {
+			return formatBatchResult(line, lineNo, nil, err)
+		}
+	}
+	return formatBatchResult(line, lineNo, resp, nil)
+}
+
+func (br *BatchRunner) worker(ctx context.Context, jobs chan *batchJob,
+		results chan *batchResult) {
+	csp := NewCardSliceProcessor(nil)
+	for job := range(jobs) {
+		line := strings.TrimSpace(job.line)
+		out := job.line
+		if ((line != "") && (!strings.HasPrefix(line, "#"))) {
+			out = br.runLine(ctx, csp, line, job.idx + 1)
+		}
+		results <- &batchResult { job.idx, out }
+	}
+}
+
+/* Run every scenario in 'in', writing the results to 'out'. Scenarios are
+ * calculated in parallel, but the results are written in input order as soon
+ * as they are ready.
+ */
+func (br *BatchRunner) Run(ctx context.Context, in io.Reader,
+		out io.Writer) error {
+	jobs := make(chan *batchJob, br.numWorkers)
+	results := make(chan *batchResult, br.numWorkers)
+	done := make(chan bool)
+	for i := 0; i < br.numWorkers; i++ {
+		go func() {
+			br.worker(ctx, jobs, results)
+			done <- true
+		}()
+	}
+
+	var readErr error
+	go func() {
+		scanner := bufio.NewScanner(in)
+		idx := 0
+		for scanner.Scan() {
+			jobs <- &batchJob { idx, scanner.Text() }
+			idx++
+		}
+		readErr = scanner.Err()
+		close(jobs)
+		for i := 0; i < br.numWorkers; i++ {
+			<-done
+		}
+		close(results)
+	}()
+
+	pending := make(map[int] string)
+	next := 0
+	var writeErr error
+	for res := range(results) {
+		pending[res.idx] = res.out
+		for {
+			str, ok := pending[next]
+			if (!ok) {
+				break
+			}
+			delete(pending, next)
+			next++
+			if (writeErr == nil) {
+				_, writeErr = fmt.Fprintln(out, str)
+			}
+		}
+	}
+	if (readErr != nil) {
+		return readErr
+	}
+	return writeErr
+}
+
+func batchMain(fileName string, numWorkers int, cacheSize int,
+		cacheFile string) {
+	in := os.Stdin
+	if (fileName != "-") {
+		var err error
+		in, err = os.Open(fileName)
+		if (err != nil) {
+			die(err)
+		}
+		defer in.Close()
+	}
+	cache, err := OpenResultCache(cacheSize, cacheFile)
+	if (err != nil) {
+		die(err)
+	}
+	defer cache.Close()
+	br := NewBatchRunner(numWorkers, cache)
+	err = br.Run(context.Background(), in, os.Stdout)
+	if (err != nil) {
+		die(err)
+	}
+}
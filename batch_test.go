@@ -0,0 +1,49 @@
+/*
+ * Copyright 2011 Colin Patrick McCabe
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, version 2.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestBatch1(t *testing.T) {
+	in := "KS QS | AS 3S 5S\n" +
+		"\n" +
+		"KD KC | KS 7H 5D 2C 9D\n" +
+		"KS QS | KS 3S 5S\n" +
+		`{"hole": "KS QS", "board": "AS 3S 5S 7D", "opponents": ["2S 4S"]}` + "\n"
+	var out bytes.Buffer
+	br := NewBatchRunner(3, NewResultCache(10))
+	err := br.Run(context.Background(), strings.NewReader(in), &out)
+	if (err != nil) {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	expected := "KS QS | AS 3S 5S => 99.81% a flush, 0.19% a straight flush\n" +
+		"\n" +
+		"KD KC | KS 7H 5D 2C 9D => 100.00% three of a kind\n" +
+		"KS QS | KS 3S 5S => error: The card K♠S appears more than once " +
+			"in your input! That is not possible.\n" +
+		`{"line":5,"mode":"exact","total":44,"results":[{"hand":"a flush",` +
+			`"count":44,"percent":100}],"text":"100.00% chance of a flush\n"}` +
+			"\n"
+	if (out.String() != expected) {
+		t.Errorf("expected:\n%s\ngot:\n%s\n", expected, out.String())
+	}
+}
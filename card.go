@@ -74,6 +74,12 @@ func (c *Card) String() string {
 	return fmt.Sprintf("%s%s", cardValToStr(c.val), suitToStr(c.suit))
 }
 
+/* Returns the card in the same format that StrToCard parses. */
+func (c *Card) ShortString() string {
+	s := suitToStr(c.suit)
+	return fmt.Sprintf("%s%s", cardValToStr(c.val), s[len(s)-1:])
+}
+
 /* It's important that the cards compare in this order. It makes detecting
  * straights easier because cards of a similar value (as opposed to suit) are
  * adjacent. Don't change this sort order without updating hand.go
@@ -191,9 +197,9 @@ func (arr CardSlice) CompareKicker(rhs CardSlice) int {
 		} else if (b < 0) {
 			return 1;
 		}
-		if (arr[a].val < arr[b].val) {
+		if (arr[a].val < rhs[b].val) {
 			return -1;
-		} else if (arr[a].val > arr[b].val) {
+		} else if (arr[a].val > rhs[b].val) {
 			return 1;
 		}
 		// ignore suit!
@@ -208,7 +214,7 @@ func (arr CardSlice) Identical(rhs CardSlice) bool {
 		return false
 	}
 	for i := range(arr) {
-		if ((arr[i].val != arr[i].val) || (arr[i].suit != arr[i].suit)) {
+		if ((arr[i].val != rhs[i].val) || (arr[i].suit != rhs[i].suit)) {
 			return false
 		}
 	}
@@ -246,6 +252,17 @@ func (arr CardSlice) String() (string) {
 	return ret
 }
 
+/* Returns the cards in the same format that StrToCards parses. */
+func (arr CardSlice) ShortString() (string) {
+	ret := ""
+	sep := ""
+	for i := range(arr) {
+		ret += sep + arr[i].ShortString()
+		sep = " "
+	}
+	return ret
+}
+
 // Could do this smarter if we knew that we were sorted...
 func (arr CardSlice) HasDuplicates() *Card {
 	for i := range(arr) {
@@ -65,6 +65,27 @@ func (bag *CardBag) Subtract(c *Card) {
 	bag.allCards = nextAllCards
 }
 
+/* Call fn once for every way of choosing k cards from the bag. The CardSlice
+ * passed to fn is reused between calls.
+ */
+func (bag *CardBag) ForEachSubset(k int, fn func(CardSlice)) {
+	if (k > bag.Len()) {
+		return
+	}
+	chooser := NewSubsetChooser(uint(bag.Len()), uint(k))
+	sub := make(CardSlice, k)
+	for ;; {
+		cur := chooser.Cur()
+		for i := range(cur) {
+			sub[i] = bag.allCards[cur[i]]
+		}
+		fn(sub)
+		if (!chooser.Next()) {
+			break
+		}
+	}
+}
+
 func (bag *CardBag) Get(num uint) *Card {
 	return bag.allCards[num]
 }
@@ -33,6 +33,15 @@ func NewCardSliceProcessor(base_ CardSlice) *CardSliceProcessor {
 	return ret
 }
 
+/* Start over with a new set of base cards and empty results. This lets one
+ * CardSliceProcessor be reused for many scenarios when it is driven directly
+ * through processSpread rather than by GoCardSliceProcessor.
+ */
+func (csp *CardSliceProcessor) Reset(base_ CardSlice) {
+	csp.base = base_.Copy()
+	csp.Results = ResultSet{}
+}
+
 func (csp *CardSliceProcessor) processSpread(spread CardSlice) {
 	setupChooser := NewSubsetChooser(SPREAD_MAX, HAND_SZ)
 	var tmpRes ResultSet
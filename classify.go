@@ -0,0 +1,474 @@
+/*
+ * Copyright 2011 Colin Patrick McCabe
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, version 2.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+/* The kinds of made hand that players talk about. These are finer-grained
+ * than the hand types in hand.go, since they take into account how our hole
+ * cards fit in with the board. They are ordered roughly from worst to best.
+ */
+const (
+	MADE_NOTHING = iota
+	MADE_OVERCARDS
+	MADE_ACE_HIGH
+	MADE_BOARD_PAIR
+	MADE_UNDERPAIR
+	MADE_BOTTOM_PAIR
+	MADE_MIDDLE_PAIR
+	MADE_POCKET_PAIR
+	MADE_TOP_PAIR
+	MADE_OVERPAIR
+	MADE_TWO_PAIR
+	MADE_TOP_TWO_PAIR
+	MADE_TRIPS
+	MADE_BOTTOM_SET
+	MADE_MIDDLE_SET
+	MADE_TOP_SET
+	MADE_STRAIGHT
+	MADE_FLUSH
+	MADE_FULL_HOUSE
+	MADE_FOUR_OF_A_KIND
+	MADE_STRAIGHT_FLUSH
+	MADE_PLAYING_BOARD
+	MAX_MADE
+)
+
+func madeToStr(m int) string {
+	switch (m) {
+	case MADE_NOTHING:
+		return "nothing"
+	case MADE_OVERCARDS:
+		return "two overcards"
+	case MADE_ACE_HIGH:
+		return "ace high"
+	case MADE_BOARD_PAIR:
+		return "only the board pair"
+	case MADE_UNDERPAIR:
+		return "underpair"
+	case MADE_BOTTOM_PAIR:
+		return "bottom pair"
+	case MADE_MIDDLE_PAIR:
+		return "middle pair"
+	case MADE_POCKET_PAIR:
+		return "pocket pair below the top card"
+	case MADE_TOP_PAIR:
+		return "top pair"
+	case MADE_OVERPAIR:
+		return "overpair"
+	case MADE_TWO_PAIR:
+		return "two pair"
+	case MADE_TOP_TWO_PAIR:
+		return "top two pair"
+	case MADE_TRIPS:
+		return "trips"
+	case MADE_BOTTOM_SET:
+		return "bottom set"
+	case MADE_MIDDLE_SET:
+		return "middle set"
+	case MADE_TOP_SET:
+		return "top set"
+	case MADE_STRAIGHT:
+		return "a straight"
+	case MADE_FLUSH:
+		return "a flush"
+	case MADE_FULL_HOUSE:
+		return "a full house"
+	case MADE_FOUR_OF_A_KIND:
+		return "four of a kind"
+	case MADE_STRAIGHT_FLUSH:
+		return "a straight flush"
+	case MADE_PLAYING_BOARD:
+		return "playing the board"
+	}
+	panic(fmt.Sprintf("unexpected made hand %d", m))
+}
+
+const (
+	KICKER_NONE = iota
+	KICKER_WEAK
+	KICKER_GOOD
+	KICKER_TOP
+)
+
+// A kicker this good or better is a good kicker.
+const GOOD_KICKER_VAL = 10
+
+// Draws. A hand may have several of these at once.
+const (
+	DRAW_FLUSH = 1 << iota
+	DRAW_NUT_FLUSH
+	DRAW_BACKDOOR_FLUSH
+	DRAW_OPEN_ENDED
+	DRAW_DOUBLE_GUTSHOT
+	DRAW_GUTSHOT
+)
+
+const DRAW_ANY_FLUSH = DRAW_FLUSH | DRAW_NUT_FLUSH
+const DRAW_ANY_STRAIGHT = DRAW_OPEN_ENDED | DRAW_DOUBLE_GUTSHOT | DRAW_GUTSHOT
+
+/* A HandClass describes our hand the way a player would: what we have made,
+ * how good our kicker is, and what we are drawing to.
+ */
+type HandClass struct {
+	Made int
+	Kicker int
+	Draws int
+}
+
+func (hc *HandClass) IsComboDraw() bool {
+	return ((hc.Draws & DRAW_ANY_FLUSH) != 0) &&
+		((hc.Draws & DRAW_ANY_STRAIGHT) != 0)
+}
+
+/* Returns true if we have neither a real made hand nor a real draw. Pairs
+ * which are only on the board, and backdoor draws, don't count.
+ */
+func (hc *HandClass) IsAir() bool {
+	return (hc.Made <= MADE_BOARD_PAIR) &&
+		((hc.Draws & (DRAW_ANY_FLUSH | DRAW_ANY_STRAIGHT)) == 0)
+}
+
+func (hc *HandClass) MadeString() string {
+	ret := madeToStr(hc.Made)
+	switch (hc.Kicker) {
+	case KICKER_WEAK:
+		ret += ", weak kicker"
+	case KICKER_GOOD:
+		ret += ", good kicker"
+	case KICKER_TOP:
+		ret += ", top kicker"
+	}
+	return ret
+}
+
+/* Returns the names of our draws, best first. */
+func (hc *HandClass) DrawStrings() []string {
+	var ret []string
+	if (hc.IsComboDraw()) {
+		ret = append(ret, "combo draw")
+	}
+	if ((hc.Draws & DRAW_NUT_FLUSH) != 0) {
+		ret = append(ret, "nut flush draw")
+	} else if ((hc.Draws & DRAW_FLUSH) != 0) {
+		ret = append(ret, "flush draw")
+	}
+	if ((hc.Draws & DRAW_OPEN_ENDED) != 0) {
+		ret = append(ret, "open-ended straight draw")
+	} else if ((hc.Draws & DRAW_DOUBLE_GUTSHOT) != 0) {
+		ret = append(ret, "double gutshot")
+	} else if ((hc.Draws & DRAW_GUTSHOT) != 0) {
+		ret = append(ret, "gutshot")
+	}
+	if ((hc.Draws & DRAW_BACKDOOR_FLUSH) != 0) {
+		ret = append(ret, "backdoor flush draw")
+	}
+	return ret
+}
+
+func (hc *HandClass) String() string {
+	draws := hc.DrawStrings()
+	if (len(draws) == 0) {
+		return hc.MadeString()
+	}
+	return hc.MadeString() + " + " + strings.Join(draws, ", ")
+}
+
+/* Returns the distinct values on the board, highest first. */
+func boardVals(board CardSlice) []int {
+	seen := make(map[int] bool)
+	var ret []int
+	for i := range(board) {
+		if (!seen[board[i].val]) {
+			seen[board[i].val] = true
+			ret = append(ret, board[i].val)
+		}
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(ret)))
+	return ret
+}
+
+func countVal(cards CardSlice, val int) int {
+	n := 0
+	for i := range(cards) {
+		if (cards[i].val == val) {
+			n++
+		}
+	}
+	return n
+}
+
+func indexOfVal(vals []int, val int) int {
+	for i := range(vals) {
+		if (vals[i] == val) {
+			return i
+		}
+	}
+	return -1
+}
+
+/* Returns the best kicker that anyone could have to go along with a pair of
+ * pairVal on this board.
+ */
+func bestKickerVal(board CardSlice, pairVal int) int {
+	for v := ACE_VAL; v >= 2; v-- {
+		if ((v != pairVal) && (countVal(board, v) == 0)) {
+			return v
+		}
+	}
+	return -1
+}
+
+func classifyKicker(board CardSlice, pairVal int, kickerVal int) int {
+	if (kickerVal == bestKickerVal(board, pairVal)) {
+		return KICKER_TOP
+	} else if (kickerVal >= GOOD_KICKER_VAL) {
+		return KICKER_GOOD
+	}
+	return KICKER_WEAK
+}
+
+/* Classify hands that are three of a kind or worse, by looking at how our
+ * hole cards match up with the board.
+ */
+func classifyPairs(hc *HandClass, hole CardSlice, board CardSlice) {
+	vals := boardVals(board)
+	top := vals[0]
+	bottom := vals[len(vals)-1]
+	a := hole[0].val
+	b := hole[1].val
+	if (a < b) {
+		a, b = b, a
+	}
+	if (a == b) {
+		idx := indexOfVal(vals, a)
+		switch {
+		case idx == 0:
+			hc.Made = MADE_TOP_SET
+		case idx == len(vals) - 1:
+			hc.Made = MADE_BOTTOM_SET
+		case idx > 0:
+			hc.Made = MADE_MIDDLE_SET
+		case a > top:
+			hc.Made = MADE_OVERPAIR
+		case a < bottom:
+			hc.Made = MADE_UNDERPAIR
+		default:
+			hc.Made = MADE_POCKET_PAIR
+		}
+		return
+	}
+	ia := indexOfVal(vals, a)
+	ib := indexOfVal(vals, b)
+	if ((ia >= 0) && (ib >= 0)) {
+		if ((ia <= 1) && (ib <= 1)) {
+			hc.Made = MADE_TOP_TWO_PAIR
+		} else {
+			hc.Made = MADE_TWO_PAIR
+		}
+		return
+	}
+	pairVal, kickerVal, idx := a, b, ia
+	if (ia < 0) {
+		pairVal, kickerVal, idx = b, a, ib
+	}
+	if (idx >= 0) {
+		if (countVal(board, pairVal) > 1) {
+			hc.Made = MADE_TRIPS
+			hc.Kicker = classifyKicker(board, pairVal, kickerVal)
+			return
+		}
+		switch {
+		case idx == 0:
+			hc.Made = MADE_TOP_PAIR
+			hc.Kicker = classifyKicker(board, pairVal, kickerVal)
+		case idx == len(vals) - 1:
+			hc.Made = MADE_BOTTOM_PAIR
+		default:
+			hc.Made = MADE_MIDDLE_PAIR
+		}
+		return
+	}
+	switch {
+	case len(vals) < len(board):
+		hc.Made = MADE_BOARD_PAIR
+	case b > top:
+		hc.Made = MADE_OVERCARDS
+	case a == ACE_VAL:
+		hc.Made = MADE_ACE_HIGH
+	default:
+		hc.Made = MADE_NOTHING
+	}
+}
+
+func suitCount(cards CardSlice, suit int) int {
+	n := 0
+	for i := range(cards) {
+		if (cards[i].suit == suit) {
+			n++
+		}
+	}
+	return n
+}
+
+/* Returns the highest value of the given suit which is not on the board. */
+func nutFlushVal(board CardSlice, suit int) int {
+	for v := ACE_VAL; v >= 2; v-- {
+		found := false
+		for i := range(board) {
+			if ((board[i].val == v) && (board[i].suit == suit)) {
+				found = true
+			}
+		}
+		if (!found) {
+			return v
+		}
+	}
+	return -1
+}
+
+func classifyFlushDraws(hc *HandClass, hole CardSlice, board CardSlice) {
+	for suit := DIAMONDS; suit <= SPADES; suit++ {
+		inHole := suitCount(hole, suit)
+		if (inHole == 0) {
+			continue
+		}
+		n := inHole + suitCount(board, suit)
+		if (n == 4) {
+			hc.Draws |= DRAW_FLUSH
+			nut := nutFlushVal(board, suit)
+			for i := range(hole) {
+				if ((hole[i].suit == suit) && (hole[i].val == nut)) {
+					hc.Draws |= DRAW_NUT_FLUSH
+				}
+			}
+		} else if ((n == 3) && (len(board) == 3)) {
+			hc.Draws |= DRAW_BACKDOOR_FLUSH
+		}
+	}
+}
+
+/* Returns a bitmap of the values present in cards. Aces are also counted as
+ * having a value of 1, so that they play low in straights.
+ */
+func valBits(cards CardSlice) int {
+	bits := 0
+	for i := range(cards) {
+		bits |= 1 << uint(cards[i].val)
+		if (cards[i].val == ACE_VAL) {
+			bits |= 1 << 1
+		}
+	}
+	return bits
+}
+
+func addValBit(bits int, val int) int {
+	bits |= 1 << uint(val)
+	if (val == ACE_VAL) {
+		bits |= 1 << 1
+	}
+	return bits
+}
+
+/* Returns the high value of the best straight in the given value bitmap, or
+ * -1 if there is no straight.
+ */
+func straightHigh(bits int) int {
+	for hi := ACE_VAL; hi >= 5; hi-- {
+		run := 0x1f << uint(hi - 4)
+		if ((bits & run) == run) {
+			return hi
+		}
+	}
+	return -1
+}
+
+func classifyStraightDraws(hc *HandClass, hole CardSlice, board CardSlice) {
+	bits := valBits(hole) | valBits(board)
+	boardBits := valBits(board)
+	completers := 0
+	for v := 2; v <= ACE_VAL; v++ {
+		hi := straightHigh(addValBit(bits, v))
+		if (hi == -1) {
+			continue
+		}
+		// Straights that are entirely on the board don't count.
+		if (straightHigh(addValBit(boardBits, v)) == hi) {
+			continue
+		}
+		completers++
+	}
+	if (completers == 0) {
+		return
+	}
+
+	// Look for four values in a row, using at least one of our hole cards,
+	// which could be completed from either end.
+	holeBits := valBits(hole)
+	for lo := 2; lo + 3 < ACE_VAL; lo++ {
+		run := 0xf << uint(lo)
+		if (((bits & run) == run) && ((holeBits & run) != 0)) {
+			hc.Draws |= DRAW_OPEN_ENDED
+			return
+		}
+	}
+	if (completers > 1) {
+		hc.Draws |= DRAW_DOUBLE_GUTSHOT
+	} else {
+		hc.Draws |= DRAW_GUTSHOT
+	}
+}
+
+/* Describe our hand on the current board. The board must have at least three
+ * cards. Draws are only looked for if there are cards still to come.
+ */
+func ClassifyHand(hole CardSlice, board CardSlice) *HandClass {
+	hc := new(HandClass)
+	best := bestHandWith(hole, board)
+	if ((len(board) == BOARD_MAX) && (best.Compare(MakeHand(board)) == 0)) {
+		hc.Made = MADE_PLAYING_BOARD
+		return hc
+	}
+	switch (best.ty) {
+	case STRAIGHT_FLUSH:
+		hc.Made = MADE_STRAIGHT_FLUSH
+	case FOUR_OF_A_KIND:
+		hc.Made = MADE_FOUR_OF_A_KIND
+	case FULL_HOUSE:
+		hc.Made = MADE_FULL_HOUSE
+	case FLUSH:
+		hc.Made = MADE_FLUSH
+	case STRAIGHT:
+		hc.Made = MADE_STRAIGHT
+	default:
+		classifyPairs(hc, hole, board)
+	}
+	if (len(board) == BOARD_MAX) {
+		return hc
+	}
+	if (best.ty < FLUSH) {
+		classifyFlushDraws(hc, hole, board)
+	}
+	if (best.ty < STRAIGHT) {
+		classifyStraightDraws(hc, hole, board)
+	}
+	return hc
+}
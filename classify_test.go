@@ -0,0 +1,46 @@
+/*
+ * Copyright 2011 Colin Patrick McCabe
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, version 2.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package main
+
+import (
+	"testing"
+)
+
+func expectClass(t *testing.T, holeStr string, boardStr string, expected string) {
+	hole, _ := StrToCards(holeStr)
+	board, _ := StrToCards(boardStr)
+	hc := ClassifyHand(hole, board)
+	if (hc.String() != expected) {
+		t.Errorf("with %s on %s, expected '%s', got '%s'", holeStr, boardStr,
+			expected, hc.String())
+	}
+}
+
+func TestClassifyHand(t *testing.T) {
+	expectClass(t, "AH KD", "AS 7D 3C", "top pair, top kicker")
+	expectClass(t, "AH 4D", "AS 7D 3C", "top pair, weak kicker")
+	expectClass(t, "QH QD", "JS 7D 3C", "overpair")
+	expectClass(t, "9S 9D", "JS 7D 9C", "middle set")
+	expectClass(t, "JS 10D", "QS 9D 2C 3H", "nothing + open-ended straight draw")
+	expectClass(t, "9S 8D", "JS 7D 2C", "nothing + gutshot")
+	expectClass(t, "AH 5H", "KH 7H 4C", "ace high + nut flush draw")
+	expectClass(t, "AC KC", "2D 7H 9C", "two overcards + backdoor flush draw")
+	expectClass(t, "JD 10D", "QD 9C 2D",
+		"nothing + combo draw, flush draw, open-ended straight draw")
+	expectClass(t, "7H 7C", "AS KD 7D 2H KC", "a full house")
+	expectClass(t, "2H 3H", "AS KS QS JS 10S", "playing the board")
+}
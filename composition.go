@@ -0,0 +1,167 @@
+/*
+ * Copyright 2011 Colin Patrick McCabe
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, version 2.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+)
+
+/* The draws we count in a range composition, in the order we show them.
+ * These are the names returned by HandClass.DrawStrings.
+ */
+var compositionDraws = []string {
+	"combo draw",
+	"nut flush draw",
+	"flush draw",
+	"open-ended straight draw",
+	"double gutshot",
+	"gutshot",
+	"backdoor flush draw",
+}
+
+/* What a range looks like on a board. Made counts the combos with each kind
+ * of made hand, split up by kicker. A combo may have several draws, so it may
+ * be counted under several of them. Air counts the combos with neither a
+ * made hand nor a draw. Each combo counts for its weight in the range.
+ */
+type RangeComposition struct {
+	Combos float64
+	Made [MAX_MADE][KICKER_TOP + 1]float64
+	Draws map[string] float64
+	Air float64
+}
+
+/* Break the combos in a range down by what they make on the board. Combos
+ * which use a board card or a dead card can't be held, and aren't counted.
+ */
+func CalcRangeComposition(r *Range, board CardSlice,
+		dead CardSlice) (*RangeComposition, error) {
+	err := checkPostflop(board, "the composition of a range")
+	if (err != nil) {
+		return nil, err
+	}
+	err = checkBoardLength(len(board))
+	if (err != nil) {
+		return nil, err
+	}
+	known := append(append(CardSlice {}, board...), dead...)
+	dupe := known.HasDuplicates()
+	if (dupe != nil) {
+		return nil, fmt.Errorf("The card %s appears more than once! That " +
+			"is not possible.", dupe)
+	}
+	combos := r.Live(known)
+	if (len(combos) == 0) {
+		return nil, fmt.Errorf("every combo in the range uses a card that " +
+			"is already out.")
+	}
+	ret := &RangeComposition { Draws: make(map[string] float64) }
+	for i := range(combos) {
+		w := r.Weight(combos[i])
+		ret.Combos += w
+		hc := ClassifyHand(combos[i], board)
+		ret.Made[hc.Made][hc.Kicker] += w
+		draws := hc.DrawStrings()
+		for j := range(draws) {
+			ret.Draws[draws[j]] += w
+		}
+		if (hc.IsAir()) {
+			ret.Air += w
+		}
+	}
+	return ret, nil
+}
+
+func (rc *RangeComposition) line(name string, n float64) string {
+	return fmt.Sprintf("%-36s %7s %7.2f%%\n", name, comboCountToStr(n),
+		n * 100.0 / rc.Combos)
+}
+
+func (rc *RangeComposition) String() string {
+	ret := fmt.Sprintf("%s combos\n", comboCountToStr(rc.Combos))
+	ret += "made hands:\n"
+	for m := MAX_MADE - 1; m >= 0; m-- {
+		for k := KICKER_TOP; k >= KICKER_NONE; k-- {
+			if (rc.Made[m][k] == 0) {
+				continue
+			}
+			hc := HandClass { Made: m, Kicker: k }
+			ret += rc.line(hc.MadeString(), rc.Made[m][k])
+		}
+	}
+	ret += "draws:\n"
+	for i := range(compositionDraws) {
+		n := rc.Draws[compositionDraws[i]]
+		if (n == 0) {
+			continue
+		}
+		ret += rc.line(compositionDraws[i], n)
+	}
+	ret += rc.line("air", rc.Air)
+	return ret
+}
+
+func compositionUsage(fs *flag.FlagSet) func() {
+	return func() {
+		fmt.Fprintf(os.Stderr,
+`%s composition: show what a range looks like on a board.
+
+Usage:
+%s composition -b [board] [options] [range]
+
+Every combo in the range is classified by the made hand and the draws it has
+on the board. The number of combos of each kind is shown, along with how much
+of the range that is.
+
+Options:
+`, os.Args[0], os.Args[0])
+		fs.PrintDefaults()
+	}
+}
+
+func compositionMain(args []string) {
+	fs := flag.NewFlagSet("composition", flag.ExitOnError)
+	fs.Usage = compositionUsage(fs)
+	var boardStr = fs.String("b", "", "the board")
+	var holeStr = fs.String("a", "", "your hole cards, which the range " +
+		"can't contain")
+	fs.Parse(args)
+
+	board, err := ParseCards(*boardStr, "the board")
+	if (err != nil) {
+		die(err)
+	}
+	hole, err := ParseCards(*holeStr, "your hole cards")
+	if (err != nil) {
+		die(err)
+	}
+	if (fs.NArg() != 1) {
+		fs.Usage()
+		os.Exit(1)
+	}
+	r, err := LoadRange(fs.Arg(0))
+	if (err != nil) {
+		die(err)
+	}
+	rc, err := CalcRangeComposition(r, board, hole)
+	if (err != nil) {
+		die(err)
+	}
+	fmt.Printf("%s on %s: %s", fs.Arg(0), board.ShortString(), rc.String())
+}
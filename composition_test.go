@@ -0,0 +1,82 @@
+/*
+ * Copyright 2011 Colin Patrick McCabe
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, version 2.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package main
+
+import (
+	"testing"
+)
+
+func TestRangeComposition1(t *testing.T) {
+	r, _ := ParseRange("AA, KK, AKs, 43o")
+	board, _ := ParseCards("KS 7H 2D", "the board")
+	rc, err := CalcRangeComposition(r, board, nil)
+	if (err != nil) {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	if (rc.Combos != 24) {
+		t.Errorf("expected 24 live combos, got %f", rc.Combos)
+	}
+	if (rc.Made[MADE_TOP_SET][KICKER_NONE] != 3) {
+		t.Errorf("expected 3 combos of top set, got %f",
+			rc.Made[MADE_TOP_SET][KICKER_NONE])
+	}
+	if (rc.Made[MADE_OVERPAIR][KICKER_NONE] != 6) {
+		t.Errorf("expected 6 overpairs, got %f",
+			rc.Made[MADE_OVERPAIR][KICKER_NONE])
+	}
+	if (rc.Made[MADE_TOP_PAIR][KICKER_TOP] != 3) {
+		t.Errorf("expected 3 combos of top pair, top kicker, got %f",
+			rc.Made[MADE_TOP_PAIR][KICKER_TOP])
+	}
+	// 43o has nothing, not even a gutshot.
+	if ((rc.Made[MADE_NOTHING][KICKER_NONE] != 12) || (rc.Air != 12)) {
+		t.Errorf("expected 12 combos of air, got %f and %f",
+			rc.Made[MADE_NOTHING][KICKER_NONE], rc.Air)
+	}
+	// Only AhKh and AdKd have backdoor flush draws.
+	if ((rc.Draws["backdoor flush draw"] != 2) || (len(rc.Draws) != 1)) {
+		t.Errorf("expected only 2 backdoor flush draws, got %v", rc.Draws)
+	}
+
+	// Our hole cards block some of the range.
+	dead, _ := ParseCards("AS AH", "your hole cards")
+	rc, err = CalcRangeComposition(r, board, dead)
+	if (err != nil) {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	if ((rc.Combos != 18) ||
+			(rc.Made[MADE_OVERPAIR][KICKER_NONE] != 1) ||
+			(rc.Made[MADE_TOP_PAIR][KICKER_TOP] != 2)) {
+		t.Errorf("expected 18 combos, 1 overpair and 2 top pairs, got %s",
+			rc.String())
+	}
+
+	// Weighted combos count as fractions of a combo.
+	r, _ = ParseRange("AA:0.5, KK")
+	rc, err = CalcRangeComposition(r, board, nil)
+	if (err != nil) {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	if ((rc.Combos != 6) || (rc.Made[MADE_OVERPAIR][KICKER_NONE] != 3)) {
+		t.Errorf("expected 6 combos and 3 overpairs, got %s", rc.String())
+	}
+
+	_, err = CalcRangeComposition(r, nil, nil)
+	if (err == nil) {
+		t.Errorf("expected an error before the flop")
+	}
+}
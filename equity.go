@@ -0,0 +1,246 @@
+/*
+ * Copyright 2011 Colin Patrick McCabe
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, version 2.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package main
+
+import (
+	"fmt"
+	"math/rand"
+)
+
+// The most random opponents we can calculate equity against.
+const MAX_RANDOM_OPPONENTS = 9
+
+/* If exact enumeration would take more showdowns than this, we deal random
+ * showdowns instead.
+ */
+const MAX_EXACT_SHOWDOWNS = 5000000
+
+/* How we did against the other players over a number of showdowns. When we
+ * tie, Share gets our fraction of the pot.
+ */
+type Equity struct {
+	Win float64
+	Tie float64
+	Loss float64
+	Share float64
+	Exact bool
+}
+
+func (eq *Equity) Total() float64 {
+	return eq.Win + eq.Tie + eq.Loss
+}
+
+/* Returns our share of the pot, on average. */
+func (eq *Equity) Value() float64 {
+	return eq.Share / eq.Total()
+}
+
+func (eq *Equity) String() string {
+	t := eq.Total()
+	return fmt.Sprintf("win %03.2f%%, tie %03.2f%%, loss %03.2f%%, " +
+		"equity %03.2f%%", eq.Win * 100.0 / t, eq.Tie * 100.0 / t,
+		eq.Loss * 100.0 / t, eq.Value() * 100.0)
+}
+
+/* Record one showdown, given our rank and the ranks of everyone else. */
+func (eq *Equity) addShowdown(ours int, theirs []int, weight float64) {
+	tied := 0
+	for i := range(theirs) {
+		if (theirs[i] > ours) {
+			eq.Loss += weight
+			return
+		} else if (theirs[i] == ours) {
+			tied++
+		}
+	}
+	if (tied == 0) {
+		eq.Win += weight
+		eq.Share += weight
+	} else {
+		eq.Tie += weight
+		eq.Share += weight / float64(tied + 1)
+	}
+}
+
+func choose(n int, k int) float64 {
+	if ((k < 0) || (k > n)) {
+		return 0
+	}
+	ret := 1.0
+	for i := 0; i < k; i++ {
+		ret = ret * float64(n - i) / float64(i + 1)
+	}
+	return ret
+}
+
+/* Returns the number of showdowns exact enumeration would need. */
+func exactShowdowns(numFuture int, boardNeed int, numRandom int) float64 {
+	ret := choose(numFuture, boardNeed)
+	left := numFuture - boardNeed
+	for i := 0; i < numRandom; i++ {
+		ret *= choose(left, HOLE_SZ)
+		left -= HOLE_SZ
+	}
+	return ret
+}
+
+/* Calculate our equity against the known opponents in the scenario, plus
+ * numRandom opponents holding random cards.
+ *
+ * Every showdown is enumerated if there aren't too many of them. Otherwise,
+ * we deal sc.Samples random showdowns (or DEFAULT_SAMPLES, if that is 0).
+ */
+func CalcEquity(sc *Scenario, numRandom int, deck *CardBag) (*Equity, error) {
+	if ((numRandom < 0) || (numRandom > MAX_RANDOM_OPPONENTS)) {
+		return nil, fmt.Errorf("the number of random opponents must be " +
+			"between 0 and %d.", MAX_RANDOM_OPPONENTS)
+	}
+	if (numRandom + len(sc.Opponents) == 0) {
+		return nil, fmt.Errorf("there must be at least one opponent to " +
+			"calculate equity.")
+	}
+	future := sc.Future(deck)
+	boardNeed := BOARD_MAX - len(sc.Board)
+	if (future.Len() < boardNeed + HOLE_SZ * numRandom) {
+		return nil, fmt.Errorf("there aren't enough cards left in the deck " +
+			"for %d more opponents.", numRandom)
+	}
+	if ((sc.Samples == 0) &&
+			(exactShowdowns(future.Len(), boardNeed, numRandom) <=
+				MAX_EXACT_SHOWDOWNS)) {
+		return exactEquity(sc, numRandom, future), nil
+	}
+	samples := sc.Samples
+	if (samples == 0) {
+		samples = DEFAULT_SAMPLES
+	}
+	return sampleEquity(sc, numRandom, future, samples), nil
+}
+
+func exactEquity(sc *Scenario, numRandom int, future *CardBag) *Equity {
+	eq := &Equity { Exact: true }
+	var buf [SPREAD_MAX]*Card
+	board := make(CardSlice, len(sc.Board), BOARD_MAX)
+	copy(board, sc.Board)
+	theirs := make([]int, len(sc.Opponents) + numRandom)
+	used := make([]bool, future.Len())
+
+	// Deal hole cards to random opponent n and everyone after them.
+	var deal func(full CardSlice, ours int, n int)
+	deal = func(full CardSlice, ours int, n int) {
+		if (n == numRandom) {
+			eq.addShowdown(ours, theirs, 1.0)
+			return
+		}
+		var hole [HOLE_SZ]*Card
+		for i := 0; i < future.Len(); i++ {
+			if (used[i]) {
+				continue
+			}
+			used[i] = true
+			hole[0] = future.Get(uint(i))
+			for j := i + 1; j < future.Len(); j++ {
+				if (used[j]) {
+					continue
+				}
+				used[j] = true
+				hole[1] = future.Get(uint(j))
+				theirs[len(sc.Opponents) + n] = holeRank(hole[:], full, buf[:0])
+				deal(full, ours, n + 1)
+				used[j] = false
+			}
+			used[i] = false
+		}
+	}
+
+	chooser := NewSubsetChooser(uint(future.Len()), uint(BOARD_MAX - len(board)))
+	for ;; {
+		cur := chooser.Cur()
+		full := board
+		for i := range(cur) {
+			used[cur[i]] = true
+			full = append(full, future.Get(cur[i]))
+		}
+		for i := range(sc.Opponents) {
+			theirs[i] = holeRank(sc.Opponents[i], full, buf[:0])
+		}
+		deal(full, holeRank(sc.Hole, full, buf[:0]), 0)
+		for i := range(cur) {
+			used[cur[i]] = false
+		}
+		if (!chooser.Next()) {
+			break
+		}
+	}
+	return eq
+}
+
+func sampleEquity(sc *Scenario, numRandom int, future *CardBag,
+		samples int) *Equity {
+	eq := new(Equity)
+	rng := rand.New(rand.NewSource(sc.Seed))
+	var buf [SPREAD_MAX]*Card
+	board := make(CardSlice, len(sc.Board), BOARD_MAX)
+	copy(board, sc.Board)
+	boardNeed := BOARD_MAX - len(sc.Board)
+	need := boardNeed + HOLE_SZ * numRandom
+	theirs := make([]int, len(sc.Opponents) + numRandom)
+	deal := future.Clone().allCards.Copy()
+	for n := 0; n < samples; n++ {
+		// partial Fisher-Yates shuffle
+		for i := 0; i < need; i++ {
+			j := i + rng.Intn(len(deal) - i)
+			deal[i], deal[j] = deal[j], deal[i]
+		}
+		full := append(board, deal[:boardNeed]...)
+		for i := range(sc.Opponents) {
+			theirs[i] = holeRank(sc.Opponents[i], full, buf[:0])
+		}
+		for i := 0; i < numRandom; i++ {
+			hole := deal[boardNeed + HOLE_SZ * i:boardNeed + HOLE_SZ * (i + 1)]
+			theirs[len(sc.Opponents) + i] = holeRank(hole, full, buf[:0])
+		}
+		eq.addShowdown(holeRank(sc.Hole, full, buf[:0]), theirs, 1.0)
+	}
+	return eq
+}
+
+func equityMethodStr(eq *Equity) string {
+	if (eq.Exact) {
+		return fmt.Sprintf("exact, %.0f showdowns", eq.Total())
+	}
+	return fmt.Sprintf("sampled, %.0f showdowns", eq.Total())
+}
+
+/* Calculate our equity against 1, 2, ... maxRandom random opponents, plus
+ * any known opponents, and lay the results out in a table.
+ */
+func EquityTable(sc *Scenario, maxRandom int, deck *CardBag) (string, error) {
+	ret := fmt.Sprintf("%-9s %8s %8s %8s %8s  %s\n", "opponents", "win",
+		"tie", "loss", "equity", "method")
+	for n := 1; n <= maxRandom; n++ {
+		eq, err := CalcEquity(sc, n, deck)
+		if (err != nil) {
+			return "", err
+		}
+		t := eq.Total()
+		ret += fmt.Sprintf("%-9d %7.2f%% %7.2f%% %7.2f%% %7.2f%%  %s\n",
+			n + len(sc.Opponents), eq.Win * 100.0 / t, eq.Tie * 100.0 / t,
+			eq.Loss * 100.0 / t, eq.Value() * 100.0, equityMethodStr(eq))
+	}
+	return ret, nil
+}
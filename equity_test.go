@@ -0,0 +1,62 @@
+/*
+ * Copyright 2011 Colin Patrick McCabe
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, version 2.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestEquity1(t *testing.T) {
+	deck := Make52CardBag()
+
+	// On the river, our equity against one random opponent is just our hand
+	// strength.
+	sc := makeScenario(t, &OddsRequest { Hole: "KH QH", Board: "AH 3H 5C 9D 2S" })
+	eq, err := CalcEquity(sc, 1, deck)
+	if (err != nil) {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	hs, _ := CalcHandStrength(sc, deck)
+	if ((!eq.Exact) || (math.Abs(eq.Value() - hs.Value()) > 1e-9)) {
+		t.Errorf("expected exact equity %f, got %s", hs.Value(), eq)
+	}
+
+	// AsAd against KsKd, before the flop.
+	sc = makeScenario(t, &OddsRequest { Hole: "AS AD", Opponents: []string { "KS KD" } })
+	eq, err = CalcEquity(sc, 0, deck)
+	if (err != nil) {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	if ((!eq.Exact) || (eq.Total() != 1712304) ||
+			(math.Abs(eq.Value() - 0.8264) > 0.0001)) {
+		t.Errorf("expected exact equity of 82.64%%, got %s", eq)
+	}
+	sc.Samples = 50000
+	sampled, err := CalcEquity(sc, 0, deck)
+	if (err != nil) {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	if ((sampled.Exact) || (math.Abs(sampled.Value() - eq.Value()) > 0.01)) {
+		t.Errorf("expected sampled equity close to %s, got %s", eq, sampled)
+	}
+
+	_, err = CalcEquity(sc, MAX_RANDOM_OPPONENTS + 1, deck)
+	if (err == nil) {
+		t.Errorf("expected an error with too many opponents")
+	}
+}
@@ -0,0 +1,527 @@
+/*
+ * Copyright 2011 Colin Patrick McCabe
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, version 2.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package main
+
+import (
+	"fmt"
+	"math/rand"
+)
+
+/* A dealer for one hand of no-limit Texas Hold'em.
+ *
+ * NewGame seats the players, posts the antes and blinds, and deals the hole
+ * cards. After that, whoever is in ToAct makes their choice with Act, until
+ * the hand is over. The board is dealt as each betting round ends, and once
+ * only one player is left, or the river betting is done, the pot is given
+ * out and Result is filled in.
+ */
+
+const (
+	STREET_PREFLOP = iota
+	STREET_FLOP
+	STREET_TURN
+	STREET_RIVER
+	STREET_SHOWDOWN
+)
+
+func StreetToStr(street int) string {
+	switch (street) {
+	case STREET_PREFLOP:
+		return "preflop"
+	case STREET_FLOP:
+		return "flop"
+	case STREET_TURN:
+		return "turn"
+	case STREET_RIVER:
+		return "river"
+	case STREET_SHOWDOWN:
+		return "showdown"
+	}
+	panic(fmt.Sprintf("unexpected street %d", street))
+}
+
+const (
+	ACTION_FOLD = iota
+	ACTION_CHECK
+	ACTION_CALL
+	ACTION_BET
+	ACTION_RAISE
+)
+
+/* Something a player does when it is their turn.
+ *
+ * For a bet or a raise, Amount is what the player's bet on this street comes
+ * to in total, so "raise to 60" is Action { ACTION_RAISE, 60 }. For a call,
+ * Act fills in Amount with the chips the player put in.
+ */
+type Action struct {
+	Ty int
+	Amount int64
+}
+
+func (a Action) String() string {
+	switch (a.Ty) {
+	case ACTION_FOLD:
+		return "fold"
+	case ACTION_CHECK:
+		return "check"
+	case ACTION_CALL:
+		return fmt.Sprintf("call %d", a.Amount)
+	case ACTION_BET:
+		return fmt.Sprintf("bet %d", a.Amount)
+	case ACTION_RAISE:
+		return fmt.Sprintf("raise to %d", a.Amount)
+	}
+	panic(fmt.Sprintf("unexpected action type %d", a.Ty))
+}
+
+type GameConfig struct {
+	SmallBlind int64
+	BigBlind int64
+	Ante int64
+	OddChip string
+}
+
+/* A player in the hand. Bet is what they have put in on this street, and
+ * Contribution is what they have put in on every street, antes included.
+ */
+type GameSeat struct {
+	Name string
+	Stack int64
+	Hole CardSlice
+	Bet int64
+	Contribution int64
+	Folded bool
+	acted bool
+}
+
+/* Returns true if the player is still in the hand, and has chips left to
+ * act with.
+ */
+func (s *GameSeat) canAct() bool {
+	return ((!s.Folded) && (s.Stack > 0))
+}
+
+func (s *GameSeat) put(amount int64) {
+	s.Stack -= amount
+	s.Bet += amount
+	s.Contribution += amount
+}
+
+/* An action taken during the hand. */
+type GameEvent struct {
+	Seat int
+	Street int
+	Action Action
+	AllIn bool
+}
+
+/* What the player in ToAct may do. ToCall is 0 when they can check.
+ * Otherwise, they can fold or call ToCall, which may be all they have left.
+ * If CanRaise is set, they can bet or raise to anything from MinRaiseTo to
+ * MaxRaiseTo. MaxRaiseTo puts them all-in, and is allowed even when it is
+ * less than MinRaiseTo.
+ */
+type ActionOptions struct {
+	ToCall int64
+	CanRaise bool
+	MinRaiseTo int64
+	MaxRaiseTo int64
+}
+
+type Game struct {
+	Config GameConfig
+	Seats []*GameSeat
+	Button int
+	SmallBlindSeat int
+	BigBlindSeat int
+	Board CardSlice
+	Street int
+	ToAct int
+	CurrentBet int64
+	MinRaise int64
+	Events []GameEvent
+	Result *ShowdownResult
+	deck CardSlice
+}
+
+/* Returns all 52 cards, in a random order. */
+func ShuffledDeck(rng *rand.Rand) CardSlice {
+	bag := Make52CardBag()
+	perm := rng.Perm(bag.Len())
+	ret := make(CardSlice, bag.Len())
+	for i := range(ret) {
+		ret[i] = bag.Get(uint(perm[i]))
+	}
+	return ret
+}
+
+/* Start a hand. The cards are dealt from the front of the deck: one card at a
+ * time to each player, starting to the left of the button, then a burn card
+ * before each street.
+ */
+func NewGame(cfg GameConfig, names []string, stacks []int64, button int,
+		deck CardSlice) (*Game, error) {
+	n := len(names)
+	if (n < 2) {
+		return nil, fmt.Errorf("there must be at least two players.")
+	}
+	if (len(stacks) != n) {
+		return nil, fmt.Errorf("there are %d players, but %d stacks.",
+			n, len(stacks))
+	}
+	if ((button < 0) || (button >= n)) {
+		return nil, fmt.Errorf("the button must be at one of the %d seats.", n)
+	}
+	if (cfg.BigBlind <= 0) {
+		return nil, fmt.Errorf("the big blind must be more than 0.")
+	}
+	if ((cfg.SmallBlind < 0) || (cfg.SmallBlind > cfg.BigBlind)) {
+		return nil, fmt.Errorf("the small blind must be from 0 to the big " +
+			"blind.")
+	}
+	if (cfg.Ante < 0) {
+		return nil, fmt.Errorf("the ante can't be negative.")
+	}
+	needed := HOLE_SZ * n + BOARD_MAX + 3
+	if (len(deck) < needed) {
+		return nil, fmt.Errorf("dealing to %d players takes %d cards, but " +
+			"the deck has only %d.", n, needed, len(deck))
+	}
+	dupe := deck.HasDuplicates()
+	if (dupe != nil) {
+		return nil, fmt.Errorf("The card %s appears more than once! " +
+			"That is not possible.", dupe)
+	}
+	g := &Game { Config: cfg, Button: button, deck: deck.Copy() }
+	seen := make(map[string] bool)
+	for i := range(names) {
+		s := &GameSeat { Name: names[i], Stack: stacks[i] }
+		if (s.Name == "") {
+			s.Name = fmt.Sprintf("seat %d", i + 1)
+		}
+		if (seen[s.Name]) {
+			return nil, fmt.Errorf("more than one player is called '%s'.",
+				s.Name)
+		}
+		seen[s.Name] = true
+		if (s.Stack <= 0) {
+			return nil, fmt.Errorf("%s has no chips.", s.Name)
+		}
+		g.Seats = append(g.Seats, s)
+	}
+	for c := 0; c < HOLE_SZ; c++ {
+		for i := 1; i <= n; i++ {
+			s := g.Seats[(button + i) % n]
+			s.Hole = append(s.Hole, g.deal())
+		}
+	}
+	for i := range(g.Seats) {
+		s := g.Seats[i]
+		s.put(minInt64(cfg.Ante, s.Stack))
+		s.Bet = 0
+	}
+	if (n == 2) {
+		g.SmallBlindSeat = button
+	} else {
+		g.SmallBlindSeat = (button + 1) % n
+	}
+	g.BigBlindSeat = (g.SmallBlindSeat + 1) % n
+	sb := g.Seats[g.SmallBlindSeat]
+	sb.put(minInt64(cfg.SmallBlind, sb.Stack))
+	bb := g.Seats[g.BigBlindSeat]
+	bb.put(minInt64(cfg.BigBlind, bb.Stack))
+	g.CurrentBet = cfg.BigBlind
+	g.MinRaise = cfg.BigBlind
+	g.ToAct = g.BigBlindSeat
+	g.advance()
+	return g, nil
+}
+
+func (g *Game) deal() *Card {
+	c := g.deck[0]
+	g.deck = g.deck[1:]
+	return c
+}
+
+/* Returns true once the pot has been given out. */
+func (g *Game) Done() bool {
+	return (g.Result != nil)
+}
+
+/* Returns the chips put in so far by everyone, on every street. */
+func (g *Game) Pot() int64 {
+	ret := int64(0)
+	for i := range(g.Seats) {
+		ret += g.Seats[i].Contribution
+	}
+	return ret
+}
+
+func (g *Game) numLive() int {
+	ret := 0
+	for i := range(g.Seats) {
+		if (!g.Seats[i].Folded) {
+			ret++
+		}
+	}
+	return ret
+}
+
+/* Returns true if the player still has to act in this betting round. */
+func (g *Game) needsToAct(i int) bool {
+	s := g.Seats[i]
+	return (s.canAct() && ((!s.acted) || (s.Bet < g.CurrentBet)))
+}
+
+/* Returns true if the betting round is over. A player who is the only one
+ * left with chips doesn't need to act unless they are facing a bet.
+ */
+func (g *Game) roundOver() bool {
+	var active []int
+	for i := range(g.Seats) {
+		if (g.Seats[i].canAct()) {
+			active = append(active, i)
+		}
+	}
+	if ((len(active) == 1) && (g.Seats[active[0]].Bet >= g.CurrentBet)) {
+		return true
+	}
+	for i := range(active) {
+		if (g.needsToAct(active[i])) {
+			return false
+		}
+	}
+	return true
+}
+
+/* Returns the next player after seat 'from' who still has to act. */
+func (g *Game) nextToAct(from int) int {
+	n := len(g.Seats)
+	for i := 1; i <= n; i++ {
+		j := (from + i) % n
+		if (g.needsToAct(j)) {
+			return j
+		}
+	}
+	panic("nobody is left to act")
+}
+
+/* Move the hand along until someone has to act, or the hand is over. */
+func (g *Game) advance() {
+	for ;; {
+		if (g.numLive() == 1) {
+			g.finishFolded()
+			return
+		}
+		if (!g.roundOver()) {
+			g.ToAct = g.nextToAct(g.ToAct)
+			return
+		}
+		if (g.Street == STREET_RIVER) {
+			g.finishShowdown()
+			return
+		}
+		g.nextStreet()
+	}
+}
+
+func (g *Game) nextStreet() {
+	for i := range(g.Seats) {
+		g.Seats[i].Bet = 0
+		g.Seats[i].acted = false
+	}
+	g.CurrentBet = 0
+	g.MinRaise = g.Config.BigBlind
+	g.Street++
+	g.deal()
+	num := 1
+	if (g.Street == STREET_FLOP) {
+		num = 3
+	}
+	for i := 0; i < num; i++ {
+		g.Board = append(g.Board, g.deal())
+	}
+	g.ToAct = g.Button
+}
+
+/* Everyone else folded, so the last player left takes the whole pot without
+ * showing their cards.
+ */
+func (g *Game) finishFolded() {
+	pot := &PotResult { Name: "main pot", Amount: g.Pot() }
+	res := &ShowdownResult { Pots: []*PotResult { pot } }
+	for i := range(g.Seats) {
+		s := g.Seats[i]
+		won := int64(0)
+		if (!s.Folded) {
+			won = pot.Amount
+			pot.Eligible = []string { s.Name }
+			pot.Winners = []PotWinner { { Name: s.Name, Amount: won } }
+		}
+		s.Stack += won
+		res.Payouts = append(res.Payouts, Payout { s.Name, won,
+			won - s.Contribution })
+	}
+	g.Result = res
+	g.ToAct = -1
+}
+
+func (g *Game) finishShowdown() {
+	req := &ShowdownRequest { Board: g.Board.ShortString(),
+		Button: g.Button, OddChip: g.Config.OddChip }
+	for i := range(g.Seats) {
+		s := g.Seats[i]
+		req.Players = append(req.Players, ShowdownPlayer { s.Name,
+			s.Hole.ShortString(), s.Contribution, s.Folded })
+	}
+	res, err := ResolveShowdown(req)
+	if (err != nil) {
+		panic(err)
+	}
+	for i := range(g.Seats) {
+		g.Seats[i].Stack += res.Payouts[i].Won
+	}
+	g.Street = STREET_SHOWDOWN
+	g.Result = res
+	g.ToAct = -1
+}
+
+/* Returns what the player in ToAct may do. */
+func (g *Game) Options() ActionOptions {
+	s := g.Seats[g.ToAct]
+	toCall := g.CurrentBet - s.Bet
+	opts := ActionOptions { ToCall: minInt64(toCall, s.Stack) }
+	others := 0
+	for i := range(g.Seats) {
+		if ((i != g.ToAct) && (g.Seats[i].canAct())) {
+			others++
+		}
+	}
+	/* A player can't raise if nobody could call it, or if they have already
+	 * acted and nobody has made a full raise since then.
+	 */
+	opts.CanRaise = ((!s.acted) && (s.Stack > toCall) && (others > 0))
+	if (opts.CanRaise) {
+		opts.MaxRaiseTo = s.Bet + s.Stack
+		opts.MinRaiseTo = minInt64(g.CurrentBet + g.MinRaise,
+			opts.MaxRaiseTo)
+	}
+	return opts
+}
+
+/* The player in ToAct takes an action. Returns an error, and changes
+ * nothing, if the action isn't allowed.
+ */
+func (g *Game) Act(a Action) error {
+	if (g.Done()) {
+		return fmt.Errorf("the hand is over.")
+	}
+	s := g.Seats[g.ToAct]
+	opts := g.Options()
+	switch (a.Ty) {
+	case ACTION_FOLD:
+		s.Folded = true
+	case ACTION_CHECK:
+		if (opts.ToCall > 0) {
+			return fmt.Errorf("%s can't check when there is %d to call.",
+				s.Name, opts.ToCall)
+		}
+	case ACTION_CALL:
+		if (opts.ToCall == 0) {
+			return fmt.Errorf("%s has nothing to call.", s.Name)
+		}
+		a.Amount = opts.ToCall
+		s.put(opts.ToCall)
+	case ACTION_BET, ACTION_RAISE:
+		if ((a.Ty == ACTION_BET) && (g.CurrentBet > 0)) {
+			return fmt.Errorf("%s can't bet when there is already a bet. " +
+				"Raise instead.", s.Name)
+		}
+		if ((a.Ty == ACTION_RAISE) && (g.CurrentBet == 0)) {
+			return fmt.Errorf("%s can't raise when nobody has bet. Bet " +
+				"instead.", s.Name)
+		}
+		if (!opts.CanRaise) {
+			return fmt.Errorf("%s can't %s now.", s.Name, raiseVerb(a.Ty))
+		}
+		if (a.Amount > opts.MaxRaiseTo) {
+			return fmt.Errorf("%s can only %s to %d.", s.Name,
+				raiseVerb(a.Ty), opts.MaxRaiseTo)
+		}
+		if ((a.Amount < opts.MinRaiseTo) && (a.Amount != opts.MaxRaiseTo)) {
+			return fmt.Errorf("%s must %s to at least %d, or go all-in.",
+				s.Name, raiseVerb(a.Ty), opts.MinRaiseTo)
+		}
+		raiseBy := a.Amount - g.CurrentBet
+		if ((g.CurrentBet == 0) || (raiseBy >= g.MinRaise)) {
+			/* A full bet or raise. Everyone gets to act again. An all-in
+			 * for less than this doesn't reopen the betting.
+			 */
+			if (raiseBy > g.MinRaise) {
+				g.MinRaise = raiseBy
+			}
+			for i := range(g.Seats) {
+				g.Seats[i].acted = false
+			}
+		}
+		g.CurrentBet = a.Amount
+		s.put(a.Amount - s.Bet)
+	default:
+		return fmt.Errorf("unknown action type %d", a.Ty)
+	}
+	s.acted = true
+	g.Events = append(g.Events, GameEvent { g.ToAct, g.Street, a,
+		((!s.Folded) && (s.Stack == 0)) })
+	g.advance()
+	return nil
+}
+
+func raiseVerb(ty int) string {
+	if (ty == ACTION_BET) {
+		return "bet"
+	}
+	return "raise"
+}
+
+func (g *Game) String() string {
+	ret := fmt.Sprintf("%s: pot %d", StreetToStr(g.Street), g.Pot())
+	if (len(g.Board) > 0) {
+		ret += ", board " + g.Board.ShortString()
+	}
+	ret += "\n"
+	for i := range(g.Seats) {
+		s := g.Seats[i]
+		mark := " "
+		if (i == g.ToAct) {
+			mark = ">"
+		}
+		ret += fmt.Sprintf("%s %-12s %s  stack %6d  bet %6d", mark, s.Name,
+			s.Hole.ShortString(), s.Stack, s.Bet)
+		switch {
+		case s.Folded:
+			ret += "  folded"
+		case s.Stack == 0:
+			ret += "  all-in"
+		}
+		if (i == g.Button) {
+			ret += "  (button)"
+		}
+		ret += "\n"
+	}
+	return ret
+}
@@ -0,0 +1,264 @@
+/*
+ * Copyright 2011 Colin Patrick McCabe
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, version 2.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package main
+
+import (
+	"math/rand"
+	"testing"
+)
+
+var TEST_GAME_CFG = GameConfig { SmallBlind: 5, BigBlind: 10 }
+
+func newTestGame(t *testing.T, cfg GameConfig, stacks []int64,
+		button int, deckStr string) *Game {
+	names := []string { "alice", "bob", "carol", "dave", "erin", "frank" }
+	var deck CardSlice
+	if (deckStr == "") {
+		deck = ShuffledDeck(rand.New(rand.NewSource(1)))
+	} else {
+		var err error
+		deck, err = ParseCards(deckStr, "the deck")
+		if (err != nil) {
+			t.Fatalf("%s", err.Error())
+		}
+	}
+	g, err := NewGame(cfg, names[:len(stacks)], stacks, button, deck)
+	if (err != nil) {
+		t.Fatalf("%s", err.Error())
+	}
+	return g
+}
+
+func act(t *testing.T, g *Game, ty int, amount int64) {
+	err := g.Act(Action { ty, amount })
+	if (err != nil) {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+}
+
+func expectActError(t *testing.T, g *Game, ty int, amount int64) {
+	err := g.Act(Action { ty, amount })
+	if (err == nil) {
+		t.Errorf("expected %s to be refused", Action { ty, amount })
+	}
+}
+
+func expectToAct(t *testing.T, g *Game, eSeat int, eStreet int) {
+	if ((g.ToAct != eSeat) || (g.Street != eStreet)) {
+		t.Fatalf("expected seat %d to act on the %s, but seat %d is to act " +
+			"on the %s", eSeat, StreetToStr(eStreet), g.ToAct,
+			StreetToStr(g.Street))
+	}
+}
+
+func expectStacks(t *testing.T, g *Game, eStacks []int64) {
+	for i := range(eStacks) {
+		if (g.Seats[i].Stack != eStacks[i]) {
+			t.Errorf("expected %s to have %d, but they have %d",
+				g.Seats[i].Name, eStacks[i], g.Seats[i].Stack)
+		}
+	}
+}
+
+func TestGameHeadsUp(t *testing.T) {
+	g := newTestGame(t, TEST_GAME_CFG, []int64 { 1000, 1000 }, 0, "")
+	/* Heads up, the button posts the small blind and acts first before
+	 * the flop, and last after it.
+	 */
+	if ((g.SmallBlindSeat != 0) || (g.BigBlindSeat != 1)) {
+		t.Errorf("expected the button to post the small blind")
+	}
+	expectToAct(t, g, 0, STREET_PREFLOP)
+	if (g.Options().ToCall != 5) {
+		t.Errorf("expected the small blind to have 5 to call, not %d",
+			g.Options().ToCall)
+	}
+	act(t, g, ACTION_CALL, 0)
+	expectToAct(t, g, 1, STREET_PREFLOP)
+	act(t, g, ACTION_CHECK, 0)
+	expectToAct(t, g, 1, STREET_FLOP)
+	if (len(g.Board) != 3) {
+		t.Errorf("expected a flop, got '%s'", g.Board.ShortString())
+	}
+	act(t, g, ACTION_BET, 20)
+	act(t, g, ACTION_FOLD, 0)
+	if (!g.Done()) {
+		t.Fatalf("expected the hand to be over")
+	}
+	expectStacks(t, g, []int64 { 990, 1010 })
+}
+
+func TestGameMinRaise(t *testing.T) {
+	g := newTestGame(t, TEST_GAME_CFG, []int64 { 1000, 1000, 1000 }, 0, "")
+	expectToAct(t, g, 0, STREET_PREFLOP)
+	expectActError(t, g, ACTION_CHECK, 0)
+	expectActError(t, g, ACTION_BET, 30)
+	expectActError(t, g, ACTION_RAISE, 15)
+	expectActError(t, g, ACTION_RAISE, 1001)
+	act(t, g, ACTION_RAISE, 30)
+	opts := g.Options()
+	if ((!opts.CanRaise) || (opts.MinRaiseTo != 50) ||
+			(opts.MaxRaiseTo != 1000)) {
+		t.Errorf("expected to be able to raise to 50 through 1000, got %v",
+			opts)
+	}
+	expectActError(t, g, ACTION_RAISE, 49)
+	act(t, g, ACTION_RAISE, 50)
+	act(t, g, ACTION_CALL, 0)
+	expectToAct(t, g, 0, STREET_PREFLOP)
+	act(t, g, ACTION_CALL, 0)
+	expectToAct(t, g, 1, STREET_FLOP)
+	if (g.Pot() != 150) {
+		t.Errorf("expected a pot of 150, got %d", g.Pot())
+	}
+}
+
+func TestGameShortAllIn(t *testing.T) {
+	g := newTestGame(t, TEST_GAME_CFG, []int64 { 1000, 35, 1000 }, 2, "")
+	expectToAct(t, g, 2, STREET_PREFLOP)
+	act(t, g, ACTION_RAISE, 25)
+	act(t, g, ACTION_FOLD, 0)
+	/* bob only has 35, so going all-in is allowed even though it isn't a
+	 * full raise.
+	 */
+	act(t, g, ACTION_RAISE, 35)
+	/* carol has already acted, and the all-in wasn't a full raise, so she
+	 * can only call or fold.
+	 */
+	expectToAct(t, g, 2, STREET_PREFLOP)
+	if (g.Options().CanRaise) {
+		t.Errorf("expected an all-in for less than a full raise not to " +
+			"reopen the betting")
+	}
+	expectActError(t, g, ACTION_RAISE, 100)
+	act(t, g, ACTION_CALL, 0)
+	/* Nobody is left to bet, so the board is dealt out. */
+	if (!g.Done()) {
+		t.Fatalf("expected the hand to be over")
+	}
+	if (len(g.Board) != BOARD_MAX) {
+		t.Errorf("expected the whole board to be dealt")
+	}
+	total := int64(0)
+	for i := range(g.Seats) {
+		total += g.Seats[i].Stack
+	}
+	if (total != 2035) {
+		t.Errorf("expected 2035 chips at the end, got %d", total)
+	}
+}
+
+func TestGameShowdown(t *testing.T) {
+	/* bob is dealt first, then carol, then alice, who has the button. */
+	g := newTestGame(t, TEST_GAME_CFG, []int64 { 500, 500, 500 }, 0,
+		"AS KD 2C AH KC 7D " +
+		"3S KS 9C 4D 4H AD 5C 6C JH")
+	act(t, g, ACTION_CALL, 0)
+	act(t, g, ACTION_CALL, 0)
+	act(t, g, ACTION_CHECK, 0)
+	for ; g.Street != STREET_SHOWDOWN; {
+		act(t, g, ACTION_CHECK, 0)
+	}
+	if (g.Board.ShortString() != "KS 9C 4D AD 6C") {
+		t.Errorf("unexpected board %s", g.Board.ShortString())
+	}
+	/* bob makes three aces, which beat carol's three kings. */
+	expectStacks(t, g, []int64 { 490, 520, 490 })
+	if (g.Result.Pots[0].Winners[0].Name != "bob") {
+		t.Errorf("expected bob to win, got %v", g.Result.Pots[0].Winners)
+	}
+}
+
+func TestGameAntes(t *testing.T) {
+	cfg := GameConfig { SmallBlind: 5, BigBlind: 10, Ante: 2 }
+	g := newTestGame(t, cfg, []int64 { 1000, 1000, 1 }, 0, "")
+	if (g.Pot() != 1 + 2 + 2 + 5) {
+		t.Errorf("expected antes and blinds of 10, got %d", g.Pot())
+	}
+	if ((g.Seats[1].Bet != 5) || (g.Seats[2].Bet != 0)) {
+		t.Errorf("expected the antes not to count as bets")
+	}
+	/* carol went all-in with her ante, and couldn't post the big blind, but
+	 * the others still have to call it.
+	 */
+	expectToAct(t, g, 0, STREET_PREFLOP)
+	if (g.Options().ToCall != 10) {
+		t.Errorf("expected 10 to call, not %d", g.Options().ToCall)
+	}
+}
+
+func TestGameErrors(t *testing.T) {
+	deck := ShuffledDeck(rand.New(rand.NewSource(1)))
+	_, err := NewGame(TEST_GAME_CFG, []string { "alice" }, []int64 { 100 },
+		0, deck)
+	if (err == nil) {
+		t.Errorf("expected an error for one player")
+	}
+	_, err = NewGame(TEST_GAME_CFG, []string { "alice", "bob" },
+		[]int64 { 100, 0 }, 0, deck)
+	if (err == nil) {
+		t.Errorf("expected an error for a player with no chips")
+	}
+	_, err = NewGame(TEST_GAME_CFG, []string { "alice", "bob" },
+		[]int64 { 100, 100 }, 0, deck[:10])
+	if (err == nil) {
+		t.Errorf("expected an error for a short deck")
+	}
+}
+
+// Play random hands with random legal actions. Chips must never be created
+// or destroyed, and every hand must come to an end.
+func TestGameRandom(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	cfg := GameConfig { SmallBlind: 5, BigBlind: 10, Ante: 1 }
+	for i := 0; i < 500; i++ {
+		n := 2 + rng.Intn(5)
+		stacks := make([]int64, n)
+		total := int64(0)
+		for j := range(stacks) {
+			stacks[j] = 1 + rng.Int63n(400)
+			total += stacks[j]
+		}
+		g := newTestGame(t, cfg, stacks, rng.Intn(n), "")
+		for ; !g.Done(); {
+			opts := g.Options()
+			a := Action { ACTION_CHECK, 0 }
+			switch r := rng.Intn(4); {
+			case (r == 0) && (opts.ToCall > 0):
+				a.Ty = ACTION_FOLD
+			case (r == 1) && (opts.CanRaise):
+				a.Ty = ACTION_RAISE
+				if (g.CurrentBet == 0) {
+					a.Ty = ACTION_BET
+				}
+				a.Amount = opts.MinRaiseTo + rng.Int63n(opts.MaxRaiseTo -
+					opts.MinRaiseTo + 1)
+			case opts.ToCall > 0:
+				a.Ty = ACTION_CALL
+			}
+			act(t, g, a.Ty, a.Amount)
+		}
+		end := int64(0)
+		for j := range(g.Seats) {
+			end += g.Seats[j].Stack
+		}
+		if (end != total) {
+			t.Fatalf("expected %d chips at the end, got %d\n%s", total, end,
+				g.String())
+		}
+	}
+}
@@ -0,0 +1,273 @@
+/*
+ * Copyright 2011 Colin Patrick McCabe
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, version 2.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"html"
+	"io/ioutil"
+	"math"
+	"os"
+)
+
+const GRID_SZ = 13
+
+/* A RangeGrid holds a value between 0 and 1 for each of the 169 hand
+ * classes, laid out in the usual starting hand matrix. Aces come first in
+ * both the rows and the columns. Pairs go on the diagonal, suited hands above
+ * it, and offsuit hands below it.
+ *
+ * Has is false for classes which have no value, and are drawn blank.
+ */
+type RangeGrid struct {
+	Title string
+	Vals [GRID_SZ][GRID_SZ]float64
+	Has [GRID_SZ][GRID_SZ]bool
+}
+
+/* Returns the hand class in a cell of the grid. */
+func gridClass(row int, col int) handClass {
+	a := ACE_VAL - row
+	b := ACE_VAL - col
+	switch {
+	case row == col:
+		return handClass { a, a, CLASS_ANY }
+	case row < col:
+		return handClass { a, b, CLASS_SUITED }
+	}
+	return handClass { b, a, CLASS_OFFSUIT }
+}
+
+/* Returns the cell of the grid that a hand class goes in. */
+func gridCell(hc handClass) (int, int) {
+	hi := ACE_VAL - hc.hi
+	lo := ACE_VAL - hc.lo
+	if (hc.suited == CLASS_OFFSUIT) {
+		return lo, hi
+	}
+	return hi, lo
+}
+
+/* Returns the hand class that a combo belongs to. */
+func comboClass(combo CardSlice) handClass {
+	hc := handClass { combo[0].val, combo[1].val, CLASS_ANY }
+	if (hc.hi < hc.lo) {
+		hc.hi, hc.lo = hc.lo, hc.hi
+	}
+	if (hc.hi != hc.lo) {
+		if (combo[0].suit == combo[1].suit) {
+			hc.suited = CLASS_SUITED
+		} else {
+			hc.suited = CLASS_OFFSUIT
+		}
+	}
+	return hc
+}
+
+/* Make a grid showing how much of each hand class is in a range. A class
+ * with every combo in the range at full weight gets a value of 1.
+ */
+func NewRangeGrid(r *Range, title string) *RangeGrid {
+	g := &RangeGrid { Title: title }
+	for row := 0; row < GRID_SZ; row++ {
+		for col := 0; col < GRID_SZ; col++ {
+			ids := gridClass(row, col).comboIds()
+			total := 0.0
+			for i := range(ids) {
+				total += r.combos[ids[i]]
+			}
+			g.Vals[row][col] = total / float64(len(ids))
+			g.Has[row][col] = (total > 0)
+		}
+	}
+	return g
+}
+
+/* Make a grid showing the equity of each hand class in the first range. The
+ * equities of the combos in a class are combined, weighted by how many
+ * showdowns each took part in.
+ */
+func (res *RangeEquityResult) Grid(title string) *RangeGrid {
+	g := &RangeGrid { Title: title }
+	var share, total [GRID_SZ][GRID_SZ]float64
+	for i := range(res.Combos) {
+		ce := res.Combos[i]
+		row, col := gridCell(comboClass(ce.Cards))
+		share[row][col] += ce.Eq.Share
+		total[row][col] += ce.Eq.Total()
+	}
+	for row := 0; row < GRID_SZ; row++ {
+		for col := 0; col < GRID_SZ; col++ {
+			if (total[row][col] > 0) {
+				g.Vals[row][col] = share[row][col] / total[row][col]
+				g.Has[row][col] = true
+			}
+		}
+	}
+	return g
+}
+
+/* Returns the red, green and blue parts of the heat map color for a value.
+ * The scale runs from red at 0, through yellow, to green at 1.
+ */
+func heatColor(v float64) (int, int, int) {
+	v = math.Max(0, math.Min(1, v))
+	if (v < 0.5) {
+		return 255, int(255 * v * 2), 0
+	}
+	return int(255 * (1 - v) * 2), 255, 0
+}
+
+/* Returns the closest color to a heat map color in the 6x6x6 color cube
+ * that most terminals support.
+ */
+func heatColorAnsi(v float64) int {
+	r, g, b := heatColor(v)
+	return 16 + 36 * ((r * 5 + 127) / 255) + 6 * ((g * 5 + 127) / 255) +
+		(b * 5 + 127) / 255
+}
+
+const ANSI_RESET = "\x1b[0m"
+
+/* Render the grid for a terminal, using ANSI color escapes. */
+func (g *RangeGrid) ANSIString() string {
+	ret := g.Title + "\n"
+	for row := 0; row < GRID_SZ; row++ {
+		for col := 0; col < GRID_SZ; col++ {
+			name := gridClass(row, col).String()
+			if (g.Has[row][col]) {
+				ret += fmt.Sprintf("\x1b[30;48;5;%dm %-3s %s",
+					heatColorAnsi(g.Vals[row][col]), name, ANSI_RESET)
+			} else {
+				ret += fmt.Sprintf(" %-3s ", name)
+			}
+		}
+		ret += "\n"
+	}
+	ret += "scale: "
+	for i := 0; i <= 10; i++ {
+		ret += fmt.Sprintf("\x1b[30;48;5;%dm%4d%%%s",
+			heatColorAnsi(float64(i) / 10.0), i * 10, ANSI_RESET)
+	}
+	ret += "\n"
+	return ret
+}
+
+/* Render the grid as a standalone HTML page. */
+func (g *RangeGrid) HTML() string {
+	title := html.EscapeString(g.Title)
+	ret := "<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n" +
+		"<title>" + title + "</title>\n" +
+		"<style>\n" +
+		"body { font-family: sans-serif; }\n" +
+		"table { border-collapse: collapse; }\n" +
+		"td { width: 3.5em; height: 2.5em; border: 1px solid #999; " +
+		"text-align: center; font-size: 0.8em; }\n" +
+		"td.empty { background: #eee; color: #999; }\n" +
+		"</style>\n</head>\n<body>\n" +
+		"<h1>" + title + "</h1>\n<table>\n"
+	for row := 0; row < GRID_SZ; row++ {
+		ret += "<tr>"
+		for col := 0; col < GRID_SZ; col++ {
+			name := gridClass(row, col).String()
+			if (!g.Has[row][col]) {
+				ret += "<td class=\"empty\">" + name + "</td>"
+				continue
+			}
+			r, gr, b := heatColor(g.Vals[row][col])
+			ret += fmt.Sprintf("<td style=\"background: rgb(%d, %d, %d)\" " +
+				"title=\"%s: %.2f%%\">%s<br>%.0f%%</td>", r, gr, b, name,
+				g.Vals[row][col] * 100.0, name, g.Vals[row][col] * 100.0)
+		}
+		ret += "</tr>\n"
+	}
+	ret += "</table>\n</body>\n</html>\n"
+	return ret
+}
+
+func gridUsage(fs *flag.FlagSet) func() {
+	return func() {
+		fmt.Fprintf(os.Stderr,
+`%s grid: draw a range as a 13x13 grid of starting hands.
+
+Usage:
+%s grid [options] [range]
+
+Pairs are on the diagonal, suited hands above it, and offsuit hands below it.
+Each hand is colored by how much of it is in the range. With -vs, each hand is
+colored by its equity against the -vs range instead.
+
+The grid is drawn in color for the terminal, or with -html, written to a web
+page.
+
+Options:
+`, os.Args[0], os.Args[0])
+		fs.PrintDefaults()
+	}
+}
+
+func gridMain(args []string) {
+	fs := flag.NewFlagSet("grid", flag.ExitOnError)
+	fs.Usage = gridUsage(fs)
+	var vsStr = fs.String("vs", "", "color hands by equity against this range")
+	var boardStr = fs.String("b", "", "the board, when using -vs")
+	var samples = fs.Int("m", 0, "number of Monte Carlo samples, when " +
+		"using -vs")
+	var seed = fs.Int64("s", 1, "Monte Carlo random seed")
+	var htmlFile = fs.String("html", "", "write the grid to this HTML file")
+	fs.Parse(args)
+	if (fs.NArg() != 1) {
+		fs.Usage()
+		os.Exit(1)
+	}
+	r, err := LoadRange(fs.Arg(0))
+	if (err != nil) {
+		die(err)
+	}
+	var g *RangeGrid
+	if (*vsStr == "") {
+		g = NewRangeGrid(r, fs.Arg(0))
+	} else {
+		vs, err := LoadRange(*vsStr)
+		if (err != nil) {
+			die(err)
+		}
+		board, err := ParseCards(*boardStr, "the board")
+		if (err != nil) {
+			die(err)
+		}
+		res, err := CalcRangeEquity([]*Range { r, vs }, board, *samples,
+			*seed, Make52CardBag())
+		if (err != nil) {
+			die(err)
+		}
+		title := fmt.Sprintf("equity of %s against %s", fs.Arg(0), *vsStr)
+		if (len(board) > 0) {
+			title += " on " + board.ShortString()
+		}
+		g = res.Grid(title)
+	}
+	if (*htmlFile == "") {
+		fmt.Printf("%s", g.ANSIString())
+		return
+	}
+	err = ioutil.WriteFile(*htmlFile, []byte(g.HTML()), 0644)
+	if (err != nil) {
+		die(err)
+	}
+}
@@ -0,0 +1,94 @@
+/*
+ * Copyright 2011 Colin Patrick McCabe
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, version 2.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGridLayout(t *testing.T) {
+	seen := make(map[string] bool)
+	for row := 0; row < GRID_SZ; row++ {
+		for col := 0; col < GRID_SZ; col++ {
+			hc := gridClass(row, col)
+			seen[hc.String()] = true
+			r, c := gridCell(hc)
+			if ((r != row) || (c != col)) {
+				t.Errorf("expected %s to be at %d,%d, got %d,%d", hc,
+					row, col, r, c)
+			}
+		}
+	}
+	if (len(seen) != 169) {
+		t.Errorf("expected 169 different hand classes, got %d", len(seen))
+	}
+	if ((gridClass(0, 1).String() != "AKs") ||
+			(gridClass(1, 0).String() != "AKo") ||
+			(gridClass(12, 12).String() != "22")) {
+		t.Errorf("expected AKs above the diagonal, AKo below it, and 22 " +
+			"in the corner")
+	}
+}
+
+func TestRangeGrid(t *testing.T) {
+	r, _ := ParseRange("AKs:0.5, AsKd, QQ")
+	g := NewRangeGrid(r, "test")
+	if ((g.Vals[0][1] != 0.5) || (g.Vals[1][0] != 1.0 / 12.0) ||
+			(g.Vals[2][2] != 1.0) || (g.Has[0][0])) {
+		t.Errorf("unexpected grid values %v", g.Vals)
+	}
+	str := g.ANSIString()
+	if ((!strings.HasPrefix(str, "test\n")) ||
+			(strings.Count(str, "\n") != GRID_SZ + 2)) {
+		t.Errorf("unexpected terminal grid:\n%s", str)
+	}
+	page := g.HTML()
+	if ((strings.Count(page, "<td") != 169) ||
+			(!strings.Contains(page, "title=\"AKs: 50.00%\""))) {
+		t.Errorf("unexpected HTML grid:\n%s", page)
+	}
+}
+
+func TestEquityGrid(t *testing.T) {
+	board, _ := ParseCards("2C 7D 9H JS 3D", "the board")
+	res, err := CalcRangeEquity(parseRanges(t, "AA, KQs", "KK"), board, 0, 1,
+		Make52CardBag())
+	if (err != nil) {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	g := res.Grid("test")
+	if ((g.Vals[0][0] != 1.0) || (!g.Has[1][2]) || (g.Vals[1][2] != 0.0) ||
+			(g.Has[1][1])) {
+		t.Errorf("expected AA at 100%% and KQs at 0%%, got %v", g.Vals)
+	}
+}
+
+func TestHeatColor(t *testing.T) {
+	r, g, b := heatColor(0)
+	if ((r != 255) || (g != 0) || (b != 0)) {
+		t.Errorf("expected red at 0, got %d,%d,%d", r, g, b)
+	}
+	r, g, b = heatColor(1)
+	if ((r != 0) || (g != 255) || (b != 0)) {
+		t.Errorf("expected green at 1, got %d,%d,%d", r, g, b)
+	}
+	if ((heatColorAnsi(0) != 196) || (heatColorAnsi(1) != 46)) {
+		t.Errorf("expected ANSI colors 196 and 46, got %d and %d",
+			heatColorAnsi(0), heatColorAnsi(1))
+	}
+}
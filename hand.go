@@ -119,18 +119,27 @@ func (h *Hand) GetTy() int {
  *
  * This will return 0 sometimes even when the two Hands are not identical. This
  * reflects the way poker works.
+ *
+ * Two pair hands keep the lower pair in val[0], so the pairs are compared
+ * starting with val[1].
  */
 func (h *Hand) Compare(rhs *Hand) int {
-	c := twc(h.ty, rhs.ty,
-		twc(h.val[0], rhs.val[0],
-			twc(h.val[1], rhs.val[1], 0)))
+	var c int
+	if ((h.ty == TWO_PAIR) && (rhs.ty == TWO_PAIR)) {
+		c = twc(h.val[1], rhs.val[1], twc(h.val[0], rhs.val[0], 0))
+	} else {
+		c = twc(h.ty, rhs.ty,
+			twc(h.val[0], rhs.val[0],
+				twc(h.val[1], rhs.val[1], 0)))
+	}
 	if ((c == 0) && (handTyHasKicker(h.ty))) {
 		c = h.cards.CompareKicker(rhs.cards)
 	}
 	return c
 }
 
-/* Return true if two hands are identical.
+/* Return true if two hands are identical. The order of the cards doesn't
+ * matter.
  */
 func (h *Hand) Identical(rhs *Hand) bool {
 	c := twc(h.ty, rhs.ty,
@@ -139,7 +148,14 @@ func (h *Hand) Identical(rhs *Hand) bool {
 	if (c != 0) {
 		return false
 	}
-	return h.cards.Identical(rhs.cards)
+	if (h.flushSuit != rhs.flushSuit) {
+		return false
+	}
+	a := h.cards.Copy()
+	sort.Sort(a)
+	b := rhs.cards.Copy()
+	sort.Sort(b)
+	return a.Identical(b)
 }
 
 func MakeHandImpl(cardsIn CardSlice) *Hand {
@@ -170,10 +186,11 @@ func MakeHandImpl(cardsIn CardSlice) *Hand {
 		// Aces play both low and high in straights.
 		//
 		// This is a special case where we have a bunch of cards where the
-		// highest card is a king, and we also have an ace.
-		// In this situation, the last run of cards is actually one card longer
-		// than it might seem without taking the ace into account.
+		// lowest card is a two, and we also have an ace.
+		// In this situation, the first run of cards is actually one card
+		// longer than it might seem without taking the ace into account.
 		runLen++
+		prev = 1
 	}
 	for i := range(cards) {
 		if (prev + 1 == cards[i].val) {
@@ -239,10 +256,12 @@ func MakeHandImpl(cardsIn CardSlice) *Hand {
 			h.val[0] = freqs[3][0]
 			h.val[1] = freqs[3][1]
 			h.ty = FULL_HOUSE
+			return h
 		} else if (len(freqs[2]) > 0) {
 			h.val[0] = freqs[3][0]
 			h.val[1] = freqs[2][0]
 			h.ty = FULL_HOUSE
+			return h
 		}
 	}
 
@@ -292,6 +311,29 @@ func MakeHand(cards CardSlice) *Hand {
 	return ret
 }
 
+/* Make the best possible Hand out of 5 or more cards, by trying every
+ * combination of HAND_SZ cards.
+ */
+func MakeBestHand(cards CardSlice) *Hand {
+	var best *Hand
+	chooser := NewSubsetChooser(uint(len(cards)), HAND_SZ)
+	sub := make(CardSlice, HAND_SZ)
+	for ;; {
+		cur := chooser.Cur()
+		for i := range(cur) {
+			sub[i] = cards[cur[i]]
+		}
+		h := MakeHand(sub)
+		if ((best == nil) || (h.Compare(best) > 0)) {
+			best = h
+		}
+		if (!chooser.Next()) {
+			break
+		}
+	}
+	return best
+}
+
 
 func (h *Hand) String() string {
 	ret := "Hand(ty:"
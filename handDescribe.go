@@ -0,0 +1,348 @@
+/*
+ * Copyright 2011 Colin Patrick McCabe
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, version 2.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+/* Make the best hand out of 5 to 7 cards. */
+func BestHand(cards CardSlice) (*Hand, error) {
+	if ((len(cards) < HAND_SZ) || (len(cards) > SPREAD_MAX)) {
+		return nil, fmt.Errorf("a hand is made from %d to %d cards, not %d.",
+			HAND_SZ, SPREAD_MAX, len(cards))
+	}
+	dupe := cards.HasDuplicates()
+	if (dupe != nil) {
+		return nil, fmt.Errorf("the card %s appears more than once.",
+			dupe.String())
+	}
+	return MakeBestHand(cards), nil
+}
+
+/* Sorts cards by how much they matter to the hand: cards that make up the
+ * biggest group of the same value come first, then higher cards first.
+ */
+type significanceSort struct {
+	cards CardSlice
+	cnt [ACE_VAL + 1]int
+}
+
+func (s *significanceSort) Len() int {
+	return len(s.cards)
+}
+
+func (s *significanceSort) Less(i, j int) bool {
+	a := s.cards[i]
+	b := s.cards[j]
+	if (s.cnt[a.val] != s.cnt[b.val]) {
+		return s.cnt[a.val] > s.cnt[b.val]
+	}
+	if (a.val != b.val) {
+		return a.val > b.val
+	}
+	return a.suit > b.suit
+}
+
+func (s *significanceSort) Swap(i, j int) {
+	s.cards[i], s.cards[j] = s.cards[j], s.cards[i]
+}
+
+/* Returns the five cards that make up the hand, in the order a dealer would
+ * read them out: the biggest group of cards of the same value first, then the
+ * rest from highest to lowest. The ace in a five-high straight goes last.
+ */
+func (h *Hand) BestFive() CardSlice {
+	s := &significanceSort { cards: h.cards.Copy() }
+	for i := range(s.cards) {
+		s.cnt[s.cards[i].val]++
+	}
+	sort.Sort(s)
+	cards := s.cards
+	if (((h.ty == STRAIGHT) || (h.ty == STRAIGHT_FLUSH)) &&
+			(cards[0].val == ACE_VAL) && (cards[1].val == 5)) {
+		cards = append(cards[1:], cards[0])
+	}
+	return cards
+}
+
+func cardValToName(v int) string {
+	switch (v) {
+	case 2:
+		return "two"
+	case 3:
+		return "three"
+	case 4:
+		return "four"
+	case 5:
+		return "five"
+	case 6:
+		return "six"
+	case 7:
+		return "seven"
+	case 8:
+		return "eight"
+	case 9:
+		return "nine"
+	case 10:
+		return "ten"
+	case JACK_VAL:
+		return "jack"
+	case QUEEN_VAL:
+		return "queen"
+	case KING_VAL:
+		return "king"
+	case ACE_VAL:
+		return "ace"
+	}
+	panic(fmt.Sprintf("unexpected card value %d", v))
+}
+
+func cardValToPluralName(v int) string {
+	if (v == 6) {
+		return "sixes"
+	}
+	return cardValToName(v) + "s"
+}
+
+func suitToName(s int) string {
+	switch (s) {
+	case DIAMONDS:
+		return "diamonds"
+	case CLUBS:
+		return "clubs"
+	case HEARTS:
+		return "hearts"
+	case SPADES:
+		return "spades"
+	}
+	panic(fmt.Sprintf("invalid suit %d", s))
+}
+
+func capitalize(str string) string {
+	return strings.ToUpper(str[:1]) + str[1:]
+}
+
+func uncapitalize(str string) string {
+	return strings.ToLower(str[:1]) + str[1:]
+}
+
+/* One of the things that two hands of the same type are compared by, in
+ * order: for example, the pair, then each of the kickers.
+ */
+type handPart struct {
+	name string
+	val int
+	plural bool
+}
+
+func (p handPart) valName() string {
+	if (p.plural) {
+		return cardValToPluralName(p.val)
+	}
+	return cardValToName(p.val)
+}
+
+var ORDINALS = []string { "first", "second", "third", "fourth", "fifth" }
+
+/* Returns the parts of the hand, in the order they are compared. */
+func (h *Hand) parts() []handPart {
+	cards := h.BestFive()
+	var groups []int
+	for i := range(cards) {
+		if ((i == 0) || (cards[i].val != cards[i-1].val)) {
+			groups = append(groups, cards[i].val)
+		}
+	}
+	kickers := func(vals []int) []handPart {
+		var ret []handPart
+		for i := range(vals) {
+			name := "kicker"
+			if (len(vals) > 1) {
+				name = ORDINALS[i] + " kicker"
+			}
+			ret = append(ret, handPart { name, vals[i], false })
+		}
+		return ret
+	}
+	switch (h.ty) {
+	case STRAIGHT_FLUSH, STRAIGHT:
+		return []handPart { { "top card", cards[0].val, false } }
+	case FOUR_OF_A_KIND:
+		return append([]handPart { { "four of a kind", groups[0], true } },
+			kickers(groups[1:])...)
+	case FULL_HOUSE:
+		return []handPart { { "three of a kind", groups[0], true },
+			{ "pair", groups[1], true } }
+	case THREE_OF_A_KIND:
+		return append([]handPart { { "three of a kind", groups[0], true } },
+			kickers(groups[1:])...)
+	case TWO_PAIR:
+		return append([]handPart { { "top pair", groups[0], true },
+			{ "bottom pair", groups[1], true } }, kickers(groups[2:])...)
+	case PAIR:
+		return append([]handPart { { "pair", groups[0], true } },
+			kickers(groups[1:])...)
+	}
+	var ret []handPart
+	for i := range(cards) {
+		ret = append(ret, handPart { ORDINALS[i] + " card", cards[i].val,
+			false })
+	}
+	return ret
+}
+
+/* Returns the names of the kickers joined together, like "ace-king-nine
+ * kickers".
+ */
+func kickersToStr(parts []handPart) string {
+	var names []string
+	for i := range(parts) {
+		names = append(names, cardValToName(parts[i].val))
+	}
+	ret := strings.Join(names, "-") + " kicker"
+	if (len(parts) > 1) {
+		ret += "s"
+	}
+	return ret
+}
+
+/* Describe the hand the way a player would, like "Kings full of sevens" or
+ * "Pair of queens, ace-king-nine kickers".
+ */
+func (h *Hand) Describe() string {
+	p := h.parts()
+	switch (h.ty) {
+	case STRAIGHT_FLUSH:
+		if (p[0].val == ACE_VAL) {
+			return "Royal flush, " + suitToName(h.flushSuit)
+		}
+		return capitalize(p[0].valName()) + "-high straight flush, " +
+			suitToName(h.flushSuit)
+	case FOUR_OF_A_KIND:
+		return "Four " + p[0].valName() + ", " + kickersToStr(p[1:])
+	case FULL_HOUSE:
+		return capitalize(p[0].valName()) + " full of " + p[1].valName()
+	case FLUSH:
+		return capitalize(p[0].valName()) + "-high flush, " +
+			suitToName(h.flushSuit)
+	case STRAIGHT:
+		return capitalize(p[0].valName()) + "-high straight"
+	case THREE_OF_A_KIND:
+		return "Three " + p[0].valName() + ", " + kickersToStr(p[1:])
+	case TWO_PAIR:
+		return "Two pair, " + p[0].valName() + " and " + p[1].valName() +
+			", " + kickersToStr(p[2:])
+	case PAIR:
+		return "Pair of " + p[0].valName() + ", " + kickersToStr(p[1:])
+	}
+	var names []string
+	for i := 1; i < len(p); i++ {
+		names = append(names, p[i].valName())
+	}
+	return capitalize(p[0].valName()) + " high, " + strings.Join(names, "-")
+}
+
+/* Compare two hands, like Hand.Compare, and explain the result: which hand
+ * type won, or which card decided it when both hands are of the same type.
+ */
+func ExplainCompare(a *Hand, b *Hand) (int, string) {
+	c := a.Compare(b)
+	if (c == 0) {
+		return 0, "Tie: " + uncapitalize(a.Describe()) + " for both hands"
+	}
+	win, lose := a, b
+	if (c < 0) {
+		win, lose = b, a
+	}
+	ret := win.Describe() + " beats " + uncapitalize(lose.Describe()) + ": "
+	if (win.ty != lose.ty) {
+		return c, ret + HandTyToStr(win.ty) + " beats " + HandTyToStr(lose.ty)
+	}
+	wp := win.parts()
+	lp := lose.parts()
+	for i := range(wp) {
+		if (wp[i].val != lp[i].val) {
+			return c, ret + fmt.Sprintf("the %s decides it, %s against %s",
+				wp[i].name, wp[i].valName(), lp[i].valName())
+		}
+	}
+	panic(fmt.Sprintf("hands compare as %d, but all their parts are the same",
+		c))
+}
+
+func compareUsage(fs *flag.FlagSet) func() {
+	return func() {
+		fmt.Fprintf(os.Stderr,
+`%s compare: compare two hands, and explain which one wins.
+
+Usage:
+%s compare [options] [cards 1] [cards 2]
+
+Each hand is made of its own cards plus the board. For example,
+%s compare -b "QS 10S 7D 4C 2H" "QH 9C" "QD 8D"
+shows the best five cards of each hand, and that the pair of queens with the
+nine kicker wins.
+
+Options:
+`, os.Args[0], os.Args[0], os.Args[0])
+		fs.PrintDefaults()
+	}
+}
+
+func compareMain(args []string) {
+	fs := flag.NewFlagSet("compare", flag.ExitOnError)
+	fs.Usage = compareUsage(fs)
+	var boardStr = fs.String("b", "", "the board")
+	fs.Parse(args)
+	if (fs.NArg() != 2) {
+		fs.Usage()
+		os.Exit(1)
+	}
+	board, err := ParseCards(*boardStr, "the board")
+	if (err != nil) {
+		die(err)
+	}
+	var holes [2]CardSlice
+	known := board.Copy()
+	for i := range(holes) {
+		holes[i], err = ParseCards(fs.Arg(i), fmt.Sprintf("hand %d", i + 1))
+		if (err != nil) {
+			die(err)
+		}
+		known = append(known, holes[i]...)
+	}
+	dupe := known.HasDuplicates()
+	if (dupe != nil) {
+		die(fmt.Errorf("the card %s appears more than once.", dupe.String()))
+	}
+	var hands [2]*Hand
+	for i := range(hands) {
+		hands[i], err = BestHand(append(holes[i].Copy(), board...))
+		if (err != nil) {
+			die(err)
+		}
+		fmt.Printf("%s: %s (%s)\n", fs.Arg(i), hands[i].Describe(),
+			hands[i].BestFive().ShortString())
+	}
+	_, why := ExplainCompare(hands[0], hands[1])
+	fmt.Printf("%s\n", why)
+}
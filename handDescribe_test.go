@@ -0,0 +1,129 @@
+/*
+ * Copyright 2011 Colin Patrick McCabe
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, version 2.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package main
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func bestHandOf(t *testing.T, str string) *Hand {
+	cards, err := ParseCards(str, "the cards")
+	if (err != nil) {
+		t.Fatalf("%s", err.Error())
+	}
+	h, err := BestHand(cards)
+	if (err != nil) {
+		t.Fatalf("%s", err.Error())
+	}
+	return h
+}
+
+func expectDescribe(t *testing.T, str string, eFive string, eDesc string) {
+	h := bestHandOf(t, str)
+	five := h.BestFive().ShortString()
+	if (five != eFive) {
+		t.Errorf("expected the best five cards of %s to be %s, got %s",
+			str, eFive, five)
+	}
+	desc := h.Describe()
+	if (desc != eDesc) {
+		t.Errorf("expected %s to be described as '%s', got '%s'",
+			str, eDesc, desc)
+	}
+}
+
+func TestDescribe(t *testing.T) {
+	expectDescribe(t, "AH 2H 7H KC 9H 4H 3S",
+		"AH 9H 7H 4H 2H", "Ace-high flush, hearts")
+	expectDescribe(t, "KS 7C KD 2S 7H KH QD",
+		"KS KH KD 7H 7C", "Kings full of sevens")
+	expectDescribe(t, "QS AD 9C 4H QH KC 2D",
+		"QS QH AD KC 9C", "Pair of queens, ace-king-nine kickers")
+	expectDescribe(t, "8S 8D AC AH 3S 3D KC",
+		"AH AC 8S 8D KC", "Two pair, aces and eights, king kicker")
+	expectDescribe(t, "AS 2D 3C 4H 5D KD",
+		"5D 4H 3C 2D AS", "Five-high straight")
+	expectDescribe(t, "10S JS QS KS AS 9S",
+		"AS KS QS JS 10S", "Royal flush, spades")
+	expectDescribe(t, "6S 6D 6C 6H 2D 9C",
+		"6S 6H 6C 6D 9C", "Four sixes, nine kicker")
+	expectDescribe(t, "JS JD JC 4H 9D",
+		"JS JC JD 9D 4H", "Three jacks, nine-four kickers")
+	expectDescribe(t, "AH KD 9H 6S 4C 3S 2D",
+		"AH KD 9H 6S 4C", "Ace high, king-nine-six-four")
+}
+
+func TestBestHandErrors(t *testing.T) {
+	cards, _ := ParseCards("AS KS QS JS", "the cards")
+	_, err := BestHand(cards)
+	if (err == nil) {
+		t.Errorf("expected an error for a four card hand")
+	}
+	cards, _ = ParseCards("AS KS QS JS AS", "the cards")
+	_, err = BestHand(cards)
+	if (err == nil) {
+		t.Errorf("expected an error for a hand with the same card twice")
+	}
+}
+
+func TestExplainCompare(t *testing.T) {
+	a := bestHandOf(t, "QH 9C QS 10S 7D 4C 2H")
+	b := bestHandOf(t, "QD 8D QS 10S 7D 4C 2H")
+	c, why := ExplainCompare(b, a)
+	if (c != -1) {
+		t.Errorf("expected the nine kicker to win, got %d", c)
+	}
+	if (!strings.HasSuffix(why, "the second kicker decides it, nine " +
+			"against eight")) {
+		t.Errorf("unexpected explanation '%s'", why)
+	}
+	if (!strings.HasPrefix(why, "Pair of queens, ten-nine-seven kickers " +
+			"beats")) {
+		t.Errorf("expected the winner to be described first, got '%s'", why)
+	}
+	f := bestHandOf(t, "AH 2H 7H KC 9H 4H 3S")
+	_, why = ExplainCompare(a, f)
+	if (!strings.HasSuffix(why, "a flush beats a pair")) {
+		t.Errorf("unexpected explanation '%s'", why)
+	}
+	w1 := bestHandOf(t, "5S 9C AS 2D 3C 4H KD")
+	w2 := bestHandOf(t, "5D KH AS 2D 3C 4H KD")
+	c, why = ExplainCompare(w1, w2)
+	if ((c != 0) || (!strings.HasPrefix(why, "Tie"))) {
+		t.Errorf("expected a tie, got %d: '%s'", c, why)
+	}
+}
+
+// ExplainCompare must find a deciding card whenever Hand.Compare does.
+func TestExplainCompareRandom(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	deck := Make52CardBag()
+	for i := 0; i < 3000; i++ {
+		a := MakeBestHand(randomCards(rng, deck, 5 + rng.Intn(3)))
+		b := MakeBestHand(randomCards(rng, deck, 5 + rng.Intn(3)))
+		c, why := ExplainCompare(a, b)
+		if (c != a.Compare(b)) {
+			t.Fatalf("ExplainCompare and Hand.Compare disagree about " +
+				"%s vs %s", a, b)
+		}
+		if (why == "") {
+			t.Fatalf("expected an explanation of %s vs %s", a, b)
+		}
+	}
+}
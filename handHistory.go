@@ -0,0 +1,430 @@
+/*
+ * Copyright 2011 Colin Patrick McCabe
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, version 2.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+/* Hand histories in the format PokerStars writes them, like this:
+ *
+ * PokerStars Hand #1001: Hold'em No Limit ($0.01/$0.02 USD) - ...
+ * Table 'Alpha' 6-max Seat #1 is the button
+ * Seat 1: alice ($2.00 in chips)
+ * Seat 2: bob ($2.00 in chips)
+ * alice: posts small blind $0.01
+ * bob: posts big blind $0.02
+ * *** HOLE CARDS ***
+ * Dealt to alice [Ah Kh]
+ * alice: raises $0.04 to $0.06
+ * ...
+ *
+ * Amounts, whether they are dollars or tournament chips, are kept in
+ * hundredths, so that adding them up is exact.
+ */
+
+/* A player in a hand history. Hole is nil if we never saw their cards. */
+type HHPlayer struct {
+	Seat int
+	Name string
+	Stack int64
+	Hole CardSlice
+	Invested int64
+	Collected int64
+	Folded bool
+	streetBet int64
+}
+
+/* Returns what the player won or lost in the hand. */
+func (p *HHPlayer) Net() int64 {
+	return p.Collected - p.Invested
+}
+
+/* Something a player did. Verb is "posts", "folds", "checks", "calls",
+ * "bets" or "raises". Amount is the chips put in by the action, including
+ * the part of a raise that calls the bet before it.
+ */
+type HHAction struct {
+	Street int
+	Player string
+	Verb string
+	Amount int64
+	AllIn bool
+}
+
+/* One hand. AllInStreet is the street on which everyone left in the hand
+ * was all-in, except perhaps one player who had covered them, or -1 if that
+ * never happened. AllInBoard is the number of board cards dealt by then.
+ */
+type HandHistory struct {
+	Id string
+	SmallBlind int64
+	BigBlind int64
+	Button int
+	Players []*HHPlayer
+	Board CardSlice
+	Actions []HHAction
+	AllInStreet int
+	AllInBoard int
+	street int
+	inSummary bool
+}
+
+var hhHeaderRe = regexp.MustCompile(`^PokerStars .*?(?:Hand|Game) #(\d+):`)
+var hhBlindsRe = regexp.MustCompile(`\(([^()/ ]+)/([^()/ ]+)`)
+var hhButtonRe = regexp.MustCompile(`Seat #(\d+) is the button`)
+var hhSeatRe = regexp.MustCompile(`^Seat (\d+): (.+) \((\S+) in chips`)
+var hhSittingOutRe = regexp.MustCompile(
+	`in chips.*\) (?:is sitting out|out of hand)`)
+var hhPostRe = regexp.MustCompile(
+	`^(.+): posts (small blind|big blind|the ante|small & big blinds) (\S+)`)
+var hhDealtRe = regexp.MustCompile(`^Dealt to (.+?) \[([^\]]+)\]`)
+var hhActionRe = regexp.MustCompile(
+	`^(.+): (folds|checks|calls|bets|raises)(?: (\S+))?(?: to (\S+))?`)
+var hhStreetRe = regexp.MustCompile(`^\*\*\* (.+?) \*\*\*(.*)$`)
+var hhUncalledRe = regexp.MustCompile(`^Uncalled bet \((\S+)\) returned to (.+)$`)
+var hhShowsRe = regexp.MustCompile(`^(.+): shows \[([^\]]+)\]`)
+var hhCollectedRe = regexp.MustCompile(`^(.+) collected (\S+) from`)
+var hhSummaryShowRe = regexp.MustCompile(
+	`^Seat \d+: (.+?)(?: \([^)]*\))* (?:showed|mucked) \[([^\]]+)\]`)
+var hhCardsRe = regexp.MustCompile(`\[([^\]]+)\]`)
+
+/* Parse an amount like $1,234.50 or 1500 into hundredths. */
+func parseHHAmount(str string) (int64, error) {
+	str = strings.TrimLeft(str, "$€£")
+	str = strings.Replace(str, ",", "", -1)
+	f, err := strconv.ParseFloat(str, 64)
+	if ((err != nil) || (f < 0)) {
+		return 0, fmt.Errorf("can't understand the amount '%s'.", str)
+	}
+	return int64(math.Round(f * 100)), nil
+}
+
+/* Write an amount in hundredths. */
+func hhAmountToStr(amount float64) string {
+	return fmt.Sprintf("%.2f", amount / 100.0)
+}
+
+/* Parse cards written like "Ah Td 2c". */
+func parseHHCards(str string) (CardSlice, error) {
+	var ret CardSlice
+	toks := strings.Fields(str)
+	for i := range(toks) {
+		tok := toks[i]
+		if (len(tok) != 2) {
+			return nil, fmt.Errorf("can't understand the card '%s'.", tok)
+		}
+		val := rangeValFromChar(tok[0])
+		suit := rangeSuitFromChar(tok[1])
+		if ((val == -1) || (suit == -1)) {
+			return nil, fmt.Errorf("can't understand the card '%s'.", tok)
+		}
+		ret = append(ret, &Card { val, suit })
+	}
+	return ret, nil
+}
+
+/* Start a hand from its header. Returns nil if the hand isn't Hold'em, since
+ * a session log may mix in other games which we can't evaluate.
+ */
+func newHandHistory(id string, header string) (*HandHistory, error) {
+	if (!strings.Contains(header, "Hold'em")) {
+		return nil, nil
+	}
+	hh := &HandHistory { Id: id, AllInStreet: -1 }
+	m := hhBlindsRe.FindStringSubmatch(header)
+	if (m == nil) {
+		return nil, fmt.Errorf("can't find the blinds in '%s'.", header)
+	}
+	var err error
+	hh.SmallBlind, err = parseHHAmount(m[1])
+	if (err != nil) {
+		return nil, err
+	}
+	hh.BigBlind, err = parseHHAmount(m[2])
+	if (err != nil) {
+		return nil, err
+	}
+	return hh, nil
+}
+
+func (hh *HandHistory) player(name string) *HHPlayer {
+	for i := range(hh.Players) {
+		if (hh.Players[i].Name == name) {
+			return hh.Players[i]
+		}
+	}
+	return nil
+}
+
+func (hh *HandHistory) setHole(name string, cardsStr string) error {
+	p := hh.player(name)
+	if ((p == nil) || (p.Hole != nil)) {
+		return nil
+	}
+	cards, err := parseHHCards(cardsStr)
+	if (err != nil) {
+		return err
+	}
+	if (len(cards) != HOLE_SZ) {
+		return fmt.Errorf("%s should have %d hole cards, not %d.", name,
+			HOLE_SZ, len(cards))
+	}
+	p.Hole = cards
+	return nil
+}
+
+/* Put chips in for a player. Live chips count towards their bet on this
+ * street; dead ones, like antes, don't.
+ */
+func (hh *HandHistory) put(p *HHPlayer, live int64, dead int64) {
+	p.Invested += live + dead
+	p.streetBet += live
+}
+
+/* See whether everyone left in the hand is all-in, apart from perhaps one
+ * player who has matched the biggest bet.
+ */
+func (hh *HandHistory) checkAllIn() {
+	live := 0
+	var withChips []*HHPlayer
+	maxBet := int64(0)
+	for i := range(hh.Players) {
+		p := hh.Players[i]
+		if (p.Folded) {
+			continue
+		}
+		live++
+		if (p.Stack > p.Invested) {
+			withChips = append(withChips, p)
+		}
+		if (p.streetBet > maxBet) {
+			maxBet = p.streetBet
+		}
+	}
+	if ((live >= 2) && ((len(withChips) == 0) || ((len(withChips) == 1) &&
+			(withChips[0].streetBet >= maxBet)))) {
+		if (hh.AllInStreet == -1) {
+			hh.AllInStreet = hh.street
+			hh.AllInBoard = len(hh.Board)
+		}
+	} else {
+		hh.AllInStreet = -1
+	}
+}
+
+func (hh *HandHistory) parseStreet(name string, rest string) error {
+	switch (name) {
+	case "HOLE CARDS":
+		hh.street = STREET_PREFLOP
+		return nil
+	case "FLOP", "TURN", "RIVER":
+		m := hhCardsRe.FindAllStringSubmatch(rest, -1)
+		if (m == nil) {
+			return fmt.Errorf("no cards were dealt on the %s.",
+				strings.ToLower(name))
+		}
+		cards, err := parseHHCards(m[len(m) - 1][1])
+		if (err != nil) {
+			return err
+		}
+		hh.Board = append(hh.Board, cards...)
+		hh.street++
+	case "SHOW DOWN":
+		hh.street = STREET_SHOWDOWN
+	case "SUMMARY":
+		hh.inSummary = true
+	default:
+		return fmt.Errorf("can't handle the '%s' part of a hand.", name)
+	}
+	for i := range(hh.Players) {
+		hh.Players[i].streetBet = 0
+	}
+	return nil
+}
+
+func (hh *HandHistory) parseAction(p *HHPlayer, verb string, amountStr string,
+		toStr string, line string) error {
+	var amount int64
+	var err error
+	if (amountStr != "") {
+		amount, err = parseHHAmount(amountStr)
+		if (err != nil) {
+			return err
+		}
+	}
+	switch (verb) {
+	case "folds":
+		p.Folded = true
+	case "calls", "bets":
+		hh.put(p, amount, 0)
+	case "raises":
+		to, err := parseHHAmount(toStr)
+		if (err != nil) {
+			return err
+		}
+		amount = to - p.streetBet
+		hh.put(p, amount, 0)
+	}
+	hh.Actions = append(hh.Actions, HHAction { hh.street, p.Name, verb,
+		amount, strings.HasSuffix(line, "and is all-in") })
+	hh.checkAllIn()
+	return nil
+}
+
+func (hh *HandHistory) parseLine(line string) error {
+	var m []string
+	if (hh.inSummary) {
+		m = hhSummaryShowRe.FindStringSubmatch(line)
+		if (m != nil) {
+			return hh.setHole(m[1], m[2])
+		}
+		return nil
+	}
+	m = hhStreetRe.FindStringSubmatch(line)
+	if (m != nil) {
+		return hh.parseStreet(m[1], m[2])
+	}
+	m = hhSeatRe.FindStringSubmatch(line)
+	if (m != nil) {
+		/* Players who are sitting out aren't dealt in. */
+		if (hhSittingOutRe.MatchString(line)) {
+			return nil
+		}
+		seat, _ := strconv.Atoi(m[1])
+		stack, err := parseHHAmount(m[3])
+		if (err != nil) {
+			return err
+		}
+		hh.Players = append(hh.Players, &HHPlayer { Seat: seat, Name: m[2],
+			Stack: stack })
+		return nil
+	}
+	m = hhButtonRe.FindStringSubmatch(line)
+	if (m != nil) {
+		hh.Button, _ = strconv.Atoi(m[1])
+		return nil
+	}
+	m = hhDealtRe.FindStringSubmatch(line)
+	if (m != nil) {
+		return hh.setHole(m[1], m[2])
+	}
+	m = hhShowsRe.FindStringSubmatch(line)
+	if (m != nil) {
+		return hh.setHole(m[1], m[2])
+	}
+	m = hhUncalledRe.FindStringSubmatch(line)
+	if (m != nil) {
+		p := hh.player(m[2])
+		if (p == nil) {
+			return fmt.Errorf("the uncalled bet went to '%s', who isn't " +
+				"in the hand.", m[2])
+		}
+		amount, err := parseHHAmount(m[1])
+		if (err != nil) {
+			return err
+		}
+		p.Invested -= amount
+		return nil
+	}
+	m = hhCollectedRe.FindStringSubmatch(line)
+	if (m != nil) {
+		p := hh.player(m[1])
+		if (p != nil) {
+			amount, err := parseHHAmount(m[2])
+			if (err != nil) {
+				return err
+			}
+			p.Collected += amount
+		}
+		return nil
+	}
+	m = hhPostRe.FindStringSubmatch(line)
+	if (m != nil) {
+		p := hh.player(m[1])
+		if (p == nil) {
+			return nil
+		}
+		amount, err := parseHHAmount(m[3])
+		if (err != nil) {
+			return err
+		}
+		switch (m[2]) {
+		case "the ante":
+			hh.put(p, 0, amount)
+		case "small & big blinds":
+			hh.put(p, hh.BigBlind, amount - hh.BigBlind)
+		default:
+			hh.put(p, amount, 0)
+		}
+		hh.Actions = append(hh.Actions, HHAction { hh.street, p.Name,
+			"posts", amount, strings.HasSuffix(line, "and is all-in") })
+		hh.checkAllIn()
+		return nil
+	}
+	/* Names are checked, so that chat like 'bob said, "carol: folds"'
+	 * isn't taken for an action.
+	 */
+	m = hhActionRe.FindStringSubmatch(line)
+	if (m != nil) {
+		p := hh.player(m[1])
+		if (p != nil) {
+			return hh.parseAction(p, m[2], m[3], m[4], line)
+		}
+	}
+	return nil
+}
+
+/* Parse every hand in a file of PokerStars hand histories. Lines which
+ * don't matter to us, like chat, are skipped, and so are hands of games other
+ * than Hold'em.
+ */
+func ParseHandHistories(in io.Reader) ([]*HandHistory, error) {
+	var ret []*HandHistory
+	var hh *HandHistory
+	scanner := bufio.NewScanner(in)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(strings.TrimPrefix(scanner.Text(),
+			"\ufeff"))
+		var err error
+		m := hhHeaderRe.FindStringSubmatch(line)
+		if (m != nil) {
+			hh, err = newHandHistory(m[1], line)
+			if ((err == nil) && (hh != nil)) {
+				ret = append(ret, hh)
+			}
+		} else if ((hh != nil) && (line != "")) {
+			err = hh.parseLine(line)
+		}
+		if (err != nil) {
+			return nil, fmt.Errorf("line %d: %s", lineNo, err.Error())
+		}
+	}
+	err := scanner.Err()
+	if (err != nil) {
+		return nil, err
+	}
+	return ret, nil
+}
@@ -0,0 +1,292 @@
+/*
+ * Copyright 2011 Colin Patrick McCabe
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, version 2.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package main
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+const TEST_HAND_HISTORIES = `PokerStars Hand #1001: Hold'em No Limit ($0.01/$0.02 USD) - 2011/01/01 12:00:00 ET
+Table 'Alpha' 6-max Seat #1 is the button
+Seat 1: alice ($2.00 in chips)
+Seat 2: bob ($1.50 in chips)
+Seat 3: carol ($2.00 in chips)
+Seat 4: dave ($2.00 in chips) is sitting out
+bob: posts small blind $0.01
+carol: posts big blind $0.02
+*** HOLE CARDS ***
+Dealt to alice [Ah Kh]
+alice: raises $0.04 to $0.06
+bob: calls $0.05
+carol: folds
+*** FLOP *** [Kd 7c 2h]
+bob: bets $0.10
+alice: raises $1.84 to $1.94 and is all-in
+bob: calls $1.34 and is all-in
+Uncalled bet ($0.50) returned to alice
+*** TURN *** [Kd 7c 2h] [3s]
+*** RIVER *** [Kd 7c 2h 3s] [7d]
+*** SHOW DOWN ***
+bob: shows [7h 7s] (four of a kind, Sevens)
+alice: shows [Ah Kh] (two pair, Kings and Sevens)
+bob collected $2.97 from pot
+*** SUMMARY ***
+Total pot $3.02 | Rake $0.05
+Board [Kd 7c 2h 3s 7d]
+Seat 1: alice (button) showed [Ah Kh] and lost with two pair, Kings and Sevens
+Seat 2: bob (small blind) showed [7h 7s] and won ($2.97) with four of a kind, Sevens
+Seat 3: carol (big blind) folded before Flop
+
+
+
+PokerStars Hand #1002: Tournament #55, $1+$0.10 USD Hold'em No Limit - Level II (15/30) - 2011/01/01 12:05:00 ET
+Table '55 1' 9-max Seat #2 is the button
+Seat 1: alice (1500 in chips)
+Seat 2: bob (1,200 in chips)
+Seat 3: carol (900 in chips)
+alice: posts the ante 5
+bob: posts the ante 5
+carol: posts the ante 5
+carol: posts small blind 15
+alice: posts big blind 30
+*** HOLE CARDS ***
+Dealt to alice [2c 2d]
+bob said, "alice: folds"
+bob: raises 60 to 90
+carol: folds
+alice: calls 60
+*** FLOP *** [Qs 8h 4c]
+alice: checks
+bob: bets 100
+alice: folds
+Uncalled bet (100) returned to bob
+bob collected 210 from pot
+*** SUMMARY ***
+Total pot 210 | Rake 0
+
+
+
+PokerStars Hand #1003: Hold'em No Limit ($0.01/$0.02 USD) - 2011/01/01 12:10:00 ET
+Table 'Alpha' 6-max Seat #3 is the button
+Seat 1: alice ($1.00 in chips)
+Seat 2: bob ($0.50 in chips)
+Seat 3: carol ($2.00 in chips)
+alice: posts small blind $0.01
+bob: posts big blind $0.02
+*** HOLE CARDS ***
+carol: calls $0.02
+alice: calls $0.01
+bob: checks
+*** FLOP *** [2s 3s 9d]
+alice: checks
+bob: checks
+carol: checks
+*** TURN *** [2s 3s 9d] [Kc]
+alice: bets $0.98 and is all-in
+bob: calls $0.48 and is all-in
+carol: calls $0.98
+*** RIVER *** [2s 3s 9d Kc] [Qh]
+*** SHOW DOWN ***
+alice: shows [As 4s] (high card Ace)
+bob: shows [Kd Kh] (three of a kind, Kings)
+carol: shows [9c 9h] (three of a kind, Nines)
+bob collected $1.50 from main pot
+carol collected $1.00 from side pot
+*** SUMMARY ***
+Total pot $2.50 Main pot $1.50. Side pot $1.00. | Rake $0
+`
+
+func parseTestHistories(t *testing.T) []*HandHistory {
+	hands, err := ParseHandHistories(strings.NewReader(TEST_HAND_HISTORIES))
+	if (err != nil) {
+		t.Fatalf("%s", err.Error())
+	}
+	if (len(hands) != 3) {
+		t.Fatalf("expected 3 hands, got %d", len(hands))
+	}
+	return hands
+}
+
+func expectInvested(t *testing.T, hh *HandHistory, eInvested []int64,
+		eCollected []int64) {
+	for i := range(eInvested) {
+		p := hh.Players[i]
+		if ((p.Invested != eInvested[i]) || (p.Collected != eCollected[i])) {
+			t.Errorf("hand #%s: expected %s to put in %d and collect %d, " +
+				"but they put in %d and collected %d", hh.Id, p.Name,
+				eInvested[i], eCollected[i], p.Invested, p.Collected)
+		}
+	}
+}
+
+func TestParseHandHistories(t *testing.T) {
+	hands := parseTestHistories(t)
+	hh := hands[0]
+	if ((hh.Id != "1001") || (hh.Button != 1) || (hh.BigBlind != 2)) {
+		t.Errorf("unexpected header %s, button %d, big blind %d", hh.Id,
+			hh.Button, hh.BigBlind)
+	}
+	if (len(hh.Players) != 3) {
+		t.Errorf("expected dave, who is sitting out, not to be dealt in, " +
+			"but got %d players", len(hh.Players))
+	}
+	if (hh.Board.ShortString() != "KD 7C 2H 3S 7D") {
+		t.Errorf("unexpected board %s", hh.Board.ShortString())
+	}
+	if (hh.Players[1].Hole.ShortString() != "7H 7S") {
+		t.Errorf("expected bob to show 7H 7S")
+	}
+	if ((hh.AllInStreet != STREET_FLOP) || (hh.AllInBoard != 3)) {
+		t.Errorf("expected an all-in on the flop, got street %d",
+			hh.AllInStreet)
+	}
+	expectInvested(t, hh, []int64 { 150, 150, 2 }, []int64 { 0, 297, 0 })
+
+	hh = hands[1]
+	if ((hh.SmallBlind != 1500) || (hh.BigBlind != 3000)) {
+		t.Errorf("expected blinds of 15/30, got %d/%d", hh.SmallBlind,
+			hh.BigBlind)
+	}
+	if (hh.Players[1].Stack != 120000) {
+		t.Errorf("expected bob to have 1,200, got %d", hh.Players[1].Stack)
+	}
+	/* The chat line must not be taken for alice folding. */
+	if (hh.Actions[5].Verb != "raises") {
+		t.Errorf("expected bob's raise to follow the blinds, got %v",
+			hh.Actions[5])
+	}
+	expectInvested(t, hh, []int64 { 9500, 9500, 2000 },
+		[]int64 { 0, 21000, 0 })
+	if (hh.AllInStreet != -1) {
+		t.Errorf("expected no all-in")
+	}
+}
+
+func TestAllInEv(t *testing.T) {
+	hands := parseTestHistories(t)
+	deck := Make52CardBag()
+	ah, err := CalcAllInEv(hands[0], deck)
+	if ((err != nil) || (ah == nil)) {
+		t.Fatalf("expected an all-in, got %v", err)
+	}
+	/* alice needs runner-runner cards to beat bob's set. */
+	alice := ah.Players[0]
+	bob := ah.Players[1]
+	if ((alice.Equity <= 0) || (alice.Equity > 0.1)) {
+		t.Errorf("expected alice to have less than 10%% equity, got %f",
+			alice.Equity)
+	}
+	if (math.Abs(alice.Expected + bob.Expected - 297) > 1e-6) {
+		t.Errorf("expected the raked pot of 297 to be shared, got %f",
+			alice.Expected + bob.Expected)
+	}
+
+	ah, err = CalcAllInEv(hands[2], deck)
+	if ((err != nil) || (ah == nil)) {
+		t.Fatalf("expected an all-in, got %v", err)
+	}
+	total := 0.0
+	for i := range(ah.Players) {
+		total += ah.Players[i].Expected
+	}
+	if (math.Abs(total - 250) > 1e-6) {
+		t.Errorf("expected the main and side pots to add up to 250, got %f",
+			total)
+	}
+	/* Only alice and carol can win the side pot, so bob's equity is less
+	 * than his share of the main pot.
+	 */
+	if (ah.Players[1].Expected > 150) {
+		t.Errorf("expected bob to expect less than the main pot, got %f",
+			ah.Players[1].Expected)
+	}
+
+	rep, err := NewAllInReport(hands)
+	if (err != nil) {
+		t.Fatalf("%s", err.Error())
+	}
+	if (len(rep.AllIns) != 2) {
+		t.Errorf("expected 2 all-ins, got %d", len(rep.AllIns))
+	}
+	for i := range(rep.Players) {
+		p := rep.Players[i]
+		if (p.Name == "dave") {
+			t.Errorf("expected dave, who sat out, not to be counted")
+		}
+		if (p.Name == "bob") {
+			if ((p.Hands != 3) || (p.AllIns != 2)) {
+				t.Errorf("expected bob to play 3 hands with 2 all-ins, got %v",
+					p)
+			}
+			if (p.Net != 147 + 11500 + 100) {
+				t.Errorf("unexpected net for bob %d", p.Net)
+			}
+			if (float64(p.Net) - p.AllInNet <= 0) {
+				t.Errorf("expected bob to have been lucky")
+			}
+		}
+	}
+}
+
+const TEST_MIXED_HAND_HISTORIES = `PokerStars Hand #2001: Omaha Pot Limit ($0.01/$0.02 USD) - 2011/01/01 13:00:00 ET
+Table 'Beta' 6-max Seat #1 is the button
+Seat 1: alice ($2.00 in chips)
+Seat 2: bob ($2.00 in chips)
+alice: posts small blind $0.01
+bob: posts big blind $0.02
+*** HOLE CARDS ***
+Dealt to alice [Ah Kh Qd Jd]
+alice: folds
+Uncalled bet ($0.01) returned to bob
+bob collected $0.02 from pot
+*** SUMMARY ***
+Total pot $0.02 | Rake $0
+
+
+
+PokerStars Hand #2002: Hold'em No Limit ($0.01/$0.02 USD) - 2011/01/01 13:01:00 ET
+Table 'Alpha' 6-max Seat #2 is the button
+Seat 1: alice ($2.00 in chips)
+Seat 2: bob ($2.00 in chips)
+bob: posts small blind $0.01
+alice: posts big blind $0.02
+*** HOLE CARDS ***
+Dealt to alice [Ts 9s]
+bob: folds
+Uncalled bet ($0.01) returned to alice
+alice collected $0.02 from pot
+*** SUMMARY ***
+Total pot $0.02 | Rake $0
+`
+
+func TestParseMixedHandHistories(t *testing.T) {
+	hands, err := ParseHandHistories(strings.NewReader(
+		TEST_MIXED_HAND_HISTORIES))
+	if (err != nil) {
+		t.Fatalf("expected the Omaha hand to be skipped, got error %s",
+			err.Error())
+	}
+	if ((len(hands) != 1) || (hands[0].Id != "2002")) {
+		t.Fatalf("expected only the Hold'em hand, got %d hands", len(hands))
+	}
+	if (hands[0].Players[0].Hole.ShortString() != "10S 9S") {
+		t.Errorf("expected alice to hold 10S 9S, got %s",
+			hands[0].Players[0].Hole.ShortString())
+	}
+}
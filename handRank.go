@@ -0,0 +1,155 @@
+/*
+ * Copyright 2011 Colin Patrick McCabe
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, version 2.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package main
+
+import (
+	"math/bits"
+)
+
+/* HandRank summarizes the best hand that can be made out of 5 to 7 cards as a
+ * single number. Bigger numbers are better hands, and equal numbers are
+ * hands that tie.
+ *
+ * This agrees with comparing the results of MakeBestHand using Hand.Compare,
+ * but it doesn't allocate anything and doesn't need to try every subset of
+ * the cards, so it is much faster. Use it when comparing lots of hands.
+ *
+ * The hand type goes in the top bits, followed by up to five card values,
+ * most important first.
+ */
+const RANK_TY_SHIFT = 20
+
+func makeRank(ty int, vals ...int) int {
+	r := ty << RANK_TY_SHIFT
+	shift := uint(16)
+	for i := range(vals) {
+		r |= vals[i] << shift
+		shift -= 4
+	}
+	return r
+}
+
+/* Returns the type of hand (PAIR, FLUSH, etc.) that a rank describes. */
+func HandRankTy(rank int) int {
+	return rank >> RANK_TY_SHIFT
+}
+
+/* Returns the n highest values in a value bitmap, highest first. */
+func topVals(valBits int, n int, buf []int) []int {
+	buf = buf[:0]
+	for v := ACE_VAL; (v >= 2) && (len(buf) < n); v-- {
+		if ((valBits & (1 << uint(v))) != 0) {
+			buf = append(buf, v)
+		}
+	}
+	return buf
+}
+
+func withLowAce(valBits int) int {
+	if ((valBits & (1 << ACE_VAL)) != 0) {
+		valBits |= 1 << 1
+	}
+	return valBits
+}
+
+func HandRank(cards CardSlice) int {
+	var cnt [ACE_VAL + 1]int
+	var suitBits [4]int
+	allBits := 0
+	for i := range(cards) {
+		c := cards[i]
+		cnt[c.val]++
+		suitBits[c.suit] |= 1 << uint(c.val)
+		allBits |= 1 << uint(c.val)
+	}
+
+	flushSuit := -1
+	for s := range(suitBits) {
+		if (bits.OnesCount(uint(suitBits[s])) >= HAND_SZ) {
+			flushSuit = s
+		}
+	}
+	if (flushSuit != -1) {
+		hi := straightHigh(withLowAce(suitBits[flushSuit]))
+		if (hi != -1) {
+			return makeRank(STRAIGHT_FLUSH, hi)
+		}
+	}
+
+	quad, trip1, trip2, pair1, pair2 := -1, -1, -1, -1, -1
+	for v := ACE_VAL; v >= 2; v-- {
+		switch (cnt[v]) {
+		case 4:
+			quad = v
+		case 3:
+			if (trip1 == -1) {
+				trip1 = v
+			} else if (trip2 == -1) {
+				trip2 = v
+			}
+		case 2:
+			if (pair1 == -1) {
+				pair1 = v
+			} else if (pair2 == -1) {
+				pair2 = v
+			}
+		}
+	}
+
+	var buf [HAND_SZ]int
+	if (quad != -1) {
+		k := topVals(allBits &^ (1 << uint(quad)), 1, buf[:])
+		return makeRank(FOUR_OF_A_KIND, quad, k[0])
+	}
+	if (trip1 != -1) {
+		over := pair1
+		if (trip2 > over) {
+			over = trip2
+		}
+		if (over != -1) {
+			return makeRank(FULL_HOUSE, trip1, over)
+		}
+	}
+	if (flushSuit != -1) {
+		return makeRank(FLUSH, topVals(suitBits[flushSuit], HAND_SZ, buf[:])...)
+	}
+	hi := straightHigh(withLowAce(allBits))
+	if (hi != -1) {
+		return makeRank(STRAIGHT, hi)
+	}
+	if (trip1 != -1) {
+		k := topVals(allBits &^ (1 << uint(trip1)), 2, buf[:])
+		return makeRank(THREE_OF_A_KIND, trip1, k[0], k[1])
+	}
+	if (pair2 != -1) {
+		k := topVals(allBits &^ ((1 << uint(pair1)) | (1 << uint(pair2))), 1,
+			buf[:])
+		return makeRank(TWO_PAIR, pair1, pair2, k[0])
+	}
+	if (pair1 != -1) {
+		k := topVals(allBits &^ (1 << uint(pair1)), 3, buf[:])
+		return makeRank(PAIR, pair1, k[0], k[1], k[2])
+	}
+	return makeRank(HIGH_CARD, topVals(allBits, HAND_SZ, buf[:])...)
+}
+
+/* Returns the rank of the best hand made from some hole cards and a board. */
+func holeRank(hole CardSlice, board CardSlice, buf CardSlice) int {
+	buf = append(buf[:0], board...)
+	buf = append(buf, hole...)
+	return HandRank(buf)
+}
@@ -0,0 +1,62 @@
+/*
+ * Copyright 2011 Colin Patrick McCabe
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, version 2.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package main
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func randomCards(rng *rand.Rand, deck *CardBag, n int) CardSlice {
+	perm := rng.Perm(deck.Len())
+	ret := make(CardSlice, n)
+	for i := range(ret) {
+		ret[i] = deck.Get(uint(perm[i]))
+	}
+	return ret
+}
+
+func sign(n int) int {
+	if (n < 0) {
+		return -1
+	} else if (n > 0) {
+		return 1
+	}
+	return 0
+}
+
+// HandRank must agree with MakeBestHand and Hand.Compare.
+func TestHandRank(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	deck := Make52CardBag()
+	for i := 0; i < 3000; i++ {
+		a := randomCards(rng, deck, 5 + rng.Intn(3))
+		b := randomCards(rng, deck, 5 + rng.Intn(3))
+		ha := MakeBestHand(a)
+		hb := MakeBestHand(b)
+		ra := HandRank(a)
+		rb := HandRank(b)
+		if (HandRankTy(ra) != ha.ty) {
+			t.Fatalf("HandRank says %s is %s, but MakeBestHand says %s", a,
+				HandTyToStr(HandRankTy(ra)), HandTyToStr(ha.ty))
+		}
+		if (sign(ra - rb) != ha.Compare(hb)) {
+			t.Fatalf("HandRank and Hand.Compare disagree about %s vs %s",
+				a, b)
+		}
+	}
+}
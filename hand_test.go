@@ -85,3 +85,46 @@ func TestHand1(t *testing.T) {
 			MakeHand(CardSlice { &Card{8, DIAMONDS}, &Card{7, DIAMONDS},
 		&Card{10, DIAMONDS}, &Card{QUEEN_VAL, DIAMONDS}, &Card{JACK_VAL, DIAMONDS} }))
 }
+
+func TestHand2(t *testing.T) {
+	c1 := CardSlice { &Card{KING_VAL, DIAMONDS}, &Card{KING_VAL, CLUBS},
+					&Card{KING_VAL, SPADES}, &Card{5, HEARTS}, &Card{5, DIAMONDS} }
+	expectHand(t, c1, FULL_HOUSE, [2]int{KING_VAL, 5}, -1)
+
+	c2 := CardSlice { &Card{ACE_VAL, DIAMONDS}, &Card{2, CLUBS}, &Card{3, HEARTS},
+					&Card{4, DIAMONDS}, &Card{5, SPADES} }
+	expectHand(t, c2, STRAIGHT, [2]int{5, -1}, -1)
+
+	c3 := CardSlice { &Card{ACE_VAL, HEARTS}, &Card{2, HEARTS}, &Card{3, HEARTS},
+					&Card{4, HEARTS}, &Card{5, HEARTS} }
+	expectHand(t, c3, STRAIGHT_FLUSH, [2]int{5, -1}, HEARTS)
+
+	// kings and twos beat queens and jacks
+	compareHands(t, 1, MakeHand(CardSlice { &Card{KING_VAL, DIAMONDS},
+		&Card{KING_VAL, CLUBS}, &Card{2, SPADES}, &Card{2, HEARTS}, &Card{3, DIAMONDS} }),
+			MakeHand(CardSlice { &Card{QUEEN_VAL, DIAMONDS}, &Card{QUEEN_VAL, CLUBS},
+		&Card{JACK_VAL, SPADES}, &Card{JACK_VAL, HEARTS}, &Card{ACE_VAL, DIAMONDS} }))
+
+	// the kicker decides
+	compareHands(t, -1, MakeHand(CardSlice { &Card{9, DIAMONDS},
+		&Card{9, CLUBS}, &Card{ACE_VAL, SPADES}, &Card{KING_VAL, HEARTS}, &Card{3, DIAMONDS} }),
+			MakeHand(CardSlice { &Card{9, HEARTS}, &Card{9, SPADES},
+		&Card{ACE_VAL, CLUBS}, &Card{KING_VAL, CLUBS}, &Card{4, DIAMONDS} }))
+
+	// the wheel is the lowest straight
+	compareHands(t, -1, MakeHand(c2), MakeHand(CardSlice { &Card{6, DIAMONDS},
+		&Card{2, CLUBS}, &Card{3, HEARTS}, &Card{4, DIAMONDS}, &Card{5, SPADES} }))
+}
+
+func TestMakeBestHand(t *testing.T) {
+	cards, _ := StrToCards("KS QS AS 3S 5S 2D 4H")
+	h := MakeBestHand(cards)
+	if ((h.ty != FLUSH) || (len(h.cards) != HAND_SZ)) {
+		t.Errorf("expected a five card flush, got %s", h)
+	}
+	cards, _ = StrToCards("KS KD 5C 5H 5S 2D 2H")
+	h = MakeBestHand(cards)
+	if ((h.ty != FULL_HOUSE) || (h.val[0] != 5) || (h.val[1] != KING_VAL)) {
+		t.Errorf("expected fives full of kings, got %s", h)
+	}
+}
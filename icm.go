@@ -0,0 +1,506 @@
+/*
+ * Copyright 2011 Colin Patrick McCabe
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, version 2.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+/* In a tournament, chips aren't worth money in proportion to how many you
+ * have. The Independent Chip Model (ICM) values a stack by the chance of
+ * finishing in each paid place. We use the Malmstrom-Harville model: the
+ * chance of a player finishing first is their share of the chips, and the
+ * chance of finishing in each place after that is their share of the chips
+ * still left once the players above them are taken out.
+ */
+
+/* The most players we will work out ICM equities for. The work doubles with
+ * each player.
+ */
+const MAX_ICM_PLAYERS = 20
+
+/* Returns each player's share of the prize pool, given the size of their
+ * stack. payouts[i] is the prize for finishing in place i + 1. Players with
+ * no chips have already been knocked out; they finish below everyone still
+ * in, and share the prizes for those places.
+ */
+func ICMEquity(stacks []int64, payouts []float64) ([]float64, error) {
+	if (len(stacks) > MAX_ICM_PLAYERS) {
+		return nil, fmt.Errorf("ICM equity can be worked out for at most " +
+			"%d players.", MAX_ICM_PLAYERS)
+	}
+	var alive []int
+	for i := range(stacks) {
+		if (stacks[i] < 0) {
+			return nil, fmt.Errorf("stacks can't be negative.")
+		}
+		if (stacks[i] > 0) {
+			alive = append(alive, i)
+		}
+	}
+	if (len(alive) == 0) {
+		return nil, fmt.Errorf("nobody has any chips.")
+	}
+	ret := make([]float64, len(stacks))
+	busted := len(stacks) - len(alive)
+	if (busted > 0) {
+		share := 0.0
+		for p := len(alive); (p < len(stacks)) && (p < len(payouts)); p++ {
+			share += payouts[p]
+		}
+		for i := range(stacks) {
+			if (stacks[i] == 0) {
+				ret[i] = share / float64(busted)
+			}
+		}
+	}
+
+	/* probs[mask] is the chance that the players in mask are the ones left
+	 * once the places above them have been filled. Taking a player out of a
+	 * mask always gives a smaller mask, so by going from the largest mask to
+	 * the smallest, every mask is complete by the time we reach it.
+	 */
+	n := len(alive)
+	full := (1 << uint(n)) - 1
+	probs := make([]float64, full + 1)
+	probs[full] = 1.0
+	for mask := full; mask > 0; mask-- {
+		if (probs[mask] == 0) {
+			continue
+		}
+		left := int64(0)
+		place := n
+		for j := 0; j < n; j++ {
+			if ((mask & (1 << uint(j))) != 0) {
+				left += stacks[alive[j]]
+				place--
+			}
+		}
+		if (place >= len(payouts)) {
+			continue
+		}
+		for j := 0; j < n; j++ {
+			if ((mask & (1 << uint(j))) == 0) {
+				continue
+			}
+			p := probs[mask] * float64(stacks[alive[j]]) / float64(left)
+			ret[alive[j]] += p * payouts[place]
+			probs[mask &^ (1 << uint(j))] += p
+		}
+	}
+	return ret, nil
+}
+
+/* A hand in a tournament where everyone has folded to the blinds. The first
+ * player is in the small blind, and the second is in the big blind. Everyone
+ * pays the ante.
+ */
+type ICMSpot struct {
+	Stacks []int64
+	Payouts []float64
+	SmallBlind int64
+	BigBlind int64
+	Ante int64
+}
+
+/* Check the stacks of the players still in a tournament. A stack of 0 would
+ * make ICMEquity tie that player with anyone who busts in the hand, although
+ * they were knocked out first.
+ */
+func checkICMStacks(stacks []int64) error {
+	for i := range(stacks) {
+		if (stacks[i] <= 0) {
+			return fmt.Errorf("every stack must be positive. Leave out " +
+				"players who have been knocked out, and the places they " +
+				"were paid for.")
+		}
+	}
+	return nil
+}
+
+func (s *ICMSpot) Validate() error {
+	if (len(s.Stacks) < 2) {
+		return fmt.Errorf("at least two stacks are needed.")
+	}
+	err := checkICMStacks(s.Stacks)
+	if (err != nil) {
+		return err
+	}
+	if ((s.SmallBlind < 0) || (s.BigBlind <= 0) || (s.Ante < 0)) {
+		return fmt.Errorf("the big blind must be positive, and the small " +
+			"blind and ante can't be negative.")
+	}
+	for i := range(s.Payouts) {
+		if (s.Payouts[i] < 0) {
+			return fmt.Errorf("payouts can't be negative.")
+		}
+	}
+	_, err = ICMEquity(s.Stacks, s.Payouts)
+	return err
+}
+
+/* Returns what each player has put in before anyone acts. */
+func (s *ICMSpot) posted() []int64 {
+	ret := make([]int64, len(s.Stacks))
+	for i := range(s.Stacks) {
+		amt := s.Ante
+		if (i == 0) {
+			amt += s.SmallBlind
+		} else if (i == 1) {
+			amt += s.BigBlind
+		}
+		if (amt > s.Stacks[i]) {
+			amt = s.Stacks[i]
+		}
+		ret[i] = amt
+	}
+	return ret
+}
+
+/* Returns the stacks after the hand, if everyone but the winner folds. */
+func (s *ICMSpot) stacksAfterFold(winner int) []int64 {
+	posted := s.posted()
+	ret := make([]int64, len(s.Stacks))
+	pot := int64(0)
+	for i := range(s.Stacks) {
+		ret[i] = s.Stacks[i] - posted[i]
+		pot += posted[i]
+	}
+	ret[winner] += pot
+	return ret
+}
+
+/* Returns the stacks after the blinds are all-in against each other. winner
+ * is the player who wins, or -1 if they split the pot. The bigger stack only
+ * risks as much as the smaller one has.
+ */
+func (s *ICMSpot) stacksAfterAllIn(winner int) []int64 {
+	posted := s.posted()
+	ret := make([]int64, len(s.Stacks))
+	eff := s.Stacks[0]
+	if (s.Stacks[1] < eff) {
+		eff = s.Stacks[1]
+	}
+	pot := 2 * eff
+	for i := range(s.Stacks) {
+		if (i < 2) {
+			ret[i] = s.Stacks[i] - eff
+		} else {
+			ret[i] = s.Stacks[i] - posted[i]
+			pot += posted[i]
+		}
+	}
+	if (winner == -1) {
+		ret[0] += pot - pot / 2
+		ret[1] += pot / 2
+	} else {
+		ret[winner] += pot
+	}
+	return ret
+}
+
+/* Returns what the player's stack is worth in the prize pool, and in chips,
+ * after each of the given outcomes, weighted by how likely they are.
+ */
+func (s *ICMSpot) value(player int, outcomes [][]int64,
+		weights []float64) (float64, float64, error) {
+	prize := 0.0
+	chips := 0.0
+	for i := range(outcomes) {
+		if (weights[i] == 0) {
+			continue
+		}
+		eqs, err := ICMEquity(outcomes[i], s.Payouts)
+		if (err != nil) {
+			return 0, 0, err
+		}
+		prize += weights[i] * eqs[player]
+		chips += weights[i] * float64(outcomes[i][player])
+	}
+	return prize, chips, nil
+}
+
+/* A decision to move all-in from the small blind, or to call an all-in from
+ * the big blind, valued in prize money and in chips. CallChance is how often
+ * the big blind calls a push; it is always 1 for a call. Equity is how the
+ * hand does when the chips go in.
+ */
+type ICMDecision struct {
+	Action string
+	Hole CardSlice
+	CallChance float64
+	Equity *Equity
+	Prize float64
+	FoldPrize float64
+	Chips float64
+	FoldChips float64
+}
+
+/* Returns how a hand does against a range on a board. */
+func holeVsRange(hole CardSlice, board CardSlice, r *Range, samples int,
+		seed int64, deck *CardBag) (*Equity, error) {
+	ours := NewRange()
+	ours.Add(hole[0], hole[1])
+	res, err := CalcRangeEquity([]*Range { ours, r }, board, samples, seed,
+		deck)
+	if (err != nil) {
+		return nil, err
+	}
+	return res.Equities[0], nil
+}
+
+/* Returns the chance of the hand winning, tying and losing. */
+func equityOdds(eq *Equity) []float64 {
+	t := eq.Total()
+	return []float64 { eq.Win / t, eq.Tie / t, eq.Loss / t }
+}
+
+/* Value pushing with a hand from the small blind, when the big blind calls
+ * with callRange.
+ */
+func (s *ICMSpot) EvalPush(hole CardSlice, callRange *Range, samples int,
+		seed int64, deck *CardBag) (*ICMDecision, error) {
+	d := &ICMDecision { Action: "push", Hole: hole }
+	live := callRange.Live(hole)
+	for i := range(live) {
+		d.CallChance += callRange.Weight(live[i])
+	}
+	d.CallChance /= choose(NUM_CARDS - HOLE_SZ, HOLE_SZ)
+	outcomes := [][]int64 { s.stacksAfterFold(0) }
+	weights := []float64 { 1.0 - d.CallChance }
+	if (d.CallChance > 0) {
+		var err error
+		d.Equity, err = holeVsRange(hole, CardSlice {}, callRange, samples, seed,
+			deck)
+		if (err != nil) {
+			return nil, err
+		}
+		odds := equityOdds(d.Equity)
+		outcomes = append(outcomes, s.stacksAfterAllIn(0),
+			s.stacksAfterAllIn(-1), s.stacksAfterAllIn(1))
+		for i := range(odds) {
+			weights = append(weights, d.CallChance * odds[i])
+		}
+	}
+	var err error
+	d.Prize, d.Chips, err = s.value(0, outcomes, weights)
+	if (err != nil) {
+		return nil, err
+	}
+	d.FoldPrize, d.FoldChips, err = s.value(0,
+		[][]int64 { s.stacksAfterFold(1) }, []float64 { 1.0 })
+	if (err != nil) {
+		return nil, err
+	}
+	return d, nil
+}
+
+/* Value calling with a hand in the big blind, when the small blind pushes
+ * with pushRange.
+ */
+func (s *ICMSpot) EvalCall(hole CardSlice, pushRange *Range, samples int,
+		seed int64, deck *CardBag) (*ICMDecision, error) {
+	d := &ICMDecision { Action: "call", Hole: hole, CallChance: 1.0 }
+	var err error
+	d.Equity, err = holeVsRange(hole, CardSlice {}, pushRange, samples, seed,
+		deck)
+	if (err != nil) {
+		return nil, err
+	}
+	odds := equityOdds(d.Equity)
+	d.Prize, d.Chips, err = s.value(1, [][]int64 { s.stacksAfterAllIn(1),
+		s.stacksAfterAllIn(-1), s.stacksAfterAllIn(0) }, odds)
+	if (err != nil) {
+		return nil, err
+	}
+	d.FoldPrize, d.FoldChips, err = s.value(1,
+		[][]int64 { s.stacksAfterFold(0) }, []float64 { 1.0 })
+	if (err != nil) {
+		return nil, err
+	}
+	return d, nil
+}
+
+func (d *ICMDecision) String() string {
+	ret := ""
+	if (d.Action == "push") {
+		ret += fmt.Sprintf("pushing %s from the small blind\n",
+			d.Hole.ShortString())
+		ret += fmt.Sprintf("the big blind calls %.2f%% of the time\n",
+			d.CallChance * 100.0)
+	} else {
+		ret += fmt.Sprintf("calling an all-in with %s in the big blind\n",
+			d.Hole.ShortString())
+	}
+	if (d.Equity != nil) {
+		ret += fmt.Sprintf("when called: %s\n", d.Equity.String())
+	}
+	ret += fmt.Sprintf("%-8s %12s %12s\n", "", "prize", "chips")
+	ret += fmt.Sprintf("%-8s %12.2f %12.1f\n", d.Action, d.Prize, d.Chips)
+	ret += fmt.Sprintf("%-8s %12.2f %12.1f\n", "fold", d.FoldPrize,
+		d.FoldChips)
+	if (d.Prize >= d.FoldPrize) {
+		ret += fmt.Sprintf("%sing is worth %.2f more than folding\n",
+			d.Action, d.Prize - d.FoldPrize)
+	} else {
+		ret += fmt.Sprintf("folding is worth %.2f more than %sing\n",
+			d.FoldPrize - d.Prize, d.Action)
+	}
+	if ((d.Prize < d.FoldPrize) != (d.Chips < d.FoldChips)) {
+		ret += "ICM changes the decision: in chips alone, the answer " +
+			"would be different\n"
+	}
+	return ret
+}
+
+/* Parse a payout structure like "50,30,20". */
+func parsePayouts(str string) ([]float64, error) {
+	var ret []float64
+	toks := strings.Split(str, ",")
+	for i := range(toks) {
+		p, err := strconv.ParseFloat(strings.TrimSpace(toks[i]), 64)
+		if ((err != nil) || (p < 0)) {
+			return nil, fmt.Errorf("invalid payout '%s'.", toks[i])
+		}
+		ret = append(ret, p)
+	}
+	return ret, nil
+}
+
+func icmTableToStr(stacks []int64, eqs []float64) string {
+	total := int64(0)
+	for i := range(stacks) {
+		total += stacks[i]
+	}
+	ret := fmt.Sprintf("%-8s %12s %8s %12s\n", "player", "stack", "chips",
+		"prize")
+	for i := range(stacks) {
+		ret += fmt.Sprintf("%-8d %12d %7.2f%% %12.2f\n", i + 1, stacks[i],
+			float64(stacks[i]) * 100.0 / float64(total), eqs[i])
+	}
+	return ret
+}
+
+func icmUsage(fs *flag.FlagSet) func() {
+	return func() {
+		fmt.Fprintf(os.Stderr,
+`%s icm: value tournament stacks in prize money.
+
+Usage:
+%s icm [options] -p [payouts] [stack] [stack] ...
+
+Shows what each stack is worth under the Independent Chip Model, given the
+payouts for each place, like "50,30,20".
+
+With -push or -call, values an all-in decision instead. Everyone else has
+folded to the blinds: the first stack is in the small blind and the second is
+in the big blind. -push values moving all-in from the small blind with the
+given hand, when the big blind calls with the range given by -r. -call values
+calling in the big blind when the small blind pushes with that range. Both
+are compared with folding, in prize money and in chips.
+
+Options:
+`, os.Args[0], os.Args[0])
+		fs.PrintDefaults()
+	}
+}
+
+func icmMain(args []string) {
+	fs := flag.NewFlagSet("icm", flag.ExitOnError)
+	fs.Usage = icmUsage(fs)
+	var payoutStr = fs.String("p", "", "the payouts, from first place down")
+	var pushStr = fs.String("push", "", "value pushing this hand")
+	var callStr = fs.String("call", "", "value calling with this hand")
+	var rangeStr = fs.String("r", "", "the range the other player calls " +
+		"or pushes with")
+	var sb = fs.Int64("sb", 0, "small blind")
+	var bb = fs.Int64("bb", 0, "big blind")
+	var ante = fs.Int64("ante", 0, "ante")
+	var samples = fs.Int("m", 0, "number of Monte Carlo samples " +
+		"(0 means enumerate every showdown if that is feasible)")
+	var seed = fs.Int64("s", 1, "Monte Carlo random seed")
+	fs.Parse(args)
+
+	if ((*payoutStr == "") || (fs.NArg() < 1)) {
+		fs.Usage()
+		os.Exit(1)
+	}
+	payouts, err := parsePayouts(*payoutStr)
+	if (err != nil) {
+		die(err)
+	}
+	stacks := make([]int64, fs.NArg())
+	for i := range(stacks) {
+		stacks[i], err = strconv.ParseInt(fs.Arg(i), 10, 64)
+		if (err != nil) {
+			die(fmt.Errorf("invalid stack '%s'.", fs.Arg(i)))
+		}
+	}
+	err = checkICMStacks(stacks)
+	if (err != nil) {
+		die(err)
+	}
+	if ((*pushStr == "") && (*callStr == "")) {
+		eqs, err := ICMEquity(stacks, payouts)
+		if (err != nil) {
+			die(err)
+		}
+		fmt.Printf("%s", icmTableToStr(stacks, eqs))
+		return
+	}
+	if ((*pushStr != "") && (*callStr != "")) {
+		die(fmt.Errorf("use either -push or -call, not both."))
+	}
+	if (*rangeStr == "") {
+		die(fmt.Errorf("give the other player's range with -r."))
+	}
+	r, err := LoadRange(*rangeStr)
+	if (err != nil) {
+		die(err)
+	}
+	s := &ICMSpot { Stacks: stacks, Payouts: payouts, SmallBlind: *sb,
+		BigBlind: *bb, Ante: *ante }
+	err = s.Validate()
+	if (err != nil) {
+		die(err)
+	}
+	holeStr := *pushStr
+	if (*callStr != "") {
+		holeStr = *callStr
+	}
+	hole, err := ParseCards(holeStr, "the hand")
+	if (err != nil) {
+		die(err)
+	}
+	if ((len(hole) != HOLE_SZ) || (hole.HasDuplicates() != nil)) {
+		die(fmt.Errorf("the hand must be two different cards."))
+	}
+	var d *ICMDecision
+	if (*pushStr != "") {
+		d, err = s.EvalPush(hole, r, *samples, *seed, Make52CardBag())
+	} else {
+		d, err = s.EvalCall(hole, r, *samples, *seed, Make52CardBag())
+	}
+	if (err != nil) {
+		die(err)
+	}
+	fmt.Printf("%s", d.String())
+}
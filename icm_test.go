@@ -0,0 +1,146 @@
+/*
+ * Copyright 2011 Colin Patrick McCabe
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, version 2.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func expectICM(t *testing.T, stacks []int64, payouts []float64,
+		expected []float64) {
+	eqs, err := ICMEquity(stacks, payouts)
+	if (err != nil) {
+		t.Fatalf("%s", err.Error())
+	}
+	for i := range(expected) {
+		if (math.Abs(eqs[i] - expected[i]) > 1e-6) {
+			t.Errorf("expected player %d with stacks %v to get %f, got %f",
+				i + 1, stacks, expected[i], eqs[i])
+		}
+	}
+}
+
+func TestICMEquity(t *testing.T) {
+	/* Winner takes all is the same as the share of the chips. */
+	expectICM(t, []int64 { 500, 300, 200 }, []float64 { 100 },
+		[]float64 { 50, 30, 20 })
+	/* Equal stacks get equal shares. */
+	expectICM(t, []int64 { 100, 100, 100, 100 }, []float64 { 50, 30, 20 },
+		[]float64 { 25, 25, 25, 25 })
+	/* The first player finishes first half the time, second
+	 * 0.3 * 5/7 + 0.2 * 5/8 of the time, and third the rest of the time.
+	 */
+	second := 0.3 * 5.0 / 7.0 + 0.2 * 5.0 / 8.0
+	expectICM(t, []int64 { 5000, 3000, 2000 }, []float64 { 50, 30, 20 },
+		[]float64 { 25 + 30 * second + 20 * (0.5 - second) })
+	/* A player with no chips has finished last. */
+	expectICM(t, []int64 { 100, 0, 300 }, []float64 { 50, 30, 20 },
+		[]float64 { 30 + 20 * 0.25, 20, 30 + 20 * 0.75 })
+
+	stacks := []int64 { 1, 7, 12, 20, 33, 54, 88, 142 }
+	payouts := []float64 { 40, 25, 15, 10, 6, 4 }
+	eqs, err := ICMEquity(stacks, payouts)
+	if (err != nil) {
+		t.Fatalf("%s", err.Error())
+	}
+	total := 0.0
+	for i := range(eqs) {
+		total += eqs[i]
+		if ((i > 0) && (eqs[i] <= eqs[i - 1])) {
+			t.Errorf("expected bigger stacks to be worth more, got %v", eqs)
+		}
+	}
+	if (math.Abs(total - 100) > 1e-6) {
+		t.Errorf("expected the whole prize pool to be paid, got %f", total)
+	}
+	_, err = ICMEquity([]int64 { 0, 0 }, payouts)
+	if (err == nil) {
+		t.Errorf("expected an error when nobody has chips")
+	}
+}
+
+func TestICMSpot(t *testing.T) {
+	s := &ICMSpot { Stacks: []int64 { 1500, 3000, 4500, 6000 },
+		Payouts: []float64 { 50, 30, 20 }, SmallBlind: 100, BigBlind: 200,
+		Ante: 25 }
+	err := s.Validate()
+	if (err != nil) {
+		t.Fatalf("%s", err.Error())
+	}
+	outcomes := [][]int64 { s.stacksAfterFold(0), s.stacksAfterFold(1),
+		s.stacksAfterAllIn(0), s.stacksAfterAllIn(-1), s.stacksAfterAllIn(1) }
+	for i := range(outcomes) {
+		total := int64(0)
+		for j := range(outcomes[i]) {
+			total += outcomes[i][j]
+		}
+		if (total != 15000) {
+			t.Errorf("expected the chips to add up to 15000, got %v",
+				outcomes[i])
+		}
+	}
+	if (outcomes[4][0] != 0) {
+		t.Errorf("expected the small blind to be knocked out, got %v",
+			outcomes[4])
+	}
+
+	/* A player who is already out would tie with the small blind if the
+	 * small blind busted in this hand. */
+	bad := &ICMSpot { Stacks: []int64 { 1500, 3000, 0, 6000 },
+		Payouts: s.Payouts, SmallBlind: 100, BigBlind: 200 }
+	if (bad.Validate() == nil) {
+		t.Errorf("expected a stack of 0 to be rejected")
+	}
+
+	deck := Make52CardBag()
+	aces, _ := ParseCards("AS AH", "")
+	any, _ := ParseRange("top100%")
+	d, err := s.EvalPush(aces, any, 2000, 1, deck)
+	if (err != nil) {
+		t.Fatalf("%s", err.Error())
+	}
+	if (math.Abs(d.CallChance - 1.0) > 1e-6) {
+		t.Errorf("expected every hand to call, got %f", d.CallChance)
+	}
+	if (d.Prize <= d.FoldPrize) {
+		t.Errorf("expected pushing aces to beat folding:\n%s", d.String())
+	}
+	/* If nobody calls, pushing just wins the blinds and antes. */
+	d, err = s.EvalPush(aces, NewRange(), 0, 1, deck)
+	if (err != nil) {
+		t.Fatalf("%s", err.Error())
+	}
+	if ((d.Equity != nil) || (d.Chips != 1500 - 125 + 400)) {
+		t.Errorf("expected pushing to steal 400 in chips, got %f", d.Chips)
+	}
+
+	/* Calling with a hand that is ahead in chips can still be a mistake
+	 * once the prize money is taken into account.
+	 */
+	s.Stacks = []int64 { 6000, 3000, 4500, 1500 }
+	ace9, _ := ParseCards("AH 9D", "")
+	top30, _ := ParseRange("top30%")
+	d, err = s.EvalCall(ace9, top30, 2000, 1, deck)
+	if (err != nil) {
+		t.Fatalf("%s", err.Error())
+	}
+	if ((d.Chips <= d.FoldChips) || (d.Prize >= d.FoldPrize)) {
+		t.Errorf("expected calling to win chips but lose prize money:\n%s",
+			d.String())
+	}
+}
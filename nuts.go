@@ -0,0 +1,147 @@
+/*
+ * Copyright 2011 Colin Patrick McCabe
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, version 2.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package main
+
+import (
+	"fmt"
+	"sort"
+)
+
+/* A Holding is a pair of hole cards that someone could have, and the best
+ * hand it makes on the board.
+ *
+ * Rank is 1 for the nuts, 2 for the next best hand, and so on. Holdings that
+ * make equally good hands share the same Rank.
+ */
+type Holding struct {
+	Cards CardSlice
+	Hand *Hand
+	Rank int
+}
+
+type holdingSlice []*Holding
+
+func (hs holdingSlice) Len() int {
+	return len(hs)
+}
+
+// Best hands come first.
+func (hs holdingSlice) Less(i, j int) bool {
+	return hs[i].Hand.Compare(hs[j].Hand) > 0
+}
+
+func (hs holdingSlice) Swap(i, j int) {
+	hs[i], hs[j] = hs[j], hs[i]
+}
+
+func checkPostflop(board CardSlice, what string) error {
+	if (len(board) < 3) {
+		return fmt.Errorf("%s can only be calculated once the flop is out.",
+			what)
+	}
+	return nil
+}
+
+/* Returns every pair of hole cards that could be held on this board, best
+ * first. Cards in 'dead' can't be held by anyone.
+ */
+func RankHoldings(board CardSlice, dead CardSlice, deck *CardBag) []*Holding {
+	bag := deck.Clone()
+	for i := range(board) {
+		bag.Subtract(board[i])
+	}
+	for i := range(dead) {
+		bag.Subtract(dead[i])
+	}
+	var ret holdingSlice
+	bag.ForEachSubset(HOLE_SZ, func(hole CardSlice) {
+		cards := hole.Copy()
+		sort.Sort(sort.Reverse(cards))
+		ret = append(ret, &Holding { cards, bestHandWith(cards, board), 0 })
+	})
+	sort.Stable(ret)
+	for i := range(ret) {
+		if ((i > 0) && (ret[i].Hand.Compare(ret[i-1].Hand) == 0)) {
+			ret[i].Rank = ret[i-1].Rank
+		} else {
+			ret[i].Rank = i + 1
+		}
+	}
+	return ret
+}
+
+func HoldingsToStr(holdings []*Holding, n int) string {
+	ret := ""
+	for i := 0; (i < n) && (i < len(holdings)); i++ {
+		h := holdings[i]
+		ret += fmt.Sprintf("%4d. %s: %s\n", h.Rank, h.Cards.ShortString(),
+			HandTyToStr(h.Hand.ty))
+	}
+	return ret
+}
+
+/* How our current hand does against every pair of hole cards an opponent
+ * could hold.
+ */
+type HandStrength struct {
+	Win int64
+	Tie int64
+	Loss int64
+}
+
+func (hs *HandStrength) Total() int64 {
+	return hs.Win + hs.Tie + hs.Loss
+}
+
+func (hs *HandStrength) Percent(n int64) float64 {
+	return float64(n) * 100.0 / float64(hs.Total())
+}
+
+/* Hand strength counts ties as half a win. */
+func (hs *HandStrength) Value() float64 {
+	return (float64(hs.Win) + float64(hs.Tie) / 2.0) / float64(hs.Total())
+}
+
+func (hs *HandStrength) String() string {
+	return fmt.Sprintf("we beat %03.2f%%, tie %03.2f%%, and lose to %03.2f%% " +
+		"of %d possible hands (hand strength %03.2f%%)\n",
+		hs.Percent(hs.Win), hs.Percent(hs.Tie), hs.Percent(hs.Loss),
+		hs.Total(), hs.Value() * 100.0)
+}
+
+/* Compare our hand right now against every pair of hole cards that an
+ * opponent could hold. Cards held by known opponents are left out.
+ */
+func CalcHandStrength(sc *Scenario, deck *CardBag) (*HandStrength, error) {
+	err := checkPostflop(sc.Board, "hand strength")
+	if (err != nil) {
+		return nil, err
+	}
+	ours := bestHandWith(sc.Hole, sc.Board)
+	hs := new(HandStrength)
+	sc.Future(deck).ForEachSubset(HOLE_SZ, func(opp CardSlice) {
+		c := ours.Compare(bestHandWith(opp, sc.Board))
+		if (c > 0) {
+			hs.Win++
+		} else if (c == 0) {
+			hs.Tie++
+		} else {
+			hs.Loss++
+		}
+	})
+	return hs, nil
+}
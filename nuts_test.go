@@ -0,0 +1,57 @@
+/*
+ * Copyright 2011 Colin Patrick McCabe
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, version 2.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package main
+
+import (
+	"testing"
+)
+
+func TestRankHoldings(t *testing.T) {
+	board, _ := StrToCards("AH 3H 5C 9D 2S")
+	holdings := RankHoldings(board, nil, Make52CardBag())
+	if (len(holdings) != 1081) {
+		t.Fatalf("expected 1081 holdings, got %d", len(holdings))
+	}
+	// 6-4 makes a six-high straight, the best hand on this board.
+	for i := 0; i < 16; i++ {
+		if ((holdings[i].Rank != 1) || (holdings[i].Hand.ty != STRAIGHT) ||
+				(holdings[i].Hand.val[0] != 6)) {
+			t.Errorf("expected holding %d to be the nuts, got %s", i,
+				holdings[i].Hand)
+		}
+	}
+	if (holdings[16].Rank != 17) {
+		t.Errorf("expected the 17th holding to have rank 17, got %d",
+			holdings[16].Rank)
+	}
+}
+
+func TestHandStrength(t *testing.T) {
+	sc := makeScenario(t, &OddsRequest { Hole: "AS AD", Board: "AH AC 5C 9D 2S" })
+	hs, err := CalcHandStrength(sc, Make52CardBag())
+	if (err != nil) {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	if ((hs.Win != 990) || (hs.Tie != 0) || (hs.Loss != 0)) {
+		t.Errorf("expected four aces to beat every hand, got %s", hs)
+	}
+	sc = makeScenario(t, &OddsRequest { Hole: "AS AD" })
+	_, err = CalcHandStrength(sc, Make52CardBag())
+	if (err == nil) {
+		t.Errorf("expected an error before the flop")
+	}
+}
@@ -0,0 +1,274 @@
+/*
+ * Copyright 2011 Colin Patrick McCabe
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, version 2.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+)
+
+const GAME_HOLDEM = "holdem"
+
+// How many futures we deal out between checks for cancellation.
+const CANCEL_CHECK_INTERVAL = 1024
+
+/* A Scenario is everything we need to know to calculate the odds for one
+ * spot: our hole cards, the board, and the hole cards of any opponents that
+ * we happen to know about. Cards held by opponents can never be dealt onto
+ * the board, so they are removed from the deck.
+ *
+ * If Samples is 0, every possible board is enumerated. Otherwise, we deal
+ * Samples random boards (Monte Carlo mode) using a generator seeded with
+ * Seed.
+ */
+type Scenario struct {
+	Hole CardSlice
+	Board CardSlice
+	Opponents []CardSlice
+	Game string
+	Samples int
+	Seed int64
+}
+
+func checkHoleLength(hlen int) error {
+	if (hlen == HOLE_SZ) {
+		return nil
+	}
+	return fmt.Errorf("illegal hole length. Expected a length of %d, " +
+		"but you gave %d hole cards.", HOLE_SZ, hlen)
+}
+
+var validBoardLens = []int { 0, 3, 4, 5 }
+
+func checkBoardLength(blen int) error {
+	for i := range(validBoardLens) {
+		if (blen == validBoardLens[i]) {
+			return nil
+		}
+	}
+	return fmt.Errorf("illegal board length. Expected a length of %s, " +
+		"but your board length was %d.", intsToStr(validBoardLens), blen)
+}
+
+func intsToStr(s []int) (string) {
+	ret := ""
+	sep := ""
+	for i := range(s) {
+		ret += fmt.Sprintf("%s%d", sep, s[i])
+		sep = ", "
+	}
+	return ret
+}
+
+/* Parse a whitespace-separated list of cards. 'what' describes the cards for
+ * the error message.
+ */
+func ParseCards(str string, what string) (CardSlice, error) {
+	cards, errIdx := StrToCards(str)
+	if (errIdx != -1) {
+		return nil, fmt.Errorf("Error parsing %s: parse error at " +
+			"character %d", what, errIdx)
+	}
+	return cards, nil
+}
+
+/* Returns all of the cards that we know about: the board, then our hole
+ * cards.
+ */
+func (sc *Scenario) Base() CardSlice {
+	base := make(CardSlice, len(sc.Board) + len(sc.Hole))
+	copy(base, sc.Board)
+	copy(base[len(sc.Board):], sc.Hole)
+	return base
+}
+
+/* Returns all of the cards that are out of the deck: the base cards, plus any
+ * cards held by known opponents.
+ */
+func (sc *Scenario) Known() CardSlice {
+	known := sc.Base()
+	for i := range(sc.Opponents) {
+		known = append(known, sc.Opponents[i]...)
+	}
+	return known
+}
+
+func (sc *Scenario) Validate() error {
+	if (sc.Game != "" && sc.Game != GAME_HOLDEM) {
+		return fmt.Errorf("unsupported game '%s'. The only game currently " +
+			"supported is '%s'.", sc.Game, GAME_HOLDEM)
+	}
+	err := checkHoleLength(len(sc.Hole))
+	if (err != nil) {
+		return err
+	}
+	err = checkBoardLength(len(sc.Board))
+	if (err != nil) {
+		return err
+	}
+	for i := range(sc.Opponents) {
+		if (len(sc.Opponents[i]) != HOLE_SZ) {
+			return fmt.Errorf("illegal hole length for opponent %d. " +
+				"Expected a length of %d, but got %d cards.",
+				i + 1, HOLE_SZ, len(sc.Opponents[i]))
+		}
+	}
+	if (sc.Samples < 0) {
+		return fmt.Errorf("the number of samples can't be negative.")
+	}
+	dupe := sc.Known().HasDuplicates()
+	if (dupe != nil) {
+		return fmt.Errorf("The card %s appears more than once in your " +
+			"input! That is not possible.", dupe)
+	}
+	return nil
+}
+
+/* Returns the cards which could still be dealt onto the board, given a bag
+ * holding the full deck.
+ */
+func (sc *Scenario) Future(deck *CardBag) *CardBag {
+	future := deck.Clone()
+	known := sc.Known()
+	for i := range(known) {
+		future.Subtract(known[i])
+	}
+	return future
+}
+
+func checkCancelled(ctx context.Context, n int) error {
+	if (n % CANCEL_CHECK_INTERVAL != 0) {
+		return nil
+	}
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+	}
+	return nil
+}
+
+/* Call fn once for every set of cards that could complete the board. In
+ * Monte Carlo mode, fn is called once per randomly dealt set instead.
+ *
+ * The CardSlice passed to fn is reused between calls.
+ */
+func (sc *Scenario) enumerateFutures(ctx context.Context, future *CardBag,
+		fn func(CardSlice)) error {
+	numFutureCards := BOARD_MAX - len(sc.Board)
+	cards := make(CardSlice, numFutureCards)
+	if (sc.Samples > 0) {
+		rng := rand.New(rand.NewSource(sc.Seed))
+		perm := make([]int, future.Len())
+		for i := range(perm) {
+			perm[i] = i
+		}
+		for n := 0; n < sc.Samples; n++ {
+			err := checkCancelled(ctx, n)
+			if (err != nil) {
+				return err
+			}
+			// partial Fisher-Yates shuffle
+			for i := 0; i < numFutureCards; i++ {
+				j := i + rng.Intn(len(perm) - i)
+				perm[i], perm[j] = perm[j], perm[i]
+				cards[i] = future.Get(uint(perm[i]))
+			}
+			fn(cards)
+		}
+		return nil
+	}
+	futureChooser := NewSubsetChooser(uint(future.Len()), uint(numFutureCards))
+	for n := 0;; n++ {
+		err := checkCancelled(ctx, n)
+		if (err != nil) {
+			return err
+		}
+		futureC := futureChooser.Cur()
+		for i := 0; i < numFutureCards; i++ {
+			cards[i] = future.Get(futureC[i])
+		}
+		fn(cards)
+		if (!futureChooser.Next()) {
+			break
+		}
+	}
+	return nil
+}
+
+/* Calculate the odds of making each type of hand, spreading the work over
+ * numCsp CardSliceProcessor goroutines.
+ */
+func CalcOdds(ctx context.Context, sc *Scenario, deck *CardBag,
+		numCsp int) (*ResultSet, error) {
+	base := sc.Base()
+	csps := make([]*CardSliceProcessor, numCsp)
+	for i := range(csps) {
+		csps[i] = NewCardSliceProcessor(base)
+		go csps[i].GoCardSliceProcessor()
+	}
+
+	cspIdx := 0
+	err := sc.enumerateFutures(ctx, sc.Future(deck), func(cards CardSlice) {
+		for i := range(cards) {
+			csps[cspIdx].Card <- cards[i]
+		}
+		cspIdx++
+		if (cspIdx >= numCsp) {
+			cspIdx = 0
+		}
+	})
+
+	// Tell cardSliceProcessors to finish
+	for i := range(csps) {
+		csps[i].Quit <- true
+	}
+
+	// Once each cardSliceProcessor is finished, get its results
+	// Merge all results together
+	allResults := new(ResultSet)
+	for i := range(csps) {
+		<-csps[i].Finished
+		allResults.MergeResultSet(&csps[i].Results)
+	}
+	if (err != nil) {
+		return nil, err
+	}
+	return allResults, nil
+}
+
+/* Calculate the odds of making each type of hand on the calling goroutine,
+ * using csp to do the work. This is useful when there are many scenarios to
+ * get through, since we can run them side by side instead.
+ */
+func CalcOddsSerial(ctx context.Context, sc *Scenario, deck *CardBag,
+		csp *CardSliceProcessor) (*ResultSet, error) {
+	base := sc.Base()
+	csp.Reset(base)
+	spread := make(CardSlice, SPREAD_MAX)
+	copy(spread, base)
+	err := sc.enumerateFutures(ctx, sc.Future(deck), func(cards CardSlice) {
+		copy(spread[len(base):], cards)
+		csp.processSpread(spread)
+	})
+	if (err != nil) {
+		return nil, err
+	}
+	res := csp.Results
+	return &res, nil
+}
@@ -0,0 +1,120 @@
+/*
+ * Copyright 2011 Colin Patrick McCabe
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, version 2.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package main
+
+import (
+	"context"
+	"fmt"
+)
+
+const (
+	MODE_EXACT = "exact"
+	MODE_MONTE_CARLO = "montecarlo"
+)
+
+// The number of boards we deal in Monte Carlo mode if nobody tells us.
+const DEFAULT_SAMPLES = 100000
+
+/* An OddsRequest is the JSON form of a Scenario. Cards are given as
+ * whitespace-separated strings, in the same format the command line uses.
+ */
+type OddsRequest struct {
+	Hole string `json:"hole"`
+	Board string `json:"board"`
+	Opponents []string `json:"opponents,omitempty"`
+	Game string `json:"game,omitempty"`
+	Mode string `json:"mode,omitempty"`
+	Samples int `json:"samples,omitempty"`
+	Seed int64 `json:"seed,omitempty"`
+}
+
+type OddsResponse struct {
+	Mode string `json:"mode"`
+	Total int64 `json:"total"`
+	Results []HandTyResult `json:"results"`
+	Text string `json:"text"`
+}
+
+/* Parse and validate an OddsRequest. Any error returned here is the fault of
+ * whoever sent the request.
+ */
+func (req *OddsRequest) Scenario() (*Scenario, error) {
+	var err error
+	sc := &Scenario { Game: req.Game, Seed: req.Seed }
+	if (sc.Game == "") {
+		sc.Game = GAME_HOLDEM
+	}
+	switch (req.Mode) {
+	case "", MODE_EXACT:
+		if (req.Samples != 0) {
+			return nil, fmt.Errorf("samples can only be given in %s mode.",
+				MODE_MONTE_CARLO)
+		}
+	case MODE_MONTE_CARLO:
+		sc.Samples = req.Samples
+		if (sc.Samples == 0) {
+			sc.Samples = DEFAULT_SAMPLES
+		}
+	default:
+		return nil, fmt.Errorf("unknown mode '%s'. Expected '%s' or '%s'.",
+			req.Mode, MODE_EXACT, MODE_MONTE_CARLO)
+	}
+	sc.Hole, err = ParseCards(req.Hole, "your hole cards")
+	if (err != nil) {
+		return nil, err
+	}
+	sc.Board, err = ParseCards(req.Board, "the board")
+	if (err != nil) {
+		return nil, err
+	}
+	for i := range(req.Opponents) {
+		var opp CardSlice
+		opp, err = ParseCards(req.Opponents[i],
+			fmt.Sprintf("the hole cards of opponent %d", i + 1))
+		if (err != nil) {
+			return nil, err
+		}
+		sc.Opponents = append(sc.Opponents, opp)
+	}
+	err = sc.Validate()
+	if (err != nil) {
+		return nil, err
+	}
+	return sc, nil
+}
+
+func (sc *Scenario) Mode() string {
+	if (sc.Samples > 0) {
+		return MODE_MONTE_CARLO
+	}
+	return MODE_EXACT
+}
+
+func NewOddsResponse(sc *Scenario, res *ResultSet) *OddsResponse {
+	return &OddsResponse { sc.Mode(), res.Total(), res.Entries(),
+		res.String() }
+}
+
+/* Run a validated Scenario and package up the results. */
+func (sc *Scenario) Run(ctx context.Context, deck *CardBag,
+		numCsp int) (*OddsResponse, error) {
+	res, err := CalcOdds(ctx, sc, deck, numCsp)
+	if (err != nil) {
+		return nil, err
+	}
+	return NewOddsResponse(sc, res), nil
+}
@@ -0,0 +1,188 @@
+/*
+ * Copyright 2011 Colin Patrick McCabe
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, version 2.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package main
+
+import (
+	"fmt"
+)
+
+const (
+	SHOWDOWN_LOSS = iota
+	SHOWDOWN_TIE
+	SHOWDOWN_WIN
+)
+
+func showdownToStr(s int) string {
+	switch (s) {
+	case SHOWDOWN_LOSS:
+		return "loss"
+	case SHOWDOWN_TIE:
+		return "tie"
+	case SHOWDOWN_WIN:
+		return "win"
+	}
+	panic(fmt.Sprintf("unexpected showdown result %d", s))
+}
+
+/* Find out how our hand does against the hands of some opponents. We lose if
+ * anyone beats us, and tie if nobody beats us but somebody matches us.
+ */
+func showdown(hero *Hand, opps []*Hand) int {
+	ret := SHOWDOWN_WIN
+	for i := range(opps) {
+		c := hero.Compare(opps[i])
+		if (c < 0) {
+			return SHOWDOWN_LOSS
+		} else if (c == 0) {
+			ret = SHOWDOWN_TIE
+		}
+	}
+	return ret
+}
+
+func bestHandWith(hole CardSlice, board CardSlice) *Hand {
+	cards := make(CardSlice, 0, len(hole) + len(board))
+	cards = append(cards, board...)
+	cards = append(cards, hole...)
+	return MakeBestHand(cards)
+}
+
+/* What happens if one particular card is dealt next. */
+type CardOutcome struct {
+	Card *Card
+	Hand *Hand
+	Showdown int
+}
+
+/* An OutsReport describes what every card which could be dealt next would do
+ * for us.
+ *
+ * Showdown results are only meaningful when we know the hole cards of at least
+ * one opponent. In that case, they describe how we would do if the hand ended
+ * right after the card was dealt.
+ */
+type OutsReport struct {
+	Current *Hand
+	CurrentShowdown int
+	HasOpponents bool
+	Cards []*CardOutcome
+}
+
+func (sc *Scenario) showdownWith(hero *Hand, board CardSlice) int {
+	opps := make([]*Hand, len(sc.Opponents))
+	for i := range(sc.Opponents) {
+		opps[i] = bestHandWith(sc.Opponents[i], board)
+	}
+	return showdown(hero, opps)
+}
+
+/* Find out what every unseen card would give us. This only makes sense on the
+ * flop or the turn, when there is still at least one card to come.
+ */
+func FindOuts(sc *Scenario, deck *CardBag) (*OutsReport, error) {
+	if ((len(sc.Board) != 3) && (len(sc.Board) != 4)) {
+		return nil, fmt.Errorf("outs can only be calculated on the flop " +
+			"or the turn, but the board has %d cards.", len(sc.Board))
+	}
+	rep := new(OutsReport)
+	rep.HasOpponents = (len(sc.Opponents) > 0)
+	rep.Current = bestHandWith(sc.Hole, sc.Board)
+	rep.CurrentShowdown = sc.showdownWith(rep.Current, sc.Board)
+
+	future := sc.Future(deck)
+	board := make(CardSlice, len(sc.Board) + 1)
+	copy(board, sc.Board)
+	for i := 0; i < future.Len(); i++ {
+		c := future.Get(uint(i))
+		board[len(sc.Board)] = c
+		h := bestHandWith(sc.Hole, board)
+		rep.Cards = append(rep.Cards,
+			&CardOutcome { c, h, sc.showdownWith(h, board) })
+	}
+	return rep, nil
+}
+
+/* Returns the cards which would give us a better type of hand than we have
+ * now, grouped by the type of hand they give us.
+ */
+func (rep *OutsReport) OutsByHandTy() [MAX_HANDS]CardSlice {
+	var ret [MAX_HANDS]CardSlice
+	for i := range(rep.Cards) {
+		ty := rep.Cards[i].Hand.ty
+		if (ty > rep.Current.ty) {
+			ret[ty] = append(ret[ty], rep.Cards[i].Card)
+		}
+	}
+	return ret
+}
+
+/* Returns the cards which would make us win or tie when we are currently
+ * behind, or win when we are currently tied.
+ */
+func (rep *OutsReport) OutsByShowdown() [SHOWDOWN_WIN + 1]CardSlice {
+	var ret [SHOWDOWN_WIN + 1]CardSlice
+	if (!rep.HasOpponents) {
+		return ret
+	}
+	for i := range(rep.Cards) {
+		s := rep.Cards[i].Showdown
+		if (s > rep.CurrentShowdown) {
+			ret[s] = append(ret[s], rep.Cards[i].Card)
+		}
+	}
+	return ret
+}
+
+func outsLine(cards CardSlice, what string) string {
+	noun := "outs"
+	if (len(cards) == 1) {
+		noun = "out"
+	}
+	return fmt.Sprintf("%d %s to %s: %s\n", len(cards), noun, what,
+		cards.ShortString())
+}
+
+func (rep *OutsReport) String() string {
+	ret := fmt.Sprintf("currently: %s", HandTyToStr(rep.Current.ty))
+	if (rep.HasOpponents) {
+		ret += fmt.Sprintf(" (%s)", showdownToStr(rep.CurrentShowdown))
+	}
+	ret += "\n"
+	byTy := rep.OutsByHandTy()
+	for ty := MAX_HANDS - 1; ty >= 0; ty-- {
+		if (len(byTy[ty]) > 0) {
+			ret += outsLine(byTy[ty], HandTyToStr(ty))
+		}
+	}
+	byShowdown := rep.OutsByShowdown()
+	for s := SHOWDOWN_WIN; s >= SHOWDOWN_LOSS; s-- {
+		if (len(byShowdown[s]) > 0) {
+			ret += outsLine(byShowdown[s], showdownToStr(s))
+		}
+	}
+	ret += "every unseen card:\n"
+	for i := range(rep.Cards) {
+		o := rep.Cards[i]
+		ret += fmt.Sprintf("%s: %s", o.Card.ShortString(), HandTyToStr(o.Hand.ty))
+		if (rep.HasOpponents) {
+			ret += fmt.Sprintf(" (%s -> %s)", showdownToStr(rep.CurrentShowdown),
+				showdownToStr(o.Showdown))
+		}
+		ret += "\n"
+	}
+	return ret
+}
@@ -0,0 +1,59 @@
+/*
+ * Copyright 2011 Colin Patrick McCabe
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, version 2.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package main
+
+import (
+	"testing"
+)
+
+func makeScenario(t *testing.T, req *OddsRequest) *Scenario {
+	sc, err := req.Scenario()
+	if (err != nil) {
+		t.Fatalf("failed to create scenario: %s", err.Error())
+	}
+	return sc
+}
+
+func TestOuts1(t *testing.T) {
+	sc := makeScenario(t, &OddsRequest { Hole: "KH QH", Board: "AH 3H 5C 9D",
+		Opponents: []string { "AS 9S" } })
+	rep, err := FindOuts(sc, Make52CardBag())
+	if (err != nil) {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	if (len(rep.Cards) != 44) {
+		t.Errorf("expected 44 unseen cards, got %d", len(rep.Cards))
+	}
+	if (rep.CurrentShowdown != SHOWDOWN_LOSS) {
+		t.Errorf("expected to be losing right now")
+	}
+	flushes := rep.OutsByHandTy()[FLUSH].ShortString()
+	if (flushes != "2H 4H 5H 6H 7H 8H 9H 10H JH") {
+		t.Errorf("unexpected flush outs: %s", flushes)
+	}
+	// 9H gives our opponent a full house.
+	wins := rep.OutsByShowdown()[SHOWDOWN_WIN].ShortString()
+	if (wins != "2H 4H 5H 6H 7H 8H 10H JH") {
+		t.Errorf("unexpected winning outs: %s", wins)
+	}
+
+	sc = makeScenario(t, &OddsRequest { Hole: "KH QH", Board: "AH 3H 5C 9D 2S" })
+	_, err = FindOuts(sc, Make52CardBag())
+	if (err == nil) {
+		t.Errorf("expected an error when there are no cards to come")
+	}
+}
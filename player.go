@@ -0,0 +1,218 @@
+/*
+ * Copyright 2011 Colin Patrick McCabe
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, version 2.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"strconv"
+	"strings"
+)
+
+/* Everything a player can see when it is their turn to act: their own hole
+ * cards, but nobody else's. Events must not be modified.
+ */
+type GameView struct {
+	Seat int
+	Hole CardSlice
+	Board CardSlice
+	Street int
+	Button int
+	BigBlind int64
+	Pot int64
+	CurrentBet int64
+	Stacks []int64
+	Bets []int64
+	Folded []bool
+	Events []GameEvent
+	Options ActionOptions
+}
+
+/* Returns what the player in ToAct can see. */
+func (g *Game) View() *GameView {
+	v := &GameView { Seat: g.ToAct, Hole: g.Seats[g.ToAct].Hole,
+		Board: g.Board, Street: g.Street, Button: g.Button,
+		BigBlind: g.Config.BigBlind, Pot: g.Pot(), CurrentBet: g.CurrentBet,
+		Events: g.Events, Options: g.Options() }
+	for i := range(g.Seats) {
+		v.Stacks = append(v.Stacks, g.Seats[i].Stack)
+		v.Bets = append(v.Bets, g.Seats[i].Bet)
+		v.Folded = append(v.Folded, g.Seats[i].Folded)
+	}
+	return v
+}
+
+/* Returns the number of players who haven't folded. */
+func (v *GameView) NumLive() int {
+	ret := 0
+	for i := range(v.Folded) {
+		if (!v.Folded[i]) {
+			ret++
+		}
+	}
+	return ret
+}
+
+/* Returns the check or call action. */
+func (v *GameView) checkOrCall() Action {
+	if (v.Options.ToCall > 0) {
+		return Action { ACTION_CALL, 0 }
+	}
+	return Action { ACTION_CHECK, 0 }
+}
+
+/* Returns a bet or raise to 'amount', moved into the legal range. */
+func (v *GameView) raiseTo(amount int64) Action {
+	if (amount < v.Options.MinRaiseTo) {
+		amount = v.Options.MinRaiseTo
+	}
+	if (amount > v.Options.MaxRaiseTo) {
+		amount = v.Options.MaxRaiseTo
+	}
+	if (v.CurrentBet == 0) {
+		return Action { ACTION_BET, amount }
+	}
+	return Action { ACTION_RAISE, amount }
+}
+
+/* Someone, or something, that plays poker. Act is given what the player can
+ * see, and returns what they do, which must be legal. Players may be asked
+ * to act in several hands at once, so any randomness should come from rng.
+ */
+type Player interface {
+	Act(v *GameView, rng *rand.Rand) Action
+}
+
+/* Play a hand to the end. */
+func PlayHand(g *Game, players []Player, rng *rand.Rand) error {
+	for ; !g.Done(); {
+		s := g.Seats[g.ToAct]
+		a := players[g.ToAct].Act(g.View(), rng)
+		err := g.Act(a)
+		if (err != nil) {
+			return fmt.Errorf("%s tried to %s on the %s: %s", s.Name,
+				a.String(), StreetToStr(g.Street), err.Error())
+		}
+	}
+	return nil
+}
+
+/* Never folds and never raises. */
+type CallingStation struct {
+}
+
+func (b *CallingStation) Act(v *GameView, rng *rand.Rand) Action {
+	return v.checkOrCall()
+}
+
+/* Folds, calls, or raises a random amount, each as often as the others. It
+ * never folds when it can check.
+ */
+type RandomBot struct {
+}
+
+func (b *RandomBot) Act(v *GameView, rng *rand.Rand) Action {
+	switch (rng.Intn(3)) {
+	case 0:
+		if (v.Options.ToCall > 0) {
+			return Action { ACTION_FOLD, 0 }
+		}
+	case 1:
+		if (v.Options.CanRaise) {
+			min := v.Options.MinRaiseTo
+			return v.raiseTo(min + rng.Int63n(v.Options.MaxRaiseTo - min + 1))
+		}
+	}
+	return v.checkOrCall()
+}
+
+/* The default number of showdowns an EquityBot deals for each decision. */
+const DEFAULT_BOT_SAMPLES = 200
+
+/* Works out its equity against everyone left in the hand, as if they held
+ * random cards. It raises the size of the pot if its equity is at least
+ * Threshold, calls if its equity is enough for the pot odds, and otherwise
+ * checks or folds.
+ */
+type EquityBot struct {
+	Threshold float64
+	Samples int
+}
+
+func (b *EquityBot) Act(v *GameView, rng *rand.Rand) Action {
+	numRandom := v.NumLive() - 1
+	if (numRandom > MAX_RANDOM_OPPONENTS) {
+		numRandom = MAX_RANDOM_OPPONENTS
+	}
+	sc := &Scenario { Hole: v.Hole, Board: v.Board, Samples: b.Samples,
+		Seed: rng.Int63() }
+	if (sc.Samples == 0) {
+		sc.Samples = DEFAULT_BOT_SAMPLES
+	}
+	eq, err := CalcEquity(sc, numRandom, Make52CardBag())
+	if (err != nil) {
+		panic(err)
+	}
+	equity := eq.Value()
+	toCall := v.Options.ToCall
+	if ((equity >= b.Threshold) && (v.Options.CanRaise)) {
+		return v.raiseTo(v.CurrentBet + v.Pot + toCall)
+	}
+	if (toCall == 0) {
+		return Action { ACTION_CHECK, 0 }
+	}
+	if (equity >= float64(toCall) / float64(v.Pot + toCall)) {
+		return Action { ACTION_CALL, 0 }
+	}
+	return Action { ACTION_FOLD, 0 }
+}
+
+/* Make a bot from a description like "call", "random", or "equity:0.6". */
+func NewBot(str string) (Player, error) {
+	name := str
+	arg := ""
+	idx := strings.Index(str, ":")
+	if (idx != -1) {
+		name = str[:idx]
+		arg = str[idx + 1:]
+	}
+	switch (name) {
+	case "call":
+		if (arg == "") {
+			return &CallingStation {}, nil
+		}
+	case "random":
+		if (arg == "") {
+			return &RandomBot {}, nil
+		}
+	case "equity":
+		threshold := 0.6
+		if (arg != "") {
+			var err error
+			threshold, err = strconv.ParseFloat(arg, 64)
+			if ((err != nil) || (threshold < 0) || (threshold > 1)) {
+				return nil, fmt.Errorf("the equity threshold must be a " +
+					"number from 0 to 1, not '%s'.", arg)
+			}
+		}
+		return &EquityBot { Threshold: threshold }, nil
+	default:
+		return nil, fmt.Errorf("unknown bot '%s'. The bots are call, " +
+			"random, and equity:[threshold].", name)
+	}
+	return nil, fmt.Errorf("the %s bot doesn't take any options.", name)
+}
@@ -17,6 +17,7 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"os"
@@ -41,68 +42,165 @@ Usage:
 -a [your hand as a whitespace-separated list of cards]
 -b [the board as a whitespace-separated list of cards]
 If no -b is given, it will be assumed that no cards are on the board.
+-o [an opponent's hole cards]
+These cards will not be dealt onto the board. -o may be given more than once.
+-n [num_opponents]
+Also show your chance of winning against this many opponents (1 to 9) holding
+random cards, in addition to any given with -o.
+-ntable
+Show your chance of winning against each number of random opponents, up to
+the number given with -n (or 9).
 
 -g [num_goroutines]               Set the number of goroutines to use.
+-m [num_samples]                  Deal this many random boards (Monte Carlo
+                                  mode) rather than trying every board.
+-s [seed]                         The random seed to use with -m.
+
+-outs
+List every card that could come next and what it would give you, grouped into
+outs. Only works on the flop or the turn.
+
+-tree
+Show how the odds change with every possible turn card, and what each turn
+card leaves you with going to the river. Only works on the flop.
+
+-nuts [n]
+List the n best hole cards that anyone could hold on this board, best first.
+-strength
+Show how many of the hole cards an opponent could hold beat us right now.
+-potential
+Show our hand strength, positive and negative potential, and effective hand
+strength against the opponent's range. Only works on the flop or the turn.
+-range [range]
+The opponent's range, for example "QQ+, AKs, AQs-ATs, KsQs". The default is
+"random", meaning any two cards. Any part of the range may be given a weight
+between 0 and 1, like "AKs:0.5", to count those combos only some of the time.
+Ranges can be combined with " + ", " & " and " - ", as in "top15%% - 22-55".
+"@file" reads the range from a file, such as one exported by a solver.
+
+-pot [chips]
+-bet [chips]
+Work out whether calling a bet of this size pays. The pot is what was in it
+before the bet. Our equity comes from -o and -n if they are given, and from
+the -range otherwise. Prints the equity needed to call, what calling is worth
+compared with folding, and, with cards still to come, how much more we would
+have to win on later streets for calling to pay.
+-stack [chips]
+The effective stack, if it limits what can be called or won later.
+-draw [hand]
+Use the chance of making this type of hand or better, such as "flush" or
+"straight", as our equity instead.
+
+-batch [file]
+Read one scenario per line from this file ('-' means stdin) and print one
+result per line. A scenario is either text such as "KS QS | AS 3S 5S", with
+any opponents' hole cards in further '|' separated fields, or a JSON request
+like those accepted by 'serve'. -g sets how many scenarios run at once.
+-cache-size [n]
+Remember the results of the last n scenarios, so that repeated scenarios, and
+those which differ only by suit, are not calculated again. 0 disables this.
+-cache-file [file]
+Keep every result in this file, and reuse the results already in it.
 
 -h this help message
 
 Usage Example:
 %s -a KS\ QS
 Find the outs you have pre-flop with a king and queen of spades.
-`, os.Args[0], os.Args[0])
-}
 
-func checkHoleLength(hlen int) {
-	if (hlen == 2) {
-		return
-	}
-	fmt.Printf("illegal hole length. Expected a length of 2, " +
-		"but you gave %d hole cards.\n", hlen)
-	os.Exit(1)
-}
+Subcommands:
+%s serve [options]
+Run an HTTP server which answers JSON odds requests. See '%s serve -h'.
 
-func checkBoardLength(blen int) {
-	var validLens = []int { 0, 3, 4, 5 }
-	for i := range(validLens) {
-		if (blen == validLens[i]) {
-			if (blen == 0) {
-				fmt.Printf("Now calculating ALL possible hands that can be " +
-					"made starting with these hole cards. This will take a " +
-					"while! You may want to set GOMAXPROCS and use -g.\n" +
-					"Note: It is much faster to calculate your odds " +
-					"AFTER the flop.\n")
-			}
-			return
-		}
-	}
+%s ranges [options] [range 1] [range 2] ...
+Play two or more ranges against each other. See '%s ranges -h'.
 
-	fmt.Printf("illegal board length. Expected a length of %s, " +
-		"but your board length was %d.\n", intsToStr(validLens), blen)
-	os.Exit(1)
+%s composition -b [board] [range]
+Show what a range makes on a board. See '%s composition -h'.
+
+%s range [options] [range]
+Write a range out the way solvers do. See '%s range -h'.
+
+%s grid [options] [range]
+Draw a range as a 13x13 grid of starting hands. See '%s grid -h'.
+
+%s showdown -b [board] [player] [player] ...
+Settle the pot at showdown, including side pots. See '%s showdown -h'.
+
+%s compare -b [board] [cards 1] [cards 2]
+Describe two hands and explain which one wins. See '%s compare -h'.
+
+%s simulate [options] [bot] [bot] ...
+Play bots against each other for many hands. See '%s simulate -h'.
+
+%s history [options] [file] [file] ...
+Compare winnings with all-in equity in hand histories. See '%s history -h'.
+
+%s timeline [options] [file] [file] ...
+Show each player's equity street by street in hand histories. See
+'%s timeline -h'.
+
+%s icm [options] -p [payouts] [stack] [stack] ...
+Value tournament stacks and all-in decisions in prize money. See
+'%s icm -h'.
+
+%s pushfold [options] [stack]
+Find the Nash equilibrium for heads-up push/fold. See '%s pushfold -h'.
+
+%s river [options] -b [board] [oop range] [ip range]
+Solve the betting on the river between two ranges. See '%s river -h'.
+`, os.Args[0], os.Args[0], os.Args[0], os.Args[0], os.Args[0], os.Args[0],
+	os.Args[0], os.Args[0], os.Args[0], os.Args[0], os.Args[0], os.Args[0],
+	os.Args[0], os.Args[0], os.Args[0], os.Args[0], os.Args[0], os.Args[0],
+	os.Args[0], os.Args[0], os.Args[0], os.Args[0], os.Args[0], os.Args[0],
+	os.Args[0], os.Args[0], os.Args[0], os.Args[0])
 }
 
-func intsToStr(s []int) (string) {
+/* A flag.Value that collects the hole cards of every opponent given with a
+ * repeated flag.
+ */
+type opponentsFlag []CardSlice
+
+func (o *opponentsFlag) String() string {
 	ret := ""
 	sep := ""
-	for i := range(s) {
-		ret += fmt.Sprintf("%s%d", sep, s[i])
-		sep = ", "
+	for i := range(*o) {
+		ret += sep + (*o)[i].String()
+		sep = "; "
 	}
 	return ret
 }
 
+func (o *opponentsFlag) Set(str string) error {
+	cards, err := ParseCards(str, fmt.Sprintf("the hole cards of opponent %d",
+		len(*o) + 1))
+	if (err != nil) {
+		return err
+	}
+	*o = append(*o, cards)
+	return nil
+}
+
+func die(err error) {
+	fmt.Printf("%s\n", err.Error())
+	os.Exit(1)
+}
+
 func processHand(h *Hand) {
 	fmt.Printf("%s\n", h.String())
 }
 
-/* Assumptions: we are the only players in the game
- * (Future enhancement: allow the user to specify cards that other players hold!)
+/* Assumptions: the odds of making each type of hand only depend on our own
+ * cards, although we may know some of the cards that other players hold. When
+ * there are opponents, known or random, we also calculate our equity against
+ * them.
  * 
  * 1. Get inputs
  * a. your hand (required)
  * b. the board (0 cards, 3 , 4, or 5 cards)
  *         Other numbers of cards represent errors
  *         (Future enhancement: support other poker games besides Texas Hold em')
+ * c. the hole cards of any opponents we know about (optional)
  * 
  * 2. for all possible final boards:
  *        Determine the best type of hand we can make with this board and the
@@ -113,6 +211,50 @@ func processHand(h *Hand) {
  *
  */
 func main() {
+	if (len(os.Args) > 1) {
+		switch (os.Args[1]) {
+		case "serve":
+			serveMain(os.Args[2:])
+			return
+		case "ranges":
+			rangesMain(os.Args[2:])
+			return
+		case "composition":
+			compositionMain(os.Args[2:])
+			return
+		case "range":
+			rangeMain(os.Args[2:])
+			return
+		case "grid":
+			gridMain(os.Args[2:])
+			return
+		case "showdown":
+			showdownMain(os.Args[2:])
+			return
+		case "compare":
+			compareMain(os.Args[2:])
+			return
+		case "simulate":
+			simulateMain(os.Args[2:])
+			return
+		case "history":
+			historyMain(os.Args[2:])
+			return
+		case "timeline":
+			timelineMain(os.Args[2:])
+			return
+		case "icm":
+			icmMain(os.Args[2:])
+			return
+		case "pushfold":
+			pushFoldMain(os.Args[2:])
+			return
+		case "river":
+			riverMain(os.Args[2:])
+			return
+		}
+	}
+
 	///// Parse and validate user input ///// 
 	flag.Usage = usage
 	var verbose = flag.Bool("v", false, "verbose")
@@ -120,91 +262,214 @@ func main() {
 	var holeStr = flag.String("a", "", "your two hole cards")
 	var boardStr = flag.String("b", "", "the board")
 	var numCsp = flag.Int("g", 3, "number of goprocs")
+	var samples = flag.Int("m", 0, "number of Monte Carlo samples")
+	var seed = flag.Int64("s", 1, "Monte Carlo random seed")
+	var opponents opponentsFlag
+	flag.Var(&opponents, "o", "an opponent's hole cards")
+	var showOuts = flag.Bool("outs", false, "list the outs")
+	var showTree = flag.Bool("tree", false, "show the odds street by street")
+	var numNuts = flag.Int("nuts", 0, "list the best N holdings on the board")
+	var showStrength = flag.Bool("strength", false, "show our hand strength")
+	var showPotential = flag.Bool("potential", false, "show our hand potential")
+	var rangeStr = flag.String("range", "random", "the opponent's range")
+	var numRandom = flag.Int("n", 0, "number of random opponents")
+	var showEquityTable = flag.Bool("ntable", false,
+		"show equity against each number of random opponents")
+	var pot = flag.Float64("pot", 0, "the pot before the bet")
+	var bet = flag.Float64("bet", 0, "the bet we have to call")
+	var stack = flag.Float64("stack", 0, "the effective stack")
+	var drawStr = flag.String("draw", "",
+		"use the chance of making this hand as our equity")
+	var batchFile = flag.String("batch", "", "read scenarios from this file")
+	var cacheSize = flag.Int("cache-size", DEFAULT_CACHE_SZ,
+		"number of batch results to remember")
+	var cacheFile = flag.String("cache-file", "",
+		"file in which to keep batch results")
 
 	flag.Parse()
 	if (*help) {
 		usage()
 		os.Exit(0)
 	}
+	if (*batchFile != "") {
+		batchMain(*batchFile, *numCsp, *cacheSize, *cacheFile)
+		return
+	}
 	if (*holeStr == "") {
 		fmt.Printf("You must give two hole cards with -a\n")
 		usage()
 		os.Exit(1)
 	}
-	var hole CardSlice
-	var errIdx int
-	hole, errIdx = StrToCards(*holeStr)
-	if (errIdx != -1) {
-		fmt.Printf("Error parsing your hole cards: parse error at character %d\n",
-					errIdx)
-		os.Exit(1)
+	sc := &Scenario { Game: GAME_HOLDEM, Opponents: opponents,
+		Samples: *samples, Seed: *seed }
+	var err error
+	sc.Hole, err = ParseCards(*holeStr, "your hole cards")
+	if (err != nil) {
+		die(err)
+	}
+	err = checkHoleLength(len(sc.Hole))
+	if (err != nil) {
+		die(err)
 	}
-	checkHoleLength(len(hole))
 	if (*verbose) {
-		fmt.Printf("Your hole cards: '%s'\n", hole.String());
+		fmt.Printf("Your hole cards: '%s'\n", sc.Hole.String());
 	}
-	var board CardSlice
-	board, errIdx = StrToCards(*boardStr)
-	if (errIdx != -1) {
-		fmt.Printf("Error parsing the board: parse error at character %d\n",
-					errIdx)
-		os.Exit(1)
+	sc.Board, err = ParseCards(*boardStr, "the board")
+	if (err != nil) {
+		die(err)
+	}
+	err = sc.Validate()
+	if (err != nil) {
+		die(err)
+	}
+	var potOdds *PotOdds
+	if ((*bet > 0) || (*pot > 0) || (*stack > 0) || (*drawStr != "")) {
+		potOdds = &PotOdds { Pot: *pot, Bet: *bet, Stack: *stack,
+			CardsToCome: (len(sc.Board) < BOARD_MAX) }
+		err = potOdds.Validate()
+		if (err != nil) {
+			die(err)
+		}
+	}
+	drawTy := -1
+	if (*drawStr != "") {
+		drawTy, err = parseHandTy(*drawStr)
+		if (err != nil) {
+			die(err)
+		}
+	}
+	if ((len(sc.Board) == 0) && (sc.Samples == 0)) {
+		fmt.Printf("Now calculating ALL possible hands that can be " +
+			"made starting with these hole cards. This will take a " +
+			"while! You may want to set GOMAXPROCS and use -g or -m.\n" +
+			"Note: It is much faster to calculate your odds " +
+			"AFTER the flop.\n")
 	}
-	checkBoardLength(len(board))
 	if (*verbose) {
-		fmt.Printf("The board: '%s'\n", board.String());
-	}
-	base := make(CardSlice, len(board) + len(hole))
-	copy(base, board)
-	copy(base[len(board):], hole)
-	dupe := base.HasDuplicates()
-	if (dupe != nil) {
-		fmt.Printf("The card %s appears more than once in your input! " +
-			"That is not possible.\n", dupe)
-		os.Exit(1)
+		fmt.Printf("The board: '%s'\n", sc.Board.String());
 	}
 
 	///// Process cards ///// 
-	csps := make([]*CardSliceProcessor, *numCsp)
-	for i := range(csps) {
-		csps[i] = NewCardSliceProcessor(base)
-		go csps[i].GoCardSliceProcessor()
-	}
-
-	future := Make52CardBag()
-	for i := range(base) {
-		future.Subtract(base[i])
-	}
-	numFutureCards := SPREAD_MAX - len(base)
-	futureChooser := NewSubsetChooser(uint(future.Len()), uint(numFutureCards))
-	cspIdx := 0
-	for ;; {
-		futureC := futureChooser.Cur()
-		for i := 0; i < numFutureCards; i++ {
-			csps[cspIdx].Card <- future.Get(futureC[i])
+	deck := Make52CardBag()
+	var outs *OutsReport
+	if (*showOuts) {
+		outs, err = FindOuts(sc, deck)
+		if (err != nil) {
+			die(err)
 		}
-		cspIdx++
-		if (cspIdx >= *numCsp) {
-			cspIdx = 0
+	}
+	var nuts []*Holding
+	if (*numNuts > 0) {
+		err = checkPostflop(sc.Board, "the nuts")
+		if (err != nil) {
+			die(err)
 		}
-		if (!futureChooser.Next()) {
-			break
+		nuts = RankHoldings(sc.Board, nil, deck)
+	}
+	var strength *HandStrength
+	if (*showStrength) {
+		strength, err = CalcHandStrength(sc, deck)
+		if (err != nil) {
+			die(err)
 		}
 	}
-
-	// Tell cardSliceProcessors to finish
-	for i := range(csps) {
-		csps[i].Quit <- true
+	var potential *HandPotential
+	if (*showPotential) {
+		var oppRange *Range
+		oppRange, err = LoadRange(*rangeStr)
+		if (err != nil) {
+			die(err)
+		}
+		potential, err = CalcHandPotential(sc, oppRange, deck)
+		if (err != nil) {
+			die(err)
+		}
 	}
-
-	// Once each cardSliceProcessor is finished, get its results
-	// Merge all results together
-	allResults := new(ResultSet)
-	for i := range(csps) {
-		<-csps[i].Finished
-		allResults.MergeResultSet(&csps[i].Results)
+	var equity *Equity
+	if ((*numRandom > 0) || (len(sc.Opponents) > 0)) {
+		equity, err = CalcEquity(sc, *numRandom, deck)
+		if (err != nil) {
+			die(err)
+		}
+	}
+	equityTable := ""
+	if (*showEquityTable) {
+		maxRandom := *numRandom
+		if (maxRandom == 0) {
+			maxRandom = MAX_RANDOM_OPPONENTS
+		}
+		equityTable, err = EquityTable(sc, maxRandom, deck)
+		if (err != nil) {
+			die(err)
+		}
+	}
+	var tree *StreetTree
+	if (*showTree) {
+		tree, err = BuildStreetTree(sc, deck)
+		if (err != nil) {
+			die(err)
+		}
+	}
+	allResults, err := CalcOdds(context.Background(), sc, deck, *numCsp)
+	if (err != nil) {
+		die(err)
+	}
+	if (potOdds != nil) {
+		if (drawTy != -1) {
+			potOdds.Equity = allResults.ChanceOfAtLeast(drawTy)
+			potOdds.What = fmt.Sprintf("chance of making %s or better",
+				HandTyToStr(drawTy))
+		} else if (equity != nil) {
+			potOdds.Equity = equity.Value()
+			potOdds.What = fmt.Sprintf("equity against %d known and %d " +
+				"random opponents", len(sc.Opponents), *numRandom)
+		} else {
+			var oppRange *Range
+			oppRange, err = LoadRange(*rangeStr)
+			if (err != nil) {
+				die(err)
+			}
+			var vsRange *Equity
+			vsRange, err = holeVsRange(sc.Hole, sc.Board, oppRange,
+				*samples, *seed, deck)
+			if (err != nil) {
+				die(err)
+			}
+			potOdds.Equity = vsRange.Value()
+			potOdds.What = fmt.Sprintf("equity against the range %s",
+				*rangeStr)
+		}
 	}
 
 	// Now print the final results
+	if (len(sc.Board) > 0) {
+		fmt.Printf("your hand: %s\n", ClassifyHand(sc.Hole, sc.Board))
+	}
 	fmt.Printf("results:\n%s", allResults.String())
+	if (equity != nil) {
+		fmt.Printf("equity against %d known and %d random opponents " +
+			"(%s):\n%s\n", len(sc.Opponents), *numRandom,
+			equityMethodStr(equity), equity.String())
+	}
+	if (equityTable != "") {
+		fmt.Printf("equity by number of opponents:\n%s", equityTable)
+	}
+	if (outs != nil) {
+		fmt.Printf("outs:\n%s", outs.String())
+	}
+	if (tree != nil) {
+		fmt.Printf("street by street:\n%s", tree.String())
+	}
+	if (nuts != nil) {
+		fmt.Printf("best holdings:\n%s", HoldingsToStr(nuts, *numNuts))
+	}
+	if (strength != nil) {
+		fmt.Printf("hand strength:\n%s", strength.String())
+	}
+	if (potential != nil) {
+		fmt.Printf("hand potential %s", potential.String())
+	}
+	if (potOdds != nil) {
+		fmt.Printf("pot odds:\n%s", potOdds.String())
+	}
 }
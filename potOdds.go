@@ -0,0 +1,154 @@
+/*
+ * Copyright 2011 Colin Patrick McCabe
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, version 2.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+/* Facing a bet, we call if our share of the final pot is worth more than
+ * what it costs to call. Pot is what was in the pot before the bet, and Bet
+ * is what we have to call. If Stack is not 0, it is the effective stack: the
+ * most either of us can put in. Equity is our share of the pot, and What
+ * says where it came from.
+ *
+ * CardsToCome is false on the river, where there are no later streets to
+ * win more chips on.
+ */
+type PotOdds struct {
+	Pot float64
+	Bet float64
+	Stack float64
+	Equity float64
+	What string
+	CardsToCome bool
+}
+
+func (po *PotOdds) Validate() error {
+	if ((po.Pot < 0) || (po.Bet <= 0) || (po.Stack < 0)) {
+		return fmt.Errorf("the bet must be positive, and the pot and the " +
+			"stack can't be negative.")
+	}
+	return nil
+}
+
+/* Returns what we have to put in to call. If we can't cover the bet, we call
+ * all-in, and the rest of the bet goes back to the bettor.
+ */
+func (po *PotOdds) ToCall() float64 {
+	if ((po.Stack > 0) && (po.Stack < po.Bet)) {
+		return po.Stack
+	}
+	return po.Bet
+}
+
+/* Returns the pot we play for if we call. */
+func (po *PotOdds) FinalPot() float64 {
+	return po.Pot + 2.0 * po.ToCall()
+}
+
+/* Returns the equity we need for calling to break even. */
+func (po *PotOdds) BreakEven() float64 {
+	return po.ToCall() / po.FinalPot()
+}
+
+/* Returns what calling wins on average, compared with folding. */
+func (po *PotOdds) CallEv() float64 {
+	return po.Equity * po.FinalPot() - po.ToCall()
+}
+
+/* Returns how much more we would have to win on later streets, when we
+ * make our hand, for calling to break even. This is 0 if calling already
+ * pays.
+ */
+func (po *PotOdds) ImpliedNeeded() float64 {
+	ev := po.CallEv()
+	if (ev >= 0) {
+		return 0
+	}
+	if (po.Equity == 0) {
+		return -1
+	}
+	return -ev / po.Equity
+}
+
+func (po *PotOdds) String() string {
+	call := po.ToCall()
+	ret := fmt.Sprintf("calling %s to win %s", chipsToStr(call),
+		chipsToStr(po.Pot + call))
+	if (call < po.Bet) {
+		ret += " (all-in)"
+	}
+	ret += fmt.Sprintf(" needs %.2f%% equity\n", po.BreakEven() * 100.0)
+	ret += fmt.Sprintf("%s: %.2f%%\n", po.What, po.Equity * 100.0)
+	ev := po.CallEv()
+	if (ev >= 0) {
+		ret += fmt.Sprintf("calling is worth %s more than folding\n",
+			chipsToStr(ev))
+		return ret
+	}
+	ret += fmt.Sprintf("folding is worth %s more than calling\n",
+		chipsToStr(-ev))
+	if (!po.CardsToCome) {
+		return ret
+	}
+	implied := po.ImpliedNeeded()
+	if (implied < 0) {
+		ret += "no amount of implied odds can make calling pay\n"
+		return ret
+	}
+	ret += fmt.Sprintf("implied odds: calling pays if you win %s more on " +
+		"later streets when you get there", chipsToStr(implied))
+	if (po.Stack > 0) {
+		behind := po.Stack - call
+		if (implied > behind) {
+			ret += fmt.Sprintf(", but only %s is left behind",
+				chipsToStr(behind))
+		} else {
+			ret += fmt.Sprintf(", out of %s left behind",
+				chipsToStr(behind))
+		}
+	}
+	ret += "\n"
+	return ret
+}
+
+/* Parse the name of a type of hand, like "flush" or "full house". */
+func parseHandTy(str string) (int, error) {
+	norm := func(s string) string {
+		s = strings.ToLower(strings.TrimSpace(s))
+		s = strings.TrimPrefix(s, "a ")
+		s = strings.Replace(s, "-", "", -1)
+		return strings.Replace(s, " ", "", -1)
+	}
+	aliases := map[string] int { "trips": THREE_OF_A_KIND,
+		"set": THREE_OF_A_KIND, "boat": FULL_HOUSE,
+		"quads": FOUR_OF_A_KIND }
+	want := norm(str)
+	ty, ok := aliases[want]
+	if (ok) {
+		return ty, nil
+	}
+	for ty = HIGH_CARD; ty < MAX_HANDS; ty++ {
+		if (norm(HandTyToStr(ty)) == want) {
+			return ty, nil
+		}
+	}
+	return -1, fmt.Errorf("unknown type of hand '%s'. Expected something " +
+		"like 'flush' or 'full house'.", str)
+}
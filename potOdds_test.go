@@ -0,0 +1,91 @@
+/*
+ * Copyright 2011 Colin Patrick McCabe
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, version 2.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package main
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestPotOdds(t *testing.T) {
+	po := &PotOdds { Pot: 100, Bet: 50, Equity: 0.2, CardsToCome: true }
+	if (po.Validate() != nil) {
+		t.Errorf("expected a pot of 100 and a bet of 50 to be valid.")
+	}
+	if (po.BreakEven() != 0.25) {
+		t.Errorf("expected calling 50 to win 150 to need 25%% equity, " +
+			"but it needed %f", po.BreakEven())
+	}
+	if (math.Abs(po.CallEv() - -10) > 1e-9) {
+		t.Errorf("expected calling with 20%% equity to lose 10, but " +
+			"got %f", po.CallEv())
+	}
+	if (math.Abs(po.ImpliedNeeded() - 50) > 1e-9) {
+		t.Errorf("expected to need 50 more in implied odds, but got %f",
+			po.ImpliedNeeded())
+	}
+	po.Stack = 60
+	if (!strings.Contains(po.String(), "but only 10 is left behind")) {
+		t.Errorf("expected the implied odds to be limited by the stack, " +
+			"but got:\n%s", po.String())
+	}
+
+	po.Stack = 20
+	if (po.ToCall() != 20) {
+		t.Errorf("expected to call all-in for 20, but got %f", po.ToCall())
+	}
+	if (math.Abs(po.BreakEven() - 1.0 / 7.0) > 1e-9) {
+		t.Errorf("expected calling 20 to win 120 to need 1/7 equity, " +
+			"but it needed %f", po.BreakEven())
+	}
+	if (po.ImpliedNeeded() != 0) {
+		t.Errorf("expected calling all-in for 20 with 20%% equity to pay.")
+	}
+	if (!strings.Contains(po.String(), "(all-in)")) {
+		t.Errorf("expected the call to be all-in, but got:\n%s",
+			po.String())
+	}
+
+	po = &PotOdds { Pot: 100, Bet: 50, CardsToCome: true }
+	if (po.ImpliedNeeded() != -1) {
+		t.Errorf("expected no implied odds to be enough with no equity.")
+	}
+	po.Bet = 0
+	if (po.Validate() == nil) {
+		t.Errorf("expected a bet of 0 to be rejected.")
+	}
+}
+
+func TestParseHandTy(t *testing.T) {
+	names := map[string] int { "flush": FLUSH, "Full House": FULL_HOUSE,
+		"a straight": STRAIGHT, "trips": THREE_OF_A_KIND,
+		"two pair": TWO_PAIR, "straight-flush": STRAIGHT_FLUSH }
+	for name, want := range(names) {
+		ty, err := parseHandTy(name)
+		if (err != nil) {
+			t.Errorf("expected to parse '%s', but got error %s", name, err)
+		} else if (ty != want) {
+			t.Errorf("expected '%s' to be %s, but got %s", name,
+				HandTyToStr(want), HandTyToStr(ty))
+		}
+	}
+	_, err := parseHandTy("royal pair")
+	if (err == nil) {
+		t.Errorf("expected 'royal pair' not to parse.")
+	}
+}
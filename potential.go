@@ -0,0 +1,126 @@
+/*
+ * Copyright 2011 Colin Patrick McCabe
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, version 2.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package main
+
+import (
+	"fmt"
+)
+
+const (
+	POT_BEHIND = iota
+	POT_TIED
+	POT_AHEAD
+)
+
+func rankCmpIdx(ours int, theirs int) int {
+	if (ours > theirs) {
+		return POT_AHEAD
+	} else if (ours == theirs) {
+		return POT_TIED
+	}
+	return POT_BEHIND
+}
+
+/* The hand potential metrics described by Billings et al. in "Opponent
+ * Modeling in Poker" (1998).
+ *
+ * HS is the chance that we are ahead of the opponent right now, counting ties
+ * as half. PPot is the chance that we are behind now but will be ahead by the
+ * river, and NPot is the chance that we are ahead now but will be behind by
+ * the river. EHS, the effective hand strength, combines all three.
+ */
+type HandPotential struct {
+	Combos float64
+	HS float64
+	PPot float64
+	NPot float64
+	EHS float64
+}
+
+/* Calculate the hand potential metrics against an opponent holding any combo
+ * in oppRange. Each combo left in the range counts for its weight.
+ *
+ * This enumerates every opponent combo together with every way the board
+ * could be completed, so it can only be done on the flop or the turn.
+ */
+func CalcHandPotential(sc *Scenario, oppRange *Range,
+		deck *CardBag) (*HandPotential, error) {
+	if ((len(sc.Board) != 3) && (len(sc.Board) != 4)) {
+		return nil, fmt.Errorf("hand potential can only be calculated on " +
+			"the flop or the turn, but the board has %d cards.", len(sc.Board))
+	}
+	opps := oppRange.Live(sc.Known())
+	if (len(opps) == 0) {
+		return nil, fmt.Errorf("every combo in the opponent's range uses " +
+			"a card that is already out.")
+	}
+	future := sc.Future(deck)
+	var hp [3][3]float64
+	var hpTotal [3]float64
+	var hsCnt [3]float64
+	var buf [SPREAD_MAX]*Card
+	// The runout is appended to the board in place, so leave room for it.
+	board := make(CardSlice, len(sc.Board), BOARD_MAX)
+	copy(board, sc.Board)
+	need := BOARD_MAX - len(sc.Board)
+
+	ret := new(HandPotential)
+	for o := range(opps) {
+		opp := opps[o]
+		w := oppRange.Weight(opp)
+		ret.Combos += w
+		idx := rankCmpIdx(holeRank(sc.Hole, board, buf[:0]),
+			holeRank(opp, board, buf[:0]))
+		hsCnt[idx] += w
+		runout := func(cards CardSlice) {
+			full := append(board, cards...)
+			idx2 := rankCmpIdx(holeRank(sc.Hole, full, buf[:0]),
+				holeRank(opp, full, buf[:0]))
+			hp[idx][idx2] += w
+			hpTotal[idx] += w
+		}
+		left := future.Clone()
+		left.Subtract(opp[0])
+		left.Subtract(opp[1])
+		left.ForEachSubset(need, runout)
+	}
+
+	ret.HS = (hsCnt[POT_AHEAD] + hsCnt[POT_TIED] / 2.0) /
+		(hsCnt[POT_AHEAD] + hsCnt[POT_TIED] + hsCnt[POT_BEHIND])
+	pDenom := hpTotal[POT_BEHIND] + hpTotal[POT_TIED] / 2.0
+	if (pDenom > 0) {
+		ret.PPot = (hp[POT_BEHIND][POT_AHEAD] + hp[POT_BEHIND][POT_TIED] / 2.0 +
+			hp[POT_TIED][POT_AHEAD] / 2.0) / pDenom
+	}
+	nDenom := hpTotal[POT_AHEAD] + hpTotal[POT_TIED] / 2.0
+	if (nDenom > 0) {
+		ret.NPot = (hp[POT_AHEAD][POT_BEHIND] + hp[POT_AHEAD][POT_TIED] / 2.0 +
+			hp[POT_TIED][POT_BEHIND] / 2.0) / nDenom
+	}
+	ret.EHS = ret.HS * (1.0 - ret.NPot) + (1.0 - ret.HS) * ret.PPot
+	return ret, nil
+}
+
+func (hp *HandPotential) String() string {
+	return fmt.Sprintf("against %s combos:\n" +
+		"hand strength: %03.2f%%\n" +
+		"positive potential: %03.2f%%\n" +
+		"negative potential: %03.2f%%\n" +
+		"effective hand strength: %03.2f%%\n",
+		comboCountToStr(hp.Combos), hp.HS * 100.0, hp.PPot * 100.0, hp.NPot * 100.0,
+		hp.EHS * 100.0)
+}
@@ -0,0 +1,55 @@
+/*
+ * Copyright 2011 Colin Patrick McCabe
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, version 2.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestHandPotential(t *testing.T) {
+	deck := Make52CardBag()
+	sc := makeScenario(t, &OddsRequest { Hole: "AH 5H", Board: "KH 7H 4C" })
+	hp, err := CalcHandPotential(sc, RandomRange(), deck)
+	if (err != nil) {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	hs, _ := CalcHandStrength(sc, deck)
+	if (math.Abs(hp.HS - hs.Value()) > 1e-9) {
+		t.Errorf("expected hand strength %f, got %f", hs.Value(), hp.HS)
+	}
+	if ((hp.PPot <= 0.0) || (hp.EHS <= hp.HS)) {
+		t.Errorf("expected a nut flush draw to have positive potential, " +
+			"got %s", hp)
+	}
+
+	// Nobody can catch up with four aces here.
+	sc = makeScenario(t, &OddsRequest { Hole: "AS AD", Board: "AH AC 9D 2S" })
+	hp, err = CalcHandPotential(sc, RandomRange(), deck)
+	if (err != nil) {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	if ((hp.HS != 1.0) || (hp.NPot != 0.0) || (hp.EHS != 1.0)) {
+		t.Errorf("expected four aces to be unbeatable, got %s", hp)
+	}
+
+	r, _ := ParseRange("AsKs")
+	_, err = CalcHandPotential(sc, r, deck)
+	if (err == nil) {
+		t.Errorf("expected an error when the whole range is blocked")
+	}
+}
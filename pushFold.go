@@ -0,0 +1,390 @@
+/*
+ * Copyright 2011 Colin Patrick McCabe
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, version 2.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"math"
+	"math/rand"
+	"os"
+)
+
+/* Heads-up with short stacks, the small blind can do well by either moving
+ * all-in or folding, and the big blind by either calling or folding. We find
+ * the Nash equilibrium of that game: a pushing range and a calling range
+ * which are each the best response to the other, so that neither player can
+ * do better by changing theirs.
+ */
+
+const NUM_HAND_CLASSES = 169
+
+/* How many showdowns we deal for each pair of hand classes, by default. */
+const DEFAULT_PREFLOP_SAMPLES = 1000
+
+/* How many rounds of best responses we play, by default. */
+const DEFAULT_PUSH_FOLD_ITERATIONS = 2000
+
+/* The all-in equity of every hand class against every other, before the
+ * flop. Equity[i][j] is the share of the pot that class i gets against class
+ * j, and Pairs[i][j] is how many ways there are to hold a combo of each
+ * without sharing a card. Classes are numbered in the order given by
+ * allHandClasses.
+ */
+type PreflopTable struct {
+	Classes []handClass
+	Combos [][]CardSlice
+	Equity [NUM_HAND_CLASSES][NUM_HAND_CLASSES]float64
+	Pairs [NUM_HAND_CLASSES][NUM_HAND_CLASSES]int
+	deck CardSlice
+}
+
+/* Work out the equity of class i against class j, by dealing showdowns.
+ * The combos that can be held together are taken in turn, so that each one
+ * gets its share of the showdowns.
+ */
+func (t *PreflopTable) calcPair(i int, j int, samples int, seed int64) {
+	var pairs [][2]CardSlice
+	for a := range(t.Combos[i]) {
+		for b := range(t.Combos[j]) {
+			if ((cardsBits(t.Combos[i][a]) & cardsBits(t.Combos[j][b])) == 0) {
+				pairs = append(pairs, [2]CardSlice { t.Combos[i][a],
+					t.Combos[j][b] })
+			}
+		}
+	}
+	t.Pairs[i][j] = len(pairs)
+	t.Pairs[j][i] = len(pairs)
+	if (i == j) {
+		t.Equity[i][j] = 0.5
+		return
+	}
+	rng := rand.New(rand.NewSource(handSeed(seed, i * NUM_HAND_CLASSES + j)))
+	left := make(CardSlice, 0, NUM_CARDS)
+	var buf [SPREAD_MAX]*Card
+	share := 0.0
+	for n := 0; n < samples; n++ {
+		p := pairs[n % len(pairs)]
+		used := cardsBits(p[0]) | cardsBits(p[1])
+		left = left[:0]
+		for id := range(t.deck) {
+			if ((used & (1 << uint(id))) == 0) {
+				left = append(left, t.deck[id])
+			}
+		}
+		for k := 0; k < BOARD_MAX; k++ {
+			r := k + rng.Intn(len(left) - k)
+			left[k], left[r] = left[r], left[k]
+		}
+		board := left[:BOARD_MAX]
+		ra := holeRank(p[0], board, buf[:])
+		rb := holeRank(p[1], board, buf[:])
+		if (ra > rb) {
+			share += 1.0
+		} else if (ra == rb) {
+			share += 0.5
+		}
+	}
+	t.Equity[i][j] = share / float64(samples)
+	t.Equity[j][i] = 1.0 - t.Equity[i][j]
+}
+
+/* Build the table of preflop all-in equities. Each pair of classes gets its
+ * own random seed, so the table is the same however many workers share the
+ * work.
+ */
+func NewPreflopTable(samples int, seed int64, numWorkers int) *PreflopTable {
+	t := &PreflopTable { Classes: allHandClasses() }
+	for id := 0; id < NUM_CARDS; id++ {
+		t.deck = append(t.deck, cardFromId(id))
+	}
+	for i := range(t.Classes) {
+		ids := t.Classes[i].comboIds()
+		var combos []CardSlice
+		for k := range(ids) {
+			combos = append(combos, CardSlice { t.deck[ids[k] / NUM_CARDS],
+				t.deck[ids[k] % NUM_CARDS] })
+		}
+		t.Combos = append(t.Combos, combos)
+	}
+	if (numWorkers < 1) {
+		numWorkers = 1
+	}
+	done := make(chan bool)
+	for w := 0; w < numWorkers; w++ {
+		go func(w int) {
+			n := 0
+			for i := 0; i < NUM_HAND_CLASSES; i++ {
+				for j := i; j < NUM_HAND_CLASSES; j++ {
+					if (n % numWorkers == w) {
+						t.calcPair(i, j, samples, seed)
+					}
+					n++
+				}
+			}
+			done <- true
+		}(w)
+	}
+	for w := 0; w < numWorkers; w++ {
+		<-done
+	}
+	return t
+}
+
+/* A heads-up push/fold game. Both players start with Stack chips, before
+ * the blinds and antes are posted.
+ */
+type PushFoldConfig struct {
+	Stack int64
+	SmallBlind int64
+	BigBlind int64
+	Ante int64
+	Iterations int
+}
+
+func (cfg *PushFoldConfig) Validate() error {
+	if ((cfg.BigBlind <= 0) || (cfg.SmallBlind < 0) || (cfg.Ante < 0)) {
+		return fmt.Errorf("the big blind must be positive, and the small " +
+			"blind and ante can't be negative.")
+	}
+	if (cfg.Stack <= cfg.BigBlind + cfg.Ante) {
+		return fmt.Errorf("the stacks must be bigger than the big blind " +
+			"and ante.")
+	}
+	if (cfg.Iterations < 1) {
+		return fmt.Errorf("at least one iteration is needed.")
+	}
+	return nil
+}
+
+/* The equilibrium. Push[i] is how often the small blind pushes with class
+ * i, and Call[i] how often the big blind calls with it. Ev is what the small
+ * blind wins per hand, in big blinds.
+ */
+type PushFoldResult struct {
+	Config PushFoldConfig
+	Classes []handClass
+	Push []float64
+	Call []float64
+	Ev float64
+}
+
+/* Returns the small blind's winnings in chips from pushing with each class,
+ * when the big blind calls with each class as often as call says.
+ */
+func (t *PreflopTable) pushEvs(cfg *PushFoldConfig, call []float64) []float64 {
+	stack := float64(cfg.Stack)
+	steal := float64(cfg.BigBlind + cfg.Ante)
+	ret := make([]float64, NUM_HAND_CLASSES)
+	for i := 0; i < NUM_HAND_CLASSES; i++ {
+		ev := 0.0
+		total := 0.0
+		for j := 0; j < NUM_HAND_CLASSES; j++ {
+			w := float64(t.Pairs[i][j])
+			called := t.Equity[i][j] * 2.0 * stack - stack
+			ev += w * (call[j] * called + (1.0 - call[j]) * steal)
+			total += w
+		}
+		ret[i] = ev / total
+	}
+	return ret
+}
+
+/* Returns the big blind's winnings in chips from calling with each class,
+ * when the small blind pushes with each class as often as push says. NaN
+ * means the small blind never pushes a hand this class can be dealt
+ * against.
+ */
+func (t *PreflopTable) callEvs(cfg *PushFoldConfig, push []float64) []float64 {
+	stack := float64(cfg.Stack)
+	ret := make([]float64, NUM_HAND_CLASSES)
+	for j := 0; j < NUM_HAND_CLASSES; j++ {
+		ev := 0.0
+		total := 0.0
+		for i := 0; i < NUM_HAND_CLASSES; i++ {
+			w := float64(t.Pairs[i][j]) * push[i]
+			ev += w * (t.Equity[j][i] * 2.0 * stack - stack)
+			total += w
+		}
+		ret[j] = math.NaN()
+		if (total > 0) {
+			ret[j] = ev / total
+		}
+	}
+	return ret
+}
+
+/* Solve the push/fold game by fictitious play: each round, both players
+ * find their best response to the average of everything the other has done
+ * so far. The averages approach the equilibrium.
+ */
+func SolvePushFold(cfg *PushFoldConfig,
+		t *PreflopTable) (*PushFoldResult, error) {
+	err := cfg.Validate()
+	if (err != nil) {
+		return nil, err
+	}
+	res := &PushFoldResult { Config: *cfg, Classes: t.Classes,
+		Push: make([]float64, NUM_HAND_CLASSES),
+		Call: make([]float64, NUM_HAND_CLASSES) }
+	fold := -float64(cfg.SmallBlind + cfg.Ante)
+	callFold := -float64(cfg.BigBlind + cfg.Ante)
+	for i := range(res.Push) {
+		res.Push[i] = 1.0
+	}
+	for n := 1; n <= cfg.Iterations; n++ {
+		pushEv := t.pushEvs(cfg, res.Call)
+		callEv := t.callEvs(cfg, res.Push)
+		for i := 0; i < NUM_HAND_CLASSES; i++ {
+			br := 0.0
+			if (pushEv[i] >= fold) {
+				br = 1.0
+			}
+			res.Push[i] += (br - res.Push[i]) / float64(n + 1)
+			br = 0.0
+			if ((!math.IsNaN(callEv[i])) && (callEv[i] >= callFold)) {
+				br = 1.0
+			}
+			res.Call[i] += (br - res.Call[i]) / float64(n + 1)
+		}
+	}
+	pushEv := t.pushEvs(cfg, res.Call)
+	dealt := 0.0
+	for i := range(res.Push) {
+		n := float64(len(t.Combos[i]))
+		res.Ev += n * (res.Push[i] * pushEv[i] + (1.0 - res.Push[i]) * fold)
+		dealt += n
+	}
+	res.Ev /= dealt * float64(cfg.BigBlind)
+	return res, nil
+}
+
+/* Returns the range of hands played at least half the time. Fictitious play
+ * keeps switching the hands on the edge of each range in and out, so their
+ * frequencies settle slowly; rounding them gives the usual charts.
+ */
+func (res *PushFoldResult) toRange(freqs []float64) *Range {
+	r := NewRange()
+	for i := range(freqs) {
+		if (freqs[i] >= 0.5) {
+			r.addHandClass(res.Classes[i], 1.0)
+		}
+	}
+	return r
+}
+
+func (res *PushFoldResult) PushRange() *Range {
+	return res.toRange(res.Push)
+}
+
+func (res *PushFoldResult) CallRange() *Range {
+	return res.toRange(res.Call)
+}
+
+/* Returns the share of all starting hands that a range holds. */
+func rangeShareOfHands(r *Range) float64 {
+	return r.WeightedLen() / choose(NUM_CARDS, HOLE_SZ)
+}
+
+func (res *PushFoldResult) String() string {
+	cfg := &res.Config
+	ret := fmt.Sprintf("heads-up push/fold with %d chips each (%.1f big " +
+		"blinds), blinds %d/%d", cfg.Stack,
+		float64(cfg.Stack) / float64(cfg.BigBlind), cfg.SmallBlind,
+		cfg.BigBlind)
+	if (cfg.Ante > 0) {
+		ret += fmt.Sprintf(", ante %d", cfg.Ante)
+	}
+	ret += "\n"
+	push := res.PushRange()
+	call := res.CallRange()
+	ret += fmt.Sprintf("small blind pushes %.1f%% of hands:\n%s\n",
+		rangeShareOfHands(push) * 100.0, push.ClassString())
+	ret += fmt.Sprintf("big blind calls %.1f%% of hands:\n%s\n",
+		rangeShareOfHands(call) * 100.0, call.ClassString())
+	ret += fmt.Sprintf("the small blind wins %.3f big blinds per hand\n",
+		res.Ev)
+	return ret
+}
+
+func pushFoldUsage(fs *flag.FlagSet) func() {
+	return func() {
+		fmt.Fprintf(os.Stderr,
+`%s pushfold: find the Nash equilibrium for heads-up push/fold.
+
+Usage:
+%s pushfold [options] [stack]
+
+Both players start with the given number of chips. The small blind can only
+move all-in or fold, and the big blind can only call or fold. The ranges where
+neither player can do better by changing theirs are found by playing best
+responses against each other over the 169 starting hand classes, and shown in
+range notation and as grids.
+
+The all-in equities of each class against each other are worked out by
+dealing -m showdowns for each pair of classes.
+
+Options:
+`, os.Args[0], os.Args[0])
+		fs.PrintDefaults()
+	}
+}
+
+func pushFoldMain(args []string) {
+	fs := flag.NewFlagSet("pushfold", flag.ExitOnError)
+	fs.Usage = pushFoldUsage(fs)
+	var sb = fs.Int64("sb", 1, "small blind")
+	var bb = fs.Int64("bb", 2, "big blind")
+	var ante = fs.Int64("ante", 0, "ante")
+	var iterations = fs.Int("i", DEFAULT_PUSH_FOLD_ITERATIONS,
+		"rounds of best responses")
+	var samples = fs.Int("m", DEFAULT_PREFLOP_SAMPLES,
+		"showdowns to deal for each pair of hand classes")
+	var seed = fs.Int64("s", 1, "random seed")
+	var numWorkers = fs.Int("g", 3, "number of goroutines to use")
+	var noGrid = fs.Bool("nogrid", false, "don't draw the grids")
+	fs.Parse(args)
+	if (fs.NArg() != 1) {
+		fs.Usage()
+		os.Exit(1)
+	}
+	var stack int64
+	_, err := fmt.Sscanf(fs.Arg(0), "%d", &stack)
+	if (err != nil) {
+		die(fmt.Errorf("invalid stack '%s'.", fs.Arg(0)))
+	}
+	if (*samples < 1) {
+		die(fmt.Errorf("at least one sample is needed."))
+	}
+	cfg := &PushFoldConfig { Stack: stack, SmallBlind: *sb, BigBlind: *bb,
+		Ante: *ante, Iterations: *iterations }
+	err = cfg.Validate()
+	if (err != nil) {
+		die(err)
+	}
+	t := NewPreflopTable(*samples, *seed, *numWorkers)
+	res, err := SolvePushFold(cfg, t)
+	if (err != nil) {
+		die(err)
+	}
+	fmt.Printf("%s", res.String())
+	if (!*noGrid) {
+		fmt.Printf("\n%s\n%s", NewRangeGrid(res.PushRange(),
+			"small blind push").ANSIString(), NewRangeGrid(res.CallRange(),
+			"big blind call").ANSIString())
+	}
+}
@@ -0,0 +1,112 @@
+/*
+ * Copyright 2011 Colin Patrick McCabe
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, version 2.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func findClass(t *testing.T, pt *PreflopTable, str string) int {
+	hc, err := parseHandClass(str)
+	if (err != nil) {
+		t.Fatalf("%s", err.Error())
+	}
+	for i := range(pt.Classes) {
+		if (pt.Classes[i] == hc) {
+			return i
+		}
+	}
+	t.Fatalf("can't find class %s", str)
+	return -1
+}
+
+func TestPreflopTable(t *testing.T) {
+	pt := NewPreflopTable(100, 1, 1)
+	for i := 0; i < NUM_HAND_CLASSES; i++ {
+		total := 0
+		for j := 0; j < NUM_HAND_CLASSES; j++ {
+			total += pt.Pairs[i][j]
+			if (math.Abs(pt.Equity[i][j] + pt.Equity[j][i] - 1.0) > 1e-9) {
+				t.Fatalf("expected equities to add up to 1 for %s and %s",
+					pt.Classes[i], pt.Classes[j])
+			}
+		}
+		/* Every combo can be dealt against any two of the other 50 cards. */
+		if (total != len(pt.Combos[i]) * 1225) {
+			t.Errorf("expected %s to be dealt against %d combos, got %d",
+				pt.Classes[i], len(pt.Combos[i]) * 1225, total)
+		}
+	}
+	aa := findClass(t, pt, "AA")
+	kk := findClass(t, pt, "KK")
+	aks := findClass(t, pt, "AKs")
+	if ((pt.Pairs[aa][kk] != 36) || (pt.Pairs[aa][aks] != 12)) {
+		t.Errorf("expected AA to be dealt against 36 KK and 12 AKs combos, " +
+			"got %d and %d", pt.Pairs[aa][kk], pt.Pairs[aa][aks])
+	}
+	if (math.Abs(pt.Equity[aa][kk] - 0.82) > 0.05) {
+		t.Errorf("expected AA to have about 82%% against KK, got %f",
+			pt.Equity[aa][kk])
+	}
+	/* The table doesn't depend on how many workers built it. */
+	pt3 := NewPreflopTable(100, 1, 3)
+	if (pt3.Equity != pt.Equity) {
+		t.Errorf("expected the same table with 1 or 3 workers")
+	}
+}
+
+func TestSolvePushFold(t *testing.T) {
+	pt := NewPreflopTable(100, 1, 1)
+	cfg := &PushFoldConfig { Stack: 20, SmallBlind: 1, BigBlind: 2,
+		Iterations: 500 }
+	res, err := SolvePushFold(cfg, pt)
+	if (err != nil) {
+		t.Fatalf("%s", err.Error())
+	}
+	/* With 10 big blinds, about 58% of hands are pushed, and 37% call. */
+	push := rangeShareOfHands(res.PushRange())
+	call := rangeShareOfHands(res.CallRange())
+	if ((math.Abs(push - 0.58) > 0.05) || (math.Abs(call - 0.37) > 0.05)) {
+		t.Errorf("expected to push about 58%% and call about 37%%, got " +
+			"%f and %f", push, call)
+	}
+	aa := findClass(t, pt, "AA")
+	seven2 := findClass(t, pt, "72o")
+	if ((res.Push[aa] < 0.5) || (res.Call[aa] < 0.5)) {
+		t.Errorf("expected AA to push and call")
+	}
+	if ((res.Push[seven2] >= 0.5) || (res.Call[seven2] >= 0.5)) {
+		t.Errorf("expected 72o to fold")
+	}
+
+	/* The shorter the stacks, the more hands are pushed. */
+	cfg.Stack = 4
+	res, err = SolvePushFold(cfg, pt)
+	if (err != nil) {
+		t.Fatalf("%s", err.Error())
+	}
+	if (rangeShareOfHands(res.PushRange()) < push + 0.15) {
+		t.Errorf("expected to push more with 2 big blinds than with 10, " +
+			"got %s", res.PushRange().ClassString())
+	}
+	cfg.Stack = 2
+	_, err = SolvePushFold(cfg, pt)
+	if (err == nil) {
+		t.Errorf("expected an error when the stacks can't cover the blind")
+	}
+}
@@ -0,0 +1,514 @@
+/*
+ * Copyright 2011 Colin Patrick McCabe
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, version 2.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package main
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+/* A Range is a set of hole cards that a player might hold.
+ *
+ * Ranges are written the way poker players usually write them, as a
+ * comma-separated list of:
+ *     AA, AKs, AKo, AK       a hand class (suited, offsuit, or both)
+ *     QQ+, ATs+              a hand class and everything above it
+ *     22-55, A2s-A5s         a run of hand classes
+ *     AsKs                   one particular combination of cards
+ *     random                 every possible combination
+ *     top15%                 the best 15% of starting hands. See TopRange.
+ * Ten may be written as either T or 10.
+ *
+ * Any piece may be followed by a weight, like AKs:0.5. This means that the
+ * player only holds those combos half of the time they could, as when they
+ * play a mixed strategy. A weighted combo counts as that fraction of a combo.
+ * Pieces without a weight have a weight of 1, and pieces with a weight of 0
+ * are left out. If a combo is given more than once, the last weight given
+ * wins.
+ *
+ * Ranges can be combined with operators, which must have spaces around them,
+ * like "top15% - 22-55". See ParseRange.
+ *
+ * Each combination of two cards is identified by a combo id. See comboId.
+ * The map holds the weight of every combo in the range.
+ */
+type Range struct {
+	combos map[int] float64
+}
+
+const NUM_CARDS = 52
+
+const NUM_COMBOS = NUM_CARDS * (NUM_CARDS - 1) / 2
+
+func cardId(c *Card) int {
+	return (c.val - 2) * 4 + c.suit
+}
+
+func cardFromId(id int) *Card {
+	return &Card { id / 4 + 2, id % 4 }
+}
+
+func comboId(a *Card, b *Card) int {
+	ia := cardId(a)
+	ib := cardId(b)
+	if (ia > ib) {
+		ia, ib = ib, ia
+	}
+	return ia * NUM_CARDS + ib
+}
+
+/* Returns the cards in a combo, highest first. */
+func comboCards(id int) CardSlice {
+	return CardSlice { cardFromId(id % NUM_CARDS), cardFromId(id / NUM_CARDS) }
+}
+
+func NewRange() *Range {
+	return &Range { make(map[int] float64) }
+}
+
+/* Returns a range holding every possible pair of hole cards. */
+func RandomRange() *Range {
+	r := NewRange()
+	for a := 0; a < NUM_CARDS; a++ {
+		for b := a + 1; b < NUM_CARDS; b++ {
+			r.combos[a * NUM_CARDS + b] = 1.0
+		}
+	}
+	return r
+}
+
+func (r *Range) Add(a *Card, b *Card) {
+	r.combos[comboId(a, b)] = 1.0
+}
+
+/* Set the weight of a combo. A weight of 0 takes the combo out of the
+ * range.
+ */
+func (r *Range) AddWeighted(a *Card, b *Card, weight float64) {
+	r.setWeight(comboId(a, b), weight)
+}
+
+func (r *Range) setWeight(id int, weight float64) {
+	if (weight == 0) {
+		delete(r.combos, id)
+	} else {
+		r.combos[id] = weight
+	}
+}
+
+func (r *Range) Contains(a *Card, b *Card) bool {
+	return r.combos[comboId(a, b)] > 0
+}
+
+/* Returns the weight of a combo, or 0 if it isn't in the range. */
+func (r *Range) Weight(combo CardSlice) float64 {
+	return r.combos[comboId(combo[0], combo[1])]
+}
+
+/* Returns the number of combos in the range, ignoring weights. */
+func (r *Range) Len() int {
+	return len(r.combos)
+}
+
+/* Returns the number of combos in the range, counting each one by its
+ * weight.
+ */
+func (r *Range) WeightedLen() float64 {
+	ret := 0.0
+	for _, w := range(r.combos) {
+		ret += w
+	}
+	return ret
+}
+
+func (r *Range) ids() []int {
+	ids := make([]int, 0, len(r.combos))
+	for id := range(r.combos) {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	return ids
+}
+
+/* Returns every combo in the range, in a consistent order. */
+func (r *Range) Combos() []CardSlice {
+	ids := r.ids()
+	ret := make([]CardSlice, len(ids))
+	for i := range(ids) {
+		ret[i] = comboCards(ids[i])
+	}
+	return ret
+}
+
+/* Returns the combos in the range which don't use any of the dead cards.
+ * Use Weight to find out how much each one counts for.
+ */
+func (r *Range) Live(dead CardSlice) []CardSlice {
+	var deadBits uint64
+	for i := range(dead) {
+		deadBits |= 1 << uint(cardId(dead[i]))
+	}
+	var ret []CardSlice
+	ids := r.ids()
+	for i := range(ids) {
+		a := ids[i] / NUM_CARDS
+		b := ids[i] % NUM_CARDS
+		if ((deadBits & ((1 << uint(a)) | (1 << uint(b)))) == 0) {
+			ret = append(ret, comboCards(ids[i]))
+		}
+	}
+	return ret
+}
+
+func weightToStr(weight float64) string {
+	return strconv.FormatFloat(weight, 'g', -1, 64)
+}
+
+/* Format a number of combos, which may be fractional once weights are
+ * involved.
+ */
+func comboCountToStr(n float64) string {
+	if (n == math.Trunc(n)) {
+		return fmt.Sprintf("%.0f", n)
+	}
+	return fmt.Sprintf("%.2f", n)
+}
+
+func (r *Range) String() string {
+	combos := r.Combos()
+	strs := make([]string, len(combos))
+	for i := range(combos) {
+		strs[i] = combos[i][0].ShortString() + combos[i][1].ShortString()
+		w := r.Weight(combos[i])
+		if (w != 1.0) {
+			strs[i] += ":" + weightToStr(w)
+		}
+	}
+	return strings.Join(strs, ",")
+}
+
+func rangeValFromChar(c byte) int {
+	switch {
+	case c >= '2' && c <= '9':
+		return int(c - '0')
+	case c == 'T' || c == 't':
+		return 10
+	case c == 'J' || c == 'j':
+		return JACK_VAL
+	case c == 'Q' || c == 'q':
+		return QUEEN_VAL
+	case c == 'K' || c == 'k':
+		return KING_VAL
+	case c == 'A' || c == 'a':
+		return ACE_VAL
+	}
+	return -1
+}
+
+func rangeSuitFromChar(c byte) int {
+	switch (c) {
+	case 'c', 'C':
+		return CLUBS
+	case 'd', 'D':
+		return DIAMONDS
+	case 'h', 'H':
+		return HEARTS
+	case 's', 'S':
+		return SPADES
+	}
+	return -1
+}
+
+const (
+	CLASS_ANY = iota
+	CLASS_SUITED
+	CLASS_OFFSUIT
+)
+
+/* A hand class, like AKs. For pairs, hi == lo and suitedness is
+ * CLASS_ANY.
+ */
+type handClass struct {
+	hi int
+	lo int
+	suited int
+}
+
+func (hc handClass) String() string {
+	ret := cardValToRangeStr(hc.hi) + cardValToRangeStr(hc.lo)
+	switch (hc.suited) {
+	case CLASS_SUITED:
+		ret += "s"
+	case CLASS_OFFSUIT:
+		ret += "o"
+	}
+	return ret
+}
+
+func cardValToRangeStr(v int) string {
+	if (v == 10) {
+		return "T"
+	}
+	return cardValToStr(v)
+}
+
+func parseHandClass(str string) (handClass, error) {
+	var hc handClass
+	if ((len(str) < 2) || (len(str) > 3)) {
+		return hc, fmt.Errorf("can't understand hand class '%s'", str)
+	}
+	hc.hi = rangeValFromChar(str[0])
+	hc.lo = rangeValFromChar(str[1])
+	if ((hc.hi == -1) || (hc.lo == -1)) {
+		return hc, fmt.Errorf("can't understand hand class '%s'", str)
+	}
+	if (hc.hi < hc.lo) {
+		hc.hi, hc.lo = hc.lo, hc.hi
+	}
+	if (len(str) == 3) {
+		switch (str[2]) {
+		case 's', 'S':
+			hc.suited = CLASS_SUITED
+		case 'o', 'O':
+			hc.suited = CLASS_OFFSUIT
+		default:
+			return hc, fmt.Errorf("can't understand hand class '%s'", str)
+		}
+		if (hc.hi == hc.lo) {
+			return hc, fmt.Errorf("a pair can't be suited or offsuit: '%s'",
+				str)
+		}
+	}
+	return hc, nil
+}
+
+/* Returns the ids of every combo in a hand class. */
+func (hc handClass) comboIds() []int {
+	var ret []int
+	for s1 := DIAMONDS; s1 <= SPADES; s1++ {
+		for s2 := DIAMONDS; s2 <= SPADES; s2++ {
+			if ((hc.hi == hc.lo) && (s1 >= s2)) {
+				continue
+			}
+			if ((hc.suited == CLASS_SUITED) && (s1 != s2)) {
+				continue
+			}
+			if ((hc.suited == CLASS_OFFSUIT) && (s1 == s2)) {
+				continue
+			}
+			ret = append(ret, comboId(&Card { hc.hi, s1 }, &Card { hc.lo, s2 }))
+		}
+	}
+	return ret
+}
+
+func (r *Range) addHandClass(hc handClass, weight float64) {
+	ids := hc.comboIds()
+	for i := range(ids) {
+		r.setWeight(ids[i], weight)
+	}
+}
+
+/* Parse one particular combination of two cards, like AsKs. */
+func parseCombo(str string) (CardSlice, bool) {
+	if (len(str) != 4) {
+		return nil, false
+	}
+	ret := make(CardSlice, 2)
+	for i := range(ret) {
+		val := rangeValFromChar(str[2 * i])
+		suit := rangeSuitFromChar(str[2 * i + 1])
+		if ((val == -1) || (suit == -1)) {
+			return nil, false
+		}
+		ret[i] = &Card { val, suit }
+	}
+	if (ret[0].Compare(ret[1]) == 0) {
+		return nil, false
+	}
+	return ret, true
+}
+
+func (r *Range) addPlus(hc handClass, weight float64) {
+	if (hc.hi == hc.lo) {
+		for v := hc.hi; v <= ACE_VAL; v++ {
+			r.addHandClass(handClass { v, v, CLASS_ANY }, weight)
+		}
+		return
+	}
+	for v := hc.lo; v < hc.hi; v++ {
+		r.addHandClass(handClass { hc.hi, v, hc.suited }, weight)
+	}
+}
+
+func (r *Range) addDash(str string, from handClass, to handClass,
+		weight float64) error {
+	if ((from.hi == from.lo) && (to.hi == to.lo)) {
+		if (from.hi > to.hi) {
+			from, to = to, from
+		}
+		for v := from.hi; v <= to.hi; v++ {
+			r.addHandClass(handClass { v, v, CLASS_ANY }, weight)
+		}
+		return nil
+	}
+	if ((from.hi != to.hi) || (from.suited != to.suited) ||
+			(from.hi == from.lo) || (to.hi == to.lo)) {
+		return fmt.Errorf("can't understand '%s'. Both ends of a run must " +
+			"be pairs, or share the same high card.", str)
+	}
+	if (from.lo > to.lo) {
+		from, to = to, from
+	}
+	for v := from.lo; v <= to.lo; v++ {
+		r.addHandClass(handClass { from.hi, v, from.suited }, weight)
+	}
+	return nil
+}
+
+/* Split the weight off the end of a piece of a range, if there is one. */
+func parseWeight(tok string) (string, float64, error) {
+	colon := strings.LastIndex(tok, ":")
+	if (colon == -1) {
+		return tok, 1.0, nil
+	}
+	wStr := strings.TrimSpace(tok[colon+1:])
+	weight, err := strconv.ParseFloat(wStr, 64)
+	if ((err != nil) || (weight < 0) || (weight > 1)) {
+		return "", 0, fmt.Errorf("can't understand the weight '%s' in '%s'. " +
+			"Weights must be between 0 and 1.", wStr, tok)
+	}
+	return strings.TrimSpace(tok[:colon]), weight, nil
+}
+
+/* Add everything described by one comma-separated piece of a range. */
+func (r *Range) addToken(tok string) error {
+	tok, weight, err := parseWeight(tok)
+	if (err != nil) {
+		return err
+	}
+	if (strings.HasPrefix(strings.ToLower(tok), "top")) {
+		return r.addTop(tok, weight)
+	}
+	tok = strings.Replace(tok, "10", "T", -1)
+	switch (strings.ToLower(tok)) {
+	case "random", "any", "all":
+		for id := range(RandomRange().combos) {
+			r.setWeight(id, weight)
+		}
+		return nil
+	}
+	combo, ok := parseCombo(tok)
+	if (ok) {
+		r.AddWeighted(combo[0], combo[1], weight)
+		return nil
+	}
+	if (strings.HasSuffix(tok, "+")) {
+		hc, err := parseHandClass(tok[:len(tok)-1])
+		if (err != nil) {
+			return err
+		}
+		r.addPlus(hc, weight)
+		return nil
+	}
+	dash := strings.Index(tok, "-")
+	if (dash != -1) {
+		from, err := parseHandClass(tok[:dash])
+		if (err != nil) {
+			return err
+		}
+		to, err := parseHandClass(tok[dash+1:])
+		if (err != nil) {
+			return err
+		}
+		return r.addDash(tok, from, to, weight)
+	}
+	hc, err := parseHandClass(tok)
+	if (err != nil) {
+		return err
+	}
+	r.addHandClass(hc, weight)
+	return nil
+}
+
+/* Parse a comma-separated list of range pieces, without any operators. */
+func parseRangeTerm(str string) (*Range, error) {
+	r := NewRange()
+	toks := strings.Split(str, ",")
+	for i := range(toks) {
+		tok := strings.TrimSpace(toks[i])
+		if (tok == "") {
+			continue
+		}
+		err := r.addToken(tok)
+		if (err != nil) {
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+/* Parse a range. Ranges may be combined with these operators, which are
+ * applied from left to right:
+ *     A + B     every combo in either range
+ *     A & B     only the combos in both ranges
+ *     A - B     the combos in A which aren't in B
+ * The operators must have spaces on both sides, so that they can't be
+ * confused with runs like 22-55, or with QQ+.
+ */
+func ParseRange(str string) (*Range, error) {
+	fields := strings.Fields(str)
+	var r *Range
+	op := "+"
+	term := ""
+	for i := 0; i <= len(fields); i++ {
+		if ((i < len(fields)) && !isRangeOp(fields[i])) {
+			term += " " + fields[i]
+			continue
+		}
+		if (strings.TrimSpace(term) == "") {
+			if (i < len(fields)) {
+				return nil, fmt.Errorf("expected a range before '%s' in " +
+					"'%s'", fields[i], str)
+			} else if (r != nil) {
+				return nil, fmt.Errorf("expected a range after '%s' in " +
+					"'%s'", op, str)
+			}
+			break
+		}
+		rhs, err := parseRangeTerm(term)
+		if (err != nil) {
+			return nil, err
+		}
+		if (r == nil) {
+			r = rhs
+		} else {
+			r = r.apply(op, rhs)
+		}
+		if (i < len(fields)) {
+			op = fields[i]
+		}
+		term = ""
+	}
+	if ((r == nil) || (r.Len() == 0)) {
+		return nil, fmt.Errorf("the range '%s' is empty.", str)
+	}
+	return r, nil
+}
@@ -0,0 +1,197 @@
+/*
+ * Copyright 2011 Colin Patrick McCabe
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, version 2.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package main
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+/* Every starting hand, best first. TopRange takes hands from the front of
+ * this list.
+ */
+const PREFLOP_ORDER =
+	"AA KK QQ JJ TT 99 88 AKs 77 AQs AKo AJs ATs AQo AJo KQs 66 A9s ATo " +
+	"KJs A8s KTs KQo A7s A9o KJo 55 QJs K9s A6s A5s A8o KTo QTs A4s A7o " +
+	"K8s A3s QJo K9o A6o A5o Q9s JTs K7s A2s QTo 44 A4o K6s Q8s K8o A3o " +
+	"K5s J9s Q9o JTo K7o K4s A2o Q7s K6o T9s K3s J8s 33 Q8o Q6s K5o J9o " +
+	"K2s Q5s K4o J7s T8s Q4s Q7o T9o J8o K3o Q3s Q6o 98s T7s J6s K2o 22 " +
+	"Q2s Q5o J5s T8o J7o Q4o 97s J4s T6s J3s Q3o 98o 87s T7o J6o J2s 96s " +
+	"Q2o T5s J5o T4s 97o 86s J4o T6o 95s T3s 76s J3o 87o T2s 96o 85s J2o " +
+	"T5o 94s 75s T4o 86o 93s 65s 95o 84s T3o 92s 76o 74s T2o 85o 54s 64s " +
+	"83s 94o 75o 82s 73s 93o 65o 53s 63s 84o 92o 43s 74o 72s 54o 64o 52s " +
+	"62s 83o 82o 42s 73o 53o 63o 32s 43o 72o 52o 62o 42o 32o"
+
+var preflopOrder []handClass
+
+/* Returns the hand classes in PREFLOP_ORDER. */
+func getPreflopOrder() []handClass {
+	if (preflopOrder != nil) {
+		return preflopOrder
+	}
+	toks := strings.Fields(PREFLOP_ORDER)
+	for i := range(toks) {
+		hc, err := parseHandClass(toks[i])
+		if (err != nil) {
+			panic(err)
+		}
+		preflopOrder = append(preflopOrder, hc)
+	}
+	return preflopOrder
+}
+
+func (hc handClass) numCombos() int {
+	switch {
+	case hc.hi == hc.lo:
+		return 6
+	case hc.suited == CLASS_SUITED:
+		return 4
+	}
+	return 12
+}
+
+/* Returns the best pct percent of starting hands, according to
+ * PREFLOP_ORDER. Whole hand classes are taken, so we stop at whichever class
+ * brings the number of combos closest to pct percent of them all.
+ */
+func TopRange(pct float64) (*Range, error) {
+	if ((pct <= 0) || (pct > 100)) {
+		return nil, fmt.Errorf("the top percentage of hands must be more " +
+			"than 0 and no more than 100, not %s.", weightToStr(pct))
+	}
+	target := pct * NUM_COMBOS / 100.0
+	r := NewRange()
+	n := 0
+	order := getPreflopOrder()
+	for i := range(order) {
+		next := n + order[i].numCombos()
+		over := math.Abs(float64(next) - target)
+		if ((n > 0) && (over >= math.Abs(float64(n) - target))) {
+			break
+		}
+		r.addHandClass(order[i], 1.0)
+		n = next
+	}
+	return r, nil
+}
+
+/* Add a piece of a range like top15%. */
+func (r *Range) addTop(tok string, weight float64) error {
+	pctStr := strings.TrimSpace(tok[len("top"):])
+	if (!strings.HasSuffix(pctStr, "%")) {
+		return fmt.Errorf("can't understand '%s'. Expected something like " +
+			"top15%%.", tok)
+	}
+	pct, err := strconv.ParseFloat(pctStr[:len(pctStr)-1], 64)
+	if (err != nil) {
+		return fmt.Errorf("can't understand '%s'. Expected something like " +
+			"top15%%.", tok)
+	}
+	top, err := TopRange(pct)
+	if (err != nil) {
+		return err
+	}
+	for id := range(top.combos) {
+		r.setWeight(id, weight)
+	}
+	return nil
+}
+
+func (r *Range) Clone() *Range {
+	ret := NewRange()
+	for id, w := range(r.combos) {
+		ret.combos[id] = w
+	}
+	return ret
+}
+
+/* Returns every combo in either range. A combo in both gets the bigger of
+ * its two weights.
+ */
+func (r *Range) Union(rhs *Range) *Range {
+	ret := r.Clone()
+	for id, w := range(rhs.combos) {
+		if (w > ret.combos[id]) {
+			ret.combos[id] = w
+		}
+	}
+	return ret
+}
+
+/* Returns the combos in both ranges, with the smaller of their two
+ * weights.
+ */
+func (r *Range) Intersect(rhs *Range) *Range {
+	ret := NewRange()
+	for id, w := range(r.combos) {
+		w2, ok := rhs.combos[id]
+		if (!ok) {
+			continue
+		}
+		if (w2 < w) {
+			w = w2
+		}
+		ret.combos[id] = w
+	}
+	return ret
+}
+
+/* Returns the combos in r, less the combos in rhs. Weights are subtracted,
+ * so taking AKs:0.5 away from AKs leaves AKs:0.5.
+ */
+func (r *Range) Subtract(rhs *Range) *Range {
+	ret := NewRange()
+	for id, w := range(r.combos) {
+		w -= rhs.combos[id]
+		if (w > 0) {
+			ret.combos[id] = w
+		}
+	}
+	return ret
+}
+
+/* Returns the combos in r which don't use any of the dead cards. */
+func (r *Range) Without(dead CardSlice) *Range {
+	ret := NewRange()
+	live := r.Live(dead)
+	for i := range(live) {
+		ret.AddWeighted(live[i][0], live[i][1], r.Weight(live[i]))
+	}
+	return ret
+}
+
+func isRangeOp(str string) bool {
+	switch (str) {
+	case "+", "&", "-":
+		return true
+	}
+	return false
+}
+
+func (r *Range) apply(op string, rhs *Range) *Range {
+	switch (op) {
+	case "+":
+		return r.Union(rhs)
+	case "&":
+		return r.Intersect(rhs)
+	case "-":
+		return r.Subtract(rhs)
+	}
+	panic(fmt.Sprintf("unknown range operator '%s'", op))
+}
@@ -0,0 +1,95 @@
+/*
+ * Copyright 2011 Colin Patrick McCabe
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, version 2.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package main
+
+import (
+	"testing"
+)
+
+func TestPreflopOrder(t *testing.T) {
+	order := getPreflopOrder()
+	if (len(order) != 169) {
+		t.Fatalf("expected 169 hand classes, got %d", len(order))
+	}
+	seen := make(map[string] bool)
+	n := 0
+	for i := range(order) {
+		seen[order[i].String()] = true
+		n += order[i].numCombos()
+	}
+	if ((len(seen) != 169) || (n != NUM_COMBOS)) {
+		t.Errorf("expected 169 different hand classes covering %d combos, " +
+			"got %d covering %d", NUM_COMBOS, len(seen), n)
+	}
+	if (order[0].String() != "AA") {
+		t.Errorf("expected AA to be the best hand, got %s", order[0])
+	}
+	if (order[len(order) - 1].String() != "32o") {
+		t.Errorf("expected 32o to be the worst hand, got %s",
+			order[len(order) - 1])
+	}
+}
+
+func TestTopRange(t *testing.T) {
+	expectRangeLen(t, "top100%", NUM_COMBOS)
+	// The very best hand is always taken, even if it is more than we
+	// asked for.
+	expectRangeLen(t, "top0.1%", 6)
+	r, _ := ParseRange("top15%")
+	if ((r.Len() < 190) || (r.Len() > 210)) {
+		t.Errorf("expected about 199 combos in the top 15%%, got %d", r.Len())
+	}
+	if (!r.Contains(&Card { ACE_VAL, SPADES }, &Card { KING_VAL, SPADES })) {
+		t.Errorf("expected AKs to be in the top 15%%")
+	}
+	bad := []string { "top0%", "top101%", "top15", "topx%" }
+	for i := range(bad) {
+		_, err := ParseRange(bad[i])
+		if (err == nil) {
+			t.Errorf("expected an error parsing '%s'", bad[i])
+		}
+	}
+}
+
+func TestRangeAlgebra(t *testing.T) {
+	expectRangeLen(t, "QQ+ + AK", 34)
+	expectRangeLen(t, "QQ+, AK - KK", 28)
+	expectRangeLen(t, "random - 22-55", NUM_COMBOS - 24)
+	expectRangeLen(t, "TT+ & QQ-22", 18)
+	expectRangeLen(t, "AA - AsAh - AdAc", 4)
+
+	r, _ := ParseRange("AKs - AKs:0.25 + QQ:0.5 & AKs, QQ")
+	if (r.WeightedLen() != 6.0) {
+		t.Errorf("expected AKs at 0.75 and QQ at 0.5, got %s", r)
+	}
+
+	dead, _ := StrToCards("AS KD")
+	r, _ = ParseRange("AA, KK")
+	r = r.Without(dead)
+	if (r.Len() != 6) {
+		t.Errorf("expected 6 combos left once AS and KD are out, got %d",
+			r.Len())
+	}
+
+	bad := []string { "AA -", "- AA", "AA + + KK", "AA - AA" }
+	for i := range(bad) {
+		_, err := ParseRange(bad[i])
+		if (err == nil) {
+			t.Errorf("expected an error parsing '%s'", bad[i])
+		}
+	}
+}
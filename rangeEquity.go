@@ -0,0 +1,392 @@
+/*
+ * Copyright 2011 Colin Patrick McCabe
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, version 2.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"math/rand"
+	"os"
+	"sort"
+)
+
+/* If we fail to deal non-overlapping combos from every range this many times
+ * in a row, we give up. The ranges are probably almost entirely blocking each
+ * other.
+ */
+const MAX_DEAL_ATTEMPTS = 100000
+
+/* How one combo in the first range does against all the other ranges.
+ * Blocks is the number of combos in the other ranges which share a card with
+ * this one, and so can't be held at the same time. Each blocked combo counts
+ * for its weight.
+ */
+type ComboEquity struct {
+	Cards CardSlice
+	Eq Equity
+	Blocks float64
+}
+
+/* The result of playing several ranges against each other. Equities holds
+ * one Equity per range, in order. Combos describes every live combo in the
+ * first range.
+ */
+type RangeEquityResult struct {
+	Equities []*Equity
+	Combos []*ComboEquity
+	Exact bool
+}
+
+/* Everything we need while playing ranges against each other. Combos are
+ * kept along with a bitmap of the cards in them, to make it quick to tell
+ * whether two combos can be held at once, and their weights. cumWeights
+ * holds the running total of the weights, which we use to pick combos at
+ * random.
+ */
+type rangeMatchup struct {
+	board CardSlice
+	live [][]CardSlice
+	liveBits [][]uint64
+	weights [][]float64
+	cumWeights [][]float64
+	boardBits uint64
+	res *RangeEquityResult
+	ranks []int
+	buf [SPREAD_MAX]*Card
+}
+
+func cardsBits(cards CardSlice) uint64 {
+	var ret uint64
+	for i := range(cards) {
+		ret |= 1 << uint(cardId(cards[i]))
+	}
+	return ret
+}
+
+/* Record one showdown between the players holding the given combos. */
+func (m *rangeMatchup) showdown(tuple []int, full CardSlice, weight float64) {
+	best := -1
+	numBest := 0
+	for i := range(tuple) {
+		m.ranks[i] = holeRank(m.live[i][tuple[i]], full, m.buf[:0])
+		if (m.ranks[i] > best) {
+			best = m.ranks[i]
+			numBest = 1
+		} else if (m.ranks[i] == best) {
+			numBest++
+		}
+	}
+	for i := range(tuple) {
+		eq := m.res.Equities[i]
+		var comboEq *Equity
+		if (i == 0) {
+			comboEq = &m.res.Combos[tuple[0]].Eq
+		}
+		if (m.ranks[i] < best) {
+			eq.Loss += weight
+			if (comboEq != nil) {
+				comboEq.Loss += weight
+			}
+			continue
+		}
+		share := weight / float64(numBest)
+		if (numBest == 1) {
+			eq.Win += weight
+		} else {
+			eq.Tie += weight
+		}
+		eq.Share += share
+		if (comboEq != nil) {
+			if (numBest == 1) {
+				comboEq.Win += weight
+			} else {
+				comboEq.Tie += weight
+			}
+			comboEq.Share += share
+		}
+	}
+}
+
+/* Returns the cards that aren't on the board or used by any combo in the
+ * tuple.
+ */
+func (m *rangeMatchup) leftInDeck(deck *CardBag, used uint64) CardSlice {
+	var ret CardSlice
+	for i := 0; i < deck.Len(); i++ {
+		c := deck.Get(uint(i))
+		if ((used & (1 << uint(cardId(c)))) == 0) {
+			ret = append(ret, c)
+		}
+	}
+	return ret
+}
+
+func (m *rangeMatchup) exact(deck *CardBag) {
+	tuple := make([]int, len(m.live))
+	full := make(CardSlice, len(m.board), BOARD_MAX)
+	copy(full, m.board)
+	need := BOARD_MAX - len(m.board)
+	// A combination of combos counts for the product of their weights.
+	var deal func(r int, used uint64, weight float64)
+	deal = func(r int, used uint64, weight float64) {
+		if (r == len(m.live)) {
+			left := &CardBag { m.leftInDeck(deck, used) }
+			left.ForEachSubset(need, func(runout CardSlice) {
+				m.showdown(tuple, append(full, runout...), weight)
+			})
+			return
+		}
+		for k := range(m.live[r]) {
+			if ((m.liveBits[r][k] & used) != 0) {
+				continue
+			}
+			tuple[r] = k
+			deal(r + 1, used | m.liveBits[r][k], weight * m.weights[r][k])
+		}
+	}
+	deal(0, m.boardBits, 1.0)
+}
+
+/* Pick a combo from range r at random. Combos with more weight are picked
+ * more often.
+ */
+func (m *rangeMatchup) pick(rng *rand.Rand, r int) int {
+	cum := m.cumWeights[r]
+	return sort.SearchFloat64s(cum, rng.Float64() * cum[len(cum) - 1])
+}
+
+func (m *rangeMatchup) sample(deck *CardBag, samples int,
+		seed int64) error {
+	rng := rand.New(rand.NewSource(seed))
+	tuple := make([]int, len(m.live))
+	full := make(CardSlice, len(m.board), BOARD_MAX)
+	copy(full, m.board)
+	need := BOARD_MAX - len(m.board)
+	for n := 0; n < samples; n++ {
+		var used uint64
+		for attempts := 0;; attempts++ {
+			if (attempts >= MAX_DEAL_ATTEMPTS) {
+				return fmt.Errorf("unable to deal non-overlapping combos " +
+					"from every range.")
+			}
+			used = m.boardBits
+			ok := true
+			for r := range(m.live) {
+				tuple[r] = m.pick(rng, r)
+				if ((m.liveBits[r][tuple[r]] & used) != 0) {
+					ok = false
+					break
+				}
+				used |= m.liveBits[r][tuple[r]]
+			}
+			if (ok) {
+				break
+			}
+		}
+		left := m.leftInDeck(deck, used)
+		for i := 0; i < need; i++ {
+			j := i + rng.Intn(len(left) - i)
+			left[i], left[j] = left[j], left[i]
+		}
+		m.showdown(tuple, append(full, left[:need]...), 1.0)
+	}
+	return nil
+}
+
+/* Play the ranges against each other on the board. Every combination of
+ * combos that can be held at the same time is considered, in proportion to
+ * the weights of the combos. This takes card removal between the ranges into
+ * account.
+ *
+ * If samples is 0, and there aren't too many showdowns, every showdown is
+ * enumerated. Otherwise we deal random showdowns.
+ */
+func CalcRangeEquity(ranges []*Range, board CardSlice, samples int,
+		seed int64, deck *CardBag) (*RangeEquityResult, error) {
+	if (len(ranges) < 2) {
+		return nil, fmt.Errorf("at least two ranges are needed.")
+	}
+	err := checkBoardLength(len(board))
+	if (err != nil) {
+		return nil, err
+	}
+	dupe := board.HasDuplicates()
+	if (dupe != nil) {
+		return nil, fmt.Errorf("The card %s appears more than once on the " +
+			"board! That is not possible.", dupe)
+	}
+	m := new(rangeMatchup)
+	m.board = board
+	m.boardBits = cardsBits(board)
+	m.ranks = make([]int, len(ranges))
+	m.res = new(RangeEquityResult)
+	showdowns := choose(NUM_CARDS - len(board) - HOLE_SZ * len(ranges),
+		BOARD_MAX - len(board))
+	for r := range(ranges) {
+		live := ranges[r].Live(board)
+		if (len(live) == 0) {
+			return nil, fmt.Errorf("range %d has no combos left once the " +
+				"board is taken into account.", r + 1)
+		}
+		bits := make([]uint64, len(live))
+		weights := make([]float64, len(live))
+		cum := make([]float64, len(live))
+		total := 0.0
+		for k := range(live) {
+			bits[k] = cardsBits(live[k])
+			weights[k] = ranges[r].Weight(live[k])
+			total += weights[k]
+			cum[k] = total
+		}
+		m.live = append(m.live, live)
+		m.liveBits = append(m.liveBits, bits)
+		m.weights = append(m.weights, weights)
+		m.cumWeights = append(m.cumWeights, cum)
+		m.res.Equities = append(m.res.Equities, new(Equity))
+		showdowns *= float64(len(live))
+	}
+	for k := range(m.live[0]) {
+		ce := &ComboEquity { Cards: m.live[0][k] }
+		for r := 1; r < len(m.live); r++ {
+			for j := range(m.live[r]) {
+				if ((m.liveBits[0][k] & m.liveBits[r][j]) != 0) {
+					ce.Blocks += m.weights[r][j]
+				}
+			}
+		}
+		m.res.Combos = append(m.res.Combos, ce)
+	}
+
+	if ((samples == 0) && (showdowns <= MAX_EXACT_SHOWDOWNS)) {
+		m.res.Exact = true
+		m.exact(deck)
+	} else {
+		if (samples == 0) {
+			samples = DEFAULT_SAMPLES
+		}
+		err = m.sample(deck, samples, seed)
+		if (err != nil) {
+			return nil, err
+		}
+	}
+	for r := range(m.res.Equities) {
+		m.res.Equities[r].Exact = m.res.Exact
+		if (m.res.Equities[r].Total() == 0) {
+			return nil, fmt.Errorf("the ranges block each other completely.")
+		}
+	}
+	return m.res, nil
+}
+
+type comboEquitySlice []*ComboEquity
+
+func (cs comboEquitySlice) Len() int {
+	return len(cs)
+}
+
+// Combos we never got to see go last.
+func (cs comboEquitySlice) Less(i, j int) bool {
+	if (cs[i].Eq.Total() == 0) {
+		return false
+	} else if (cs[j].Eq.Total() == 0) {
+		return true
+	}
+	return cs[i].Eq.Value() > cs[j].Eq.Value()
+}
+
+func (cs comboEquitySlice) Swap(i, j int) {
+	cs[i], cs[j] = cs[j], cs[i]
+}
+
+func (res *RangeEquityResult) String(rangeStrs []string) string {
+	ret := ""
+	for r := range(res.Equities) {
+		ret += fmt.Sprintf("range %d (%s): %s\n", r + 1, rangeStrs[r],
+			res.Equities[r].String())
+	}
+	combos := make(comboEquitySlice, len(res.Combos))
+	copy(combos, res.Combos)
+	sort.Stable(combos)
+	ret += fmt.Sprintf("combos in range 1 (%s):\n", equityMethodStr(res.Equities[0]))
+	ret += fmt.Sprintf("%-6s %8s %8s %8s %6s\n", "combo", "equity", "win",
+		"tie", "blocks")
+	for i := range(combos) {
+		ce := combos[i]
+		t := ce.Eq.Total()
+		if (t == 0) {
+			ret += fmt.Sprintf("%-6s %8s %8s %8s %6s\n",
+				ce.Cards[0].ShortString() + ce.Cards[1].ShortString(),
+				"-", "-", "-", comboCountToStr(ce.Blocks))
+			continue
+		}
+		ret += fmt.Sprintf("%-6s %7.2f%% %7.2f%% %7.2f%% %6s\n",
+			ce.Cards[0].ShortString() + ce.Cards[1].ShortString(),
+			ce.Eq.Value() * 100.0, ce.Eq.Win * 100.0 / t,
+			ce.Eq.Tie * 100.0 / t, comboCountToStr(ce.Blocks))
+	}
+	return ret
+}
+
+func rangesUsage(fs *flag.FlagSet) func() {
+	return func() {
+		fmt.Fprintf(os.Stderr,
+`%s ranges: play two or more ranges against each other.
+
+Usage:
+%s ranges [options] [range 1] [range 2] ...
+
+Each range is written like "QQ+, AKs, AQs-ATs, KsQs". The equity of every
+range is shown, followed by the equity of each combo in the first range
+against the others, and how many of their combos it blocks.
+
+Options:
+`, os.Args[0], os.Args[0])
+		fs.PrintDefaults()
+	}
+}
+
+func rangesMain(args []string) {
+	fs := flag.NewFlagSet("ranges", flag.ExitOnError)
+	fs.Usage = rangesUsage(fs)
+	var boardStr = fs.String("b", "", "the board")
+	var samples = fs.Int("m", 0, "number of Monte Carlo samples " +
+		"(0 means enumerate every showdown if that is feasible)")
+	var seed = fs.Int64("s", 1, "Monte Carlo random seed")
+	fs.Parse(args)
+
+	board, err := ParseCards(*boardStr, "the board")
+	if (err != nil) {
+		die(err)
+	}
+	if (fs.NArg() < 2) {
+		fs.Usage()
+		os.Exit(1)
+	}
+	ranges := make([]*Range, fs.NArg())
+	for i := range(ranges) {
+		ranges[i], err = LoadRange(fs.Arg(i))
+		if (err != nil) {
+			die(err)
+		}
+	}
+	res, err := CalcRangeEquity(ranges, board, *samples, *seed,
+		Make52CardBag())
+	if (err != nil) {
+		die(err)
+	}
+	fmt.Printf("%s", res.String(fs.Args()))
+}
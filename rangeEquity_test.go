@@ -0,0 +1,139 @@
+/*
+ * Copyright 2011 Colin Patrick McCabe
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, version 2.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func parseRanges(t *testing.T, strs ...string) []*Range {
+	ret := make([]*Range, len(strs))
+	for i := range(strs) {
+		var err error
+		ret[i], err = ParseRange(strs[i])
+		if (err != nil) {
+			t.Fatalf("unexpected error parsing '%s': %s", strs[i], err.Error())
+		}
+	}
+	return ret
+}
+
+func TestRangeEquity1(t *testing.T) {
+	deck := Make52CardBag()
+	board, _ := ParseCards("2C 7D 9H JS 3D", "the board")
+
+	// Each AA combo can face all 6 KK combos, and wins every time. Each KQs
+	// combo blocks 3 of the KK combos, and loses to the other 3.
+	res, err := CalcRangeEquity(parseRanges(t, "AA, KQs", "KK"), board, 0, 1,
+		deck)
+	if (err != nil) {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	if ((!res.Exact) || (res.Equities[0].Total() != 48) ||
+			(math.Abs(res.Equities[0].Value() - 0.75) > 1e-9) ||
+			(math.Abs(res.Equities[1].Value() - 0.25) > 1e-9)) {
+		t.Errorf("expected exact equities of 75%% and 25%% over 48 " +
+			"showdowns, got %s and %s", res.Equities[0], res.Equities[1])
+	}
+	if (len(res.Combos) != 10) {
+		t.Fatalf("expected 10 combos in the first range, got %d",
+			len(res.Combos))
+	}
+	for i := range(res.Combos) {
+		ce := res.Combos[i]
+		expectBlocks, expectEq := 0.0, 1.0
+		if (ce.Cards[0].val == KING_VAL) {
+			expectBlocks, expectEq = 3.0, 0.0
+		}
+		if ((ce.Blocks != expectBlocks) || (ce.Eq.Value() != expectEq)) {
+			t.Errorf("expected %s to block %f combos with equity %f, " +
+				"got %f and %s", ce.Cards, expectBlocks, expectEq,
+				ce.Blocks, &ce.Eq)
+		}
+	}
+}
+
+func TestWeightedRangeEquity(t *testing.T) {
+	deck := Make52CardBag()
+	board, _ := ParseCards("2C 7D 9H JS 3D", "the board")
+
+	// Each AA combo wins against every KK combo, with a weight of
+	// 0.5 * 0.5. Each KQs combo loses to 3 KK combos, with a weight of 0.5.
+	// That makes 9 wins to 6 losses.
+	ranges := parseRanges(t, "AA:0.5, KQs", "KK:0.5")
+	res, err := CalcRangeEquity(ranges, board, 0, 1, deck)
+	if (err != nil) {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	if ((!res.Exact) || (res.Equities[0].Total() != 15) ||
+			(math.Abs(res.Equities[0].Value() - 0.6) > 1e-9)) {
+		t.Errorf("expected exact equity of 60%% over 15 showdowns, got %s",
+			res.Equities[0])
+	}
+	if (res.Combos[0].Blocks != 1.5) {
+		t.Errorf("expected %s to block 1.5 combos, got %f",
+			res.Combos[0].Cards, res.Combos[0].Blocks)
+	}
+
+	res, err = CalcRangeEquity(ranges, board, 20000, 1, deck)
+	if (err != nil) {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	if ((res.Exact) || (math.Abs(res.Equities[0].Value() - 0.6) > 0.02)) {
+		t.Errorf("expected sampled equity close to 60%%, got %s",
+			res.Equities[0])
+	}
+}
+
+func TestRangeEquity2(t *testing.T) {
+	deck := Make52CardBag()
+
+	// AA against KK before the flop is too big to enumerate, so it is
+	// sampled.
+	res, err := CalcRangeEquity(parseRanges(t, "AA", "KK"), nil, 0, 1, deck)
+	if (err != nil) {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	if ((res.Exact) || (math.Abs(res.Equities[0].Value() - 0.82) > 0.01)) {
+		t.Errorf("expected sampled equity close to 82%%, got %s",
+			res.Equities[0])
+	}
+
+	// Three ranges share the pot between them.
+	res, err = CalcRangeEquity(parseRanges(t, "QQ+", "AK", "random"), nil,
+		20000, 1, deck)
+	if (err != nil) {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	sum := 0.0
+	for i := range(res.Equities) {
+		sum += res.Equities[i].Value()
+	}
+	if (math.Abs(sum - 1.0) > 1e-9) {
+		t.Errorf("expected the equities to add up to 1, got %f", sum)
+	}
+
+	_, err = CalcRangeEquity(parseRanges(t, "AA"), nil, 0, 1, deck)
+	if (err == nil) {
+		t.Errorf("expected an error with only one range")
+	}
+	_, err = CalcRangeEquity(parseRanges(t, "AsAh", "AsAh"), nil, 0, 1, deck)
+	if (err == nil) {
+		t.Errorf("expected an error when the ranges block each other")
+	}
+}
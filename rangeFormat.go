@@ -0,0 +1,187 @@
+/*
+ * Copyright 2011 Colin Patrick McCabe
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, version 2.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+)
+
+/* Solvers and range editors share ranges as plain text, in one of two
+ * formats:
+ *     AA,KK,AKs:0.5,AQo:0.25      hand classes, each with an optional weight
+ *     AsAh:1,AsKs:0.5,...         single combos, each with its weight
+ * ParseRange reads both of these. ClassString and ComboString write them.
+ */
+
+/* Returns every hand class, in the order solvers usually list them: by high
+ * card, then by low card, with the pair first, then suited, then offsuit.
+ */
+func allHandClasses() []handClass {
+	var ret []handClass
+	for hi := ACE_VAL; hi >= 2; hi-- {
+		ret = append(ret, handClass { hi, hi, CLASS_ANY })
+		for lo := hi - 1; lo >= 2; lo-- {
+			ret = append(ret, handClass { hi, lo, CLASS_SUITED })
+			ret = append(ret, handClass { hi, lo, CLASS_OFFSUIT })
+		}
+	}
+	return ret
+}
+
+func suitToSolverStr(s int) string {
+	switch (s) {
+	case DIAMONDS:
+		return "d"
+	case CLUBS:
+		return "c"
+	case HEARTS:
+		return "h"
+	case SPADES:
+		return "s"
+	}
+	panic(fmt.Sprintf("unexpected suit %d", s))
+}
+
+/* Write a combo the way solvers do, like AsKs or Th9h. */
+func comboIdToSolverStr(id int) string {
+	cards := comboCards(id)
+	ret := ""
+	for i := range(cards) {
+		ret += cardValToRangeStr(cards[i].val) + suitToSolverStr(cards[i].suit)
+	}
+	return ret
+}
+
+func weightSuffix(weight float64) string {
+	if (weight == 1.0) {
+		return ""
+	}
+	return ":" + weightToStr(weight)
+}
+
+/* Write the range as a list of hand classes. Where the combos in a class
+ * don't all have the same weight, the combos are listed one by one instead.
+ */
+func (r *Range) ClassString() string {
+	var strs []string
+	classes := allHandClasses()
+	for i := range(classes) {
+		ids := classes[i].comboIds()
+		whole := true
+		for j := range(ids) {
+			if (r.combos[ids[j]] != r.combos[ids[0]]) {
+				whole = false
+				break
+			}
+		}
+		if (whole) {
+			if (r.combos[ids[0]] > 0) {
+				strs = append(strs, classes[i].String() +
+					weightSuffix(r.combos[ids[0]]))
+			}
+			continue
+		}
+		for j := range(ids) {
+			w := r.combos[ids[j]]
+			if (w > 0) {
+				strs = append(strs, comboIdToSolverStr(ids[j]) + weightSuffix(w))
+			}
+		}
+	}
+	return strings.Join(strs, ",")
+}
+
+/* Write every combo in the range, each with its weight. */
+func (r *Range) ComboString() string {
+	var strs []string
+	classes := allHandClasses()
+	for i := range(classes) {
+		ids := classes[i].comboIds()
+		for j := range(ids) {
+			w := r.combos[ids[j]]
+			if (w > 0) {
+				strs = append(strs, comboIdToSolverStr(ids[j]) + ":" +
+					weightToStr(w))
+			}
+		}
+	}
+	return strings.Join(strs, ",")
+}
+
+/* Parse a range, or if str starts with @, read the range from the file named
+ * by the rest of it.
+ */
+func LoadRange(str string) (*Range, error) {
+	if (!strings.HasPrefix(str, "@")) {
+		return ParseRange(str)
+	}
+	buf, err := ioutil.ReadFile(str[1:])
+	if (err != nil) {
+		return nil, err
+	}
+	return ParseRange(string(buf))
+}
+
+func rangeUsage(fs *flag.FlagSet) func() {
+	return func() {
+		fmt.Fprintf(os.Stderr,
+`%s range: convert a range to the formats solvers use.
+
+Usage:
+%s range [options] [range]
+
+The range may be anything accepted by -range, or @file to read it from a
+file. It is written out as a list of hand classes with weights, like
+"AA,KK:0.5,AKs", or with -combos, as a list of combos, like "AsAh:1,AsKs:0.5".
+
+Options:
+`, os.Args[0], os.Args[0])
+		fs.PrintDefaults()
+	}
+}
+
+func rangeMain(args []string) {
+	fs := flag.NewFlagSet("range", flag.ExitOnError)
+	fs.Usage = rangeUsage(fs)
+	var combos = fs.Bool("combos", false, "list every combo with its weight")
+	var outFile = fs.String("out", "", "write the range to this file")
+	fs.Parse(args)
+	if (fs.NArg() != 1) {
+		fs.Usage()
+		os.Exit(1)
+	}
+	r, err := LoadRange(fs.Arg(0))
+	if (err != nil) {
+		die(err)
+	}
+	str := r.ClassString()
+	if (*combos) {
+		str = r.ComboString()
+	}
+	if (*outFile == "") {
+		fmt.Printf("%s\n", str)
+		return
+	}
+	err = ioutil.WriteFile(*outFile, []byte(str + "\n"), 0644)
+	if (err != nil) {
+		die(err)
+	}
+}
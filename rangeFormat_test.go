@@ -0,0 +1,111 @@
+/*
+ * Copyright 2011 Colin Patrick McCabe
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, version 2.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package main
+
+import (
+	"io/ioutil"
+	"math/rand"
+	"path/filepath"
+	"testing"
+)
+
+func expectSameRange(t *testing.T, what string, a *Range, b *Range) {
+	if (len(a.combos) != len(b.combos)) {
+		t.Errorf("%s: expected %d combos, got %d", what, len(a.combos),
+			len(b.combos))
+		return
+	}
+	for id, w := range(a.combos) {
+		if (b.combos[id] != w) {
+			t.Errorf("%s: expected %s to have weight %f, got %f", what,
+				comboIdToSolverStr(id), w, b.combos[id])
+			return
+		}
+	}
+}
+
+func TestRangeFormats(t *testing.T) {
+	r, _ := ParseRange("QQ+, AKs:0.5, AsKd, T9s:0.25")
+	if (r.ClassString() != "AA,AKs:0.5,AsKd,KK,QQ,T9s:0.25") {
+		t.Errorf("unexpected class string '%s'", r.ClassString())
+	}
+	r, _ = ParseRange("AsKs, ThTc:0.5")
+	if (r.ComboString() != "AsKs:1,ThTc:0.5") {
+		t.Errorf("unexpected combo string '%s'", r.ComboString())
+	}
+
+	// Solvers write out combos with weights of 0, which we leave out.
+	r, err := ParseRange("AsKs:1.0, AhKh:0.0, AdKd:0.75")
+	if (err != nil) {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	if (r.ComboString() != "AdKd:0.75,AsKs:1") {
+		t.Errorf("unexpected combo string '%s'", r.ComboString())
+	}
+}
+
+func TestRangeRoundTrip(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	weights := []float64 { 1.0, 0.5, 0.25, 0.125, 0.3 }
+	for n := 0; n < 20; n++ {
+		r := NewRange()
+		classes := allHandClasses()
+		for i := range(classes) {
+			// Some classes are left out, some are added whole, and some
+			// have a different weight for every combo.
+			switch (rng.Intn(3)) {
+			case 1:
+				r.addHandClass(classes[i], weights[rng.Intn(len(weights))])
+			case 2:
+				ids := classes[i].comboIds()
+				for j := range(ids) {
+					r.setWeight(ids[j], weights[rng.Intn(len(weights))])
+				}
+			}
+		}
+		r2, err := ParseRange(r.ClassString())
+		if (err != nil) {
+			t.Fatalf("unexpected error: %s", err.Error())
+		}
+		expectSameRange(t, "class string", r, r2)
+		r2, err = ParseRange(r.ComboString())
+		if (err != nil) {
+			t.Fatalf("unexpected error: %s", err.Error())
+		}
+		expectSameRange(t, "combo string", r, r2)
+	}
+}
+
+func TestLoadRange(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "range.txt")
+	err := ioutil.WriteFile(path, []byte("AA,KK:0.5,\nAsKs:1\n"), 0644)
+	if (err != nil) {
+		t.Fatalf("failed to write %s: %s", path, err.Error())
+	}
+	r, err := LoadRange("@" + path)
+	if (err != nil) {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	if (r.WeightedLen() != 10) {
+		t.Errorf("expected 10 combos, got %s", r.ClassString())
+	}
+	_, err = LoadRange("@" + filepath.Join(dir, "nonexistent"))
+	if (err == nil) {
+		t.Errorf("expected an error loading a file that doesn't exist")
+	}
+}
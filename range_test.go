@@ -0,0 +1,89 @@
+/*
+ * Copyright 2011 Colin Patrick McCabe
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, version 2.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package main
+
+import (
+	"testing"
+)
+
+func expectRangeLen(t *testing.T, str string, eLen int) {
+	r, err := ParseRange(str)
+	if (err != nil) {
+		t.Errorf("failed to parse range '%s': %s", str, err.Error())
+		return
+	}
+	if (r.Len() != eLen) {
+		t.Errorf("expected range '%s' to have %d combos, got %d", str,
+			eLen, r.Len())
+	}
+}
+
+func TestParseRange(t *testing.T) {
+	expectRangeLen(t, "AA", 6)
+	expectRangeLen(t, "AKs", 4)
+	expectRangeLen(t, "AKo", 12)
+	expectRangeLen(t, "KA", 16)
+	expectRangeLen(t, "QQ+", 18)
+	expectRangeLen(t, "ATs+", 16)
+	expectRangeLen(t, "A2s-A5s", 16)
+	expectRangeLen(t, "44-22", 18)
+	expectRangeLen(t, "AsKs", 1)
+	expectRangeLen(t, "A10s, AsTs, KhQd", 5)
+	expectRangeLen(t, "random", 1326)
+
+	bad := []string { "", "AAs", "AK-QJ", "AsAs", "XY", "AKx", "AKs:0",
+		"AKs:1.5", "AKs:x", "AKs:-0.5" }
+	for i := range(bad) {
+		_, err := ParseRange(bad[i])
+		if (err == nil) {
+			t.Errorf("expected an error parsing '%s'", bad[i])
+		}
+	}
+}
+
+func TestRangeLive(t *testing.T) {
+	r, _ := ParseRange("AA, AKs")
+	dead, _ := StrToCards("AS 2D")
+	live := r.Live(dead)
+	if (len(live) != 6) {
+		t.Errorf("expected 6 live combos, got %d", len(live))
+	}
+}
+
+func TestWeightedRange(t *testing.T) {
+	r, err := ParseRange("AKs:0.5, QQ:0.25, AsKs, JJ")
+	if (err != nil) {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	if ((r.Len() != 16) || (r.WeightedLen() != 10.0)) {
+		t.Errorf("expected 16 combos weighing 10, got %d weighing %f",
+			r.Len(), r.WeightedLen())
+	}
+	combo, _ := StrToCards("KS AS")
+	if (r.Weight(combo) != 1.0) {
+		t.Errorf("expected AsKs to have a weight of 1, got %f", r.Weight(combo))
+	}
+	combo, _ = StrToCards("AH KH")
+	if (r.Weight(combo) != 0.5) {
+		t.Errorf("expected AhKh to have a weight of 0.5, got %f",
+			r.Weight(combo))
+	}
+	r, _ = ParseRange("AsKs:0.5, AhAd")
+	if (r.String() != "ASKS:0.5,AHAD") {
+		t.Errorf("unexpected range string '%s'", r.String())
+	}
+}
@@ -0,0 +1,279 @@
+/*
+ * Copyright 2011 Colin Patrick McCabe
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, version 2.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package main
+
+import (
+	"bufio"
+	"container/list"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// The number of results we remember by default.
+const DEFAULT_CACHE_SZ = 10000
+
+/* Every way of relabeling the four suits. Since the deck is symmetric in
+ * suit, two scenarios which differ only by a relabeling always have the same
+ * odds.
+ */
+var suitPerms [][4]int = makeSuitPerms()
+
+func makeSuitPerms() [][4]int {
+	var ret [][4]int
+	var perm [4]int
+	var used [4]bool
+	var gen func(n int)
+	gen = func(n int) {
+		if (n == 4) {
+			ret = append(ret, perm)
+			return
+		}
+		for s := DIAMONDS; s <= SPADES; s++ {
+			if (used[s]) {
+				continue
+			}
+			used[s] = true
+			perm[n] = s
+			gen(n + 1)
+			used[s] = false
+		}
+	}
+	gen(0)
+	return ret
+}
+
+/* Serialize some cards for use in a cache key, after relabeling their suits
+ * with perm. The cards are sorted first, so that their order doesn't matter.
+ */
+func cardsKey(cards CardSlice, perm [4]int) string {
+	relabeled := cards.Copy()
+	for i := range(relabeled) {
+		relabeled[i].suit = perm[relabeled[i].suit]
+	}
+	sort.Sort(relabeled)
+	ret := ""
+	for i := range(relabeled) {
+		ret += relabeled[i].ShortString()
+	}
+	return ret
+}
+
+/* Returns the canonical form of a Scenario.
+ *
+ * Two scenarios have the same canonical form if they are the same up to the
+ * order of the cards and a relabeling of the suits. We try every relabeling
+ * and pick whichever one gives the smallest key.
+ */
+func (sc *Scenario) CanonicalKey() string {
+	best := ""
+	for p := range(suitPerms) {
+		opps := make([]string, len(sc.Opponents))
+		for i := range(sc.Opponents) {
+			opps[i] = cardsKey(sc.Opponents[i], suitPerms[p])
+		}
+		sort.Strings(opps)
+		key := cardsKey(sc.Hole, suitPerms[p]) + "|" +
+			cardsKey(sc.Board, suitPerms[p]) + "|" + strings.Join(opps, ",")
+		if ((best == "") || (key < best)) {
+			best = key
+		}
+	}
+	return fmt.Sprintf("%s|%s|%d|%d|%s", sc.Game, sc.Mode(), sc.Samples,
+		sc.Seed, best)
+}
+
+type cacheEntry struct {
+	Key string `json:"key"`
+	Response *OddsResponse `json:"response"`
+}
+
+/* A ResultCache remembers the results of the most recent maxEntries
+ * scenarios, keyed on Scenario.CanonicalKey.
+ *
+ * If a store file is opened, every result is also appended to it, and the
+ * results already in it are loaded back in. The file is rewritten to hold
+ * only the cached results when it is opened, and again whenever it has grown
+ * to twice the size of the cache. A nil *ResultCache is valid and never holds
+ * anything.
+ */
+type ResultCache struct {
+	lock sync.Mutex
+	maxEntries int
+	lru *list.List
+	items map[string] *list.Element
+	store *os.File
+	storeLines int
+}
+
+func NewResultCache(maxEntries int) *ResultCache {
+	if (maxEntries <= 0) {
+		return nil
+	}
+	c := new(ResultCache)
+	c.maxEntries = maxEntries
+	c.lru = list.New()
+	c.items = make(map[string] *list.Element)
+	return c
+}
+
+/* Load the results in a store file, and append every new result to it from
+ * now on. The file is created if it doesn't exist.
+ */
+func (c *ResultCache) OpenStore(path string) error {
+	f, err := os.OpenFile(path, os.O_RDWR | os.O_CREATE | os.O_APPEND, 0644)
+	if (err != nil) {
+		return err
+	}
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, MAX_REQUEST_SZ)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		var ent cacheEntry
+		err = json.Unmarshal(scanner.Bytes(), &ent)
+		if ((err != nil) || (ent.Response == nil)) {
+			f.Close()
+			return fmt.Errorf("%s:%d: corrupt cache entry", path, lineNo)
+		}
+		c.insert(ent.Key, ent.Response)
+	}
+	err = scanner.Err()
+	if (err != nil) {
+		f.Close()
+		return err
+	}
+	c.store = f
+	err = c.compactStore()
+	if (err != nil) {
+		f.Close()
+		c.store = nil
+		return err
+	}
+	return nil
+}
+
+/* Rewrite the store file with only the entries in the cache, least recently
+ * used first, so that loading it again gives the same cache.
+ *
+ * Must be called with the lock held, or before anyone else can see c.
+ */
+func (c *ResultCache) compactStore() error {
+	err := c.store.Truncate(0)
+	if (err != nil) {
+		return err
+	}
+	w := bufio.NewWriter(c.store)
+	for elem := c.lru.Back(); elem != nil; elem = elem.Prev() {
+		buf, err := json.Marshal(elem.Value.(*cacheEntry))
+		if (err != nil) {
+			return err
+		}
+		_, err = w.Write(append(buf, '\n'))
+		if (err != nil) {
+			return err
+		}
+	}
+	c.storeLines = c.lru.Len()
+	return w.Flush()
+}
+
+func (c *ResultCache) Close() error {
+	if ((c == nil) || (c.store == nil)) {
+		return nil
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	err := c.store.Close()
+	c.store = nil
+	return err
+}
+
+func (c *ResultCache) Get(key string) (*OddsResponse, bool) {
+	if (c == nil) {
+		return nil, false
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	elem, ok := c.items[key]
+	if (!ok) {
+		return nil, false
+	}
+	c.lru.MoveToFront(elem)
+	return elem.Value.(*cacheEntry).Response, true
+}
+
+// Must be called with the lock held, or before anyone else can see c.
+func (c *ResultCache) insert(key string, resp *OddsResponse) {
+	elem, ok := c.items[key]
+	if (ok) {
+		elem.Value.(*cacheEntry).Response = resp
+		c.lru.MoveToFront(elem)
+		return
+	}
+	c.items[key] = c.lru.PushFront(&cacheEntry { key, resp })
+	for ; c.lru.Len() > c.maxEntries; {
+		oldest := c.lru.Back()
+		c.lru.Remove(oldest)
+		delete(c.items, oldest.Value.(*cacheEntry).Key)
+	}
+}
+
+func (c *ResultCache) Put(key string, resp *OddsResponse) error {
+	if (c == nil) {
+		return nil
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.insert(key, resp)
+	if (c.store == nil) {
+		return nil
+	}
+	buf, err := json.Marshal(&cacheEntry { key, resp })
+	if (err != nil) {
+		return err
+	}
+	_, err = c.store.Write(append(buf, '\n'))
+	if (err != nil) {
+		return err
+	}
+	c.storeLines++
+	if (c.storeLines >= 2 * c.maxEntries) {
+		return c.compactStore()
+	}
+	return nil
+}
+
+/* Open a ResultCache as configured on the command line. */
+func OpenResultCache(maxEntries int, storePath string) (*ResultCache, error) {
+	c := NewResultCache(maxEntries)
+	if (storePath == "") {
+		return c, nil
+	}
+	if (c == nil) {
+		return nil, fmt.Errorf("a cache file can't be used when the " +
+			"cache size is 0.")
+	}
+	err := c.OpenStore(storePath)
+	if (err != nil) {
+		return nil, err
+	}
+	return c, nil
+}
@@ -0,0 +1,135 @@
+/*
+ * Copyright 2011 Colin Patrick McCabe
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, version 2.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func scenarioKey(t *testing.T, hole string, board string) string {
+	req := &OddsRequest { Hole: hole, Board: board }
+	sc, err := req.Scenario()
+	if (err != nil) {
+		t.Fatalf("failed to create scenario: %s", err.Error())
+	}
+	return sc.CanonicalKey()
+}
+
+func TestCanonicalKey(t *testing.T) {
+	k1 := scenarioKey(t, "KS QS", "AS 3S 5H")
+	k2 := scenarioKey(t, "QH KH", "5D AH 3H")
+	if (k1 != k2) {
+		t.Errorf("expected suit-equivalent scenarios to have the same " +
+			"key, but got %s and %s", k1, k2)
+	}
+	k3 := scenarioKey(t, "KS QS", "AS 3S 5S")
+	if (k1 == k3) {
+		t.Errorf("expected different scenarios to have different keys, " +
+			"but both were %s", k1)
+	}
+}
+
+func TestResultCacheLru(t *testing.T) {
+	c := NewResultCache(2)
+	c.Put("a", &OddsResponse { Total: 1 })
+	c.Put("b", &OddsResponse { Total: 2 })
+	c.Get("a")
+	c.Put("c", &OddsResponse { Total: 3 })
+	_, ok := c.Get("b")
+	if (ok) {
+		t.Errorf("expected b to be evicted as the least recently used entry")
+	}
+	resp, ok := c.Get("a")
+	if ((!ok) || (resp.Total != 1)) {
+		t.Errorf("expected a to still be cached")
+	}
+	var nilCache *ResultCache
+	nilCache.Put("a", &OddsResponse { Total: 1 })
+	_, ok = nilCache.Get("a")
+	if (ok) {
+		t.Errorf("expected a nil cache to hold nothing")
+	}
+}
+
+func TestResultCacheStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache")
+	c, err := OpenResultCache(10, path)
+	if (err != nil) {
+		t.Fatalf("failed to open cache: %s", err.Error())
+	}
+	c.Put("a", &OddsResponse { Total: 1, Text: "hello" })
+	c.Close()
+
+	c, err = OpenResultCache(10, path)
+	if (err != nil) {
+		t.Fatalf("failed to reopen cache: %s", err.Error())
+	}
+	defer c.Close()
+	resp, ok := c.Get("a")
+	if ((!ok) || (resp.Text != "hello")) {
+		t.Errorf("expected the stored result to be loaded back in")
+	}
+}
+
+func storeLines(t *testing.T, path string) int {
+	buf, err := os.ReadFile(path)
+	if (err != nil) {
+		t.Fatalf("failed to read the cache file: %s", err.Error())
+	}
+	return bytes.Count(buf, []byte("\n"))
+}
+
+func TestResultCacheStoreCompacts(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache")
+	c, err := OpenResultCache(3, path)
+	if (err != nil) {
+		t.Fatalf("failed to open cache: %s", err.Error())
+	}
+	for i := 0; i < 20; i++ {
+		c.Put(fmt.Sprintf("k%d", i), &OddsResponse { Text: "old" })
+		if (storeLines(t, path) >= 6) {
+			t.Fatalf("expected the cache file to be compacted, but it " +
+				"has %d entries", storeLines(t, path))
+		}
+	}
+	c.Put("k0", &OddsResponse { Text: "new" })
+	c.Close()
+
+	c, err = OpenResultCache(3, path)
+	if (err != nil) {
+		t.Fatalf("failed to reopen cache: %s", err.Error())
+	}
+	defer c.Close()
+	if (storeLines(t, path) != 3) {
+		t.Errorf("expected 3 entries once reopened, got %d",
+			storeLines(t, path))
+	}
+	for _, key := range([]string { "k18", "k19", "k0" }) {
+		_, ok := c.Get(key)
+		if (!ok) {
+			t.Errorf("expected %s to be loaded back in", key)
+		}
+	}
+	resp, _ := c.Get("k0")
+	if ((resp == nil) || (resp.Text != "new")) {
+		t.Errorf("expected the newest result for k0 to be kept")
+	}
+}
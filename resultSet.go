@@ -20,6 +20,7 @@ import (
 	"fmt"
 )
 
+/* Counts how often we make each type of hand. */
 type ResultSet struct {
 	handTyCnt [MAX_HANDS] int64
 }
@@ -47,12 +48,52 @@ func (res *ResultSet) MergeResultSet(rhs *ResultSet) {
 	}
 }
 
-func (res *ResultSet) String() string {
+func (res *ResultSet) Total() int64 {
 	var totalHands int64
 	totalHands = 0
 	for i := range(res.handTyCnt) {
 		totalHands = totalHands + res.handTyCnt[i]
 	}
+	return totalHands
+}
+
+/* Returns the chance of making this type of hand or better. */
+func (res *ResultSet) ChanceOfAtLeast(ty int) float64 {
+	var cnt int64
+	for i := ty; i < MAX_HANDS; i++ {
+		cnt += res.handTyCnt[i]
+	}
+	return float64(cnt) / float64(res.Total())
+}
+
+/* The odds of making one type of hand, in a form that is easy to hand to
+ * encoding/json.
+ */
+type HandTyResult struct {
+	Hand string `json:"hand"`
+	Count int64 `json:"count"`
+	Percent float64 `json:"percent"`
+}
+
+/* Returns one HandTyResult for every type of hand that we have a chance of
+ * making, from worst to best.
+ */
+func (res *ResultSet) Entries() []HandTyResult {
+	totalHands := res.Total()
+	ret := []HandTyResult {}
+	for i := range(res.handTyCnt) {
+		if (res.handTyCnt[i] == 0) {
+			continue
+		}
+		percent := float64(res.handTyCnt[i]) * 100.0 / float64(totalHands)
+		ret = append(ret, HandTyResult { HandTyToStr(i), res.handTyCnt[i],
+			percent })
+	}
+	return ret
+}
+
+func (res *ResultSet) String() string {
+	totalHands := res.Total()
 
 	ret := ""
 	for i := range(res.handTyCnt) {
@@ -0,0 +1,769 @@
+/*
+ * Copyright 2011 Colin Patrick McCabe
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, version 2.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+/* Once the river is dealt there are no more cards to come, so the rest of
+ * the hand is a game between two ranges over a small tree of bets. We solve
+ * it with counterfactual regret minimization (CFR). Each combo in each range
+ * keeps a regret for every action at every point in the tree where that
+ * player acts. Playing each action in proportion to its positive regret, and
+ * averaging over many iterations, approaches a Nash equilibrium.
+ *
+ * We use CFR+, which never lets a regret drop below zero and gives later
+ * iterations more weight in the average. Rather than walking the tree once
+ * per pair of combos, each walk carries a vector with one entry per combo.
+ *
+ * The first player is out of position (oop) and acts first. The second
+ * player is in position (ip).
+ */
+
+const DEFAULT_RIVER_ITERATIONS = 1000
+
+const (
+	RIVER_ACTION = iota
+	RIVER_FOLD
+	RIVER_SHOWDOWN
+)
+
+var RIVER_PLAYER_NAMES = []string { "oop", "ip" }
+
+/* A spot on the river. Bets are fractions of the pot. Stack is what each
+ * player has behind, and after a bet, at most MaxRaises raises can follow.
+ */
+type RiverConfig struct {
+	Board CardSlice
+	Ranges [2]*Range
+	Pot float64
+	Stack float64
+	Bets []float64
+	AllIn bool
+	MaxRaises int
+	Iterations int
+}
+
+/* A point in the betting tree. Bets holds what each player has put in on the
+ * river. At a fold, Player is the one who folded. Regrets and strategy sums
+ * are kept for each combo of the player to act, for each action.
+ */
+type riverNode struct {
+	Ty int
+	Player int
+	Bets [2]float64
+	History string
+	Actions []string
+	Children []*riverNode
+	regrets [][]float64
+	stratSum [][]float64
+	reach []float64
+	ev []float64
+}
+
+type RiverSolution struct {
+	Config *RiverConfig
+	Combos [2][]CardSlice
+	Root *riverNode
+	Ev [2]float64
+	Exploitability float64
+	weights [2][]float64
+	strength [2][]int
+	cards [2][][2]int
+	same [2][]int
+	order [2][]int
+	numNodes int
+}
+
+func chipsToStr(amt float64) string {
+	return strconv.FormatFloat(math.Floor(amt * 100.0 + 0.5) / 100.0, 'f',
+		-1, 64)
+}
+
+func (cfg *RiverConfig) Validate() error {
+	if (len(cfg.Board) != BOARD_MAX) {
+		return fmt.Errorf("the board must have five cards.")
+	}
+	dupe := cfg.Board.HasDuplicates()
+	if (dupe != nil) {
+		return fmt.Errorf("The card %s appears more than once on the " +
+			"board! That is not possible.", dupe)
+	}
+	if ((cfg.Pot <= 0) || (cfg.Stack < 0)) {
+		return fmt.Errorf("the pot must be positive, and the stacks can't " +
+			"be negative.")
+	}
+	for i := range(cfg.Bets) {
+		if (cfg.Bets[i] <= 0) {
+			return fmt.Errorf("bet sizes must be positive.")
+		}
+	}
+	if ((cfg.MaxRaises < 0) || (cfg.Iterations < 1)) {
+		return fmt.Errorf("the number of raises can't be negative, and " +
+			"at least one iteration is needed.")
+	}
+	return nil
+}
+
+/* Returns the amounts a player could bet or raise to, given what the other
+ * player has put in. Sizes that would need more than the stack become
+ * all-in, and are only listed once.
+ */
+func (cfg *RiverConfig) betAmounts(bets [2]float64, p int) []float64 {
+	o := 1 - p
+	toCall := bets[o] - bets[p]
+	potAfterCall := cfg.Pot + bets[0] + bets[1] + toCall
+	var ret []float64
+	add := func(amt float64) {
+		if (amt > cfg.Stack) {
+			amt = cfg.Stack
+		}
+		if (amt <= bets[o]) {
+			return
+		}
+		for i := range(ret) {
+			if (ret[i] == amt) {
+				return
+			}
+		}
+		ret = append(ret, amt)
+	}
+	for i := range(cfg.Bets) {
+		add(bets[o] + cfg.Bets[i] * potAfterCall)
+	}
+	if (cfg.AllIn) {
+		add(cfg.Stack)
+	}
+	sort.Float64s(ret)
+	return ret
+}
+
+/* Build the betting tree below the given point. checked is true if the
+ * other player has just checked, and raises counts the bets and raises so
+ * far.
+ */
+func (sol *RiverSolution) build(p int, bets [2]float64, raises int,
+		checked bool, history string) *riverNode {
+	cfg := sol.Config
+	o := 1 - p
+	n := &riverNode { Ty: RIVER_ACTION, Player: p, Bets: bets,
+		History: history }
+	sol.numNodes++
+	addChild := func(action string, child *riverNode) {
+		n.Actions = append(n.Actions, action)
+		n.Children = append(n.Children, child)
+	}
+	next := func(action string) string {
+		if (history == "") {
+			return RIVER_PLAYER_NAMES[p] + " " + action
+		}
+		return history + ", " + RIVER_PLAYER_NAMES[p] + " " + action
+	}
+	verb := "bet"
+	if (bets[o] == bets[p]) {
+		if (checked) {
+			addChild("check", &riverNode { Ty: RIVER_SHOWDOWN, Bets: bets })
+		} else {
+			addChild("check", sol.build(o, bets, raises, true,
+				next("checks")))
+		}
+	} else {
+		verb = "raise to"
+		addChild("fold", &riverNode { Ty: RIVER_FOLD, Player: p,
+			Bets: bets })
+		called := bets
+		called[p] = bets[o]
+		addChild("call", &riverNode { Ty: RIVER_SHOWDOWN, Bets: called })
+	}
+	if ((raises > cfg.MaxRaises) || (bets[o] >= cfg.Stack)) {
+		return n
+	}
+	amts := cfg.betAmounts(bets, p)
+	for i := range(amts) {
+		action := verb + " " + chipsToStr(amts[i])
+		if (amts[i] == cfg.Stack) {
+			action = "all-in " + chipsToStr(amts[i])
+		}
+		nb := bets
+		nb[p] = amts[i]
+		addChild(action, sol.build(o, nb, raises + 1, false, next(action)))
+	}
+	return n
+}
+
+/* Sorts combos by the strength of their best hand. */
+type riverHandSlice struct {
+	hands []*Hand
+	idx []int
+}
+
+func (hs riverHandSlice) Len() int {
+	return len(hs.idx)
+}
+
+func (hs riverHandSlice) Less(i, j int) bool {
+	return hs.hands[hs.idx[i]].Compare(hs.hands[hs.idx[j]]) < 0
+}
+
+func (hs riverHandSlice) Swap(i, j int) {
+	hs.idx[i], hs.idx[j] = hs.idx[j], hs.idx[i]
+}
+
+/* Sorts a player's combos from weakest to strongest. */
+type riverOrderSlice struct {
+	idx []int
+	strength []int
+}
+
+func (rs riverOrderSlice) Len() int {
+	return len(rs.idx)
+}
+
+func (rs riverOrderSlice) Less(i, j int) bool {
+	return rs.strength[rs.idx[i]] < rs.strength[rs.idx[j]]
+}
+
+func (rs riverOrderSlice) Swap(i, j int) {
+	rs.idx[i], rs.idx[j] = rs.idx[j], rs.idx[i]
+}
+
+/* Rank every combo in both ranges. Combos are sorted with Hand.Compare, and
+ * those which tie get the same strength.
+ */
+func (sol *RiverSolution) rankCombos() {
+	var hs riverHandSlice
+	var owner [][2]int
+	for p := 0; p < 2; p++ {
+		sol.strength[p] = make([]int, len(sol.Combos[p]))
+		for k := range(sol.Combos[p]) {
+			hs.hands = append(hs.hands, bestHandWith(sol.Combos[p][k],
+				sol.Config.Board))
+			hs.idx = append(hs.idx, len(owner))
+			owner = append(owner, [2]int { p, k })
+		}
+	}
+	sort.Sort(hs)
+	strength := 0
+	for i := range(hs.idx) {
+		if ((i > 0) &&
+				(hs.hands[hs.idx[i]].Compare(hs.hands[hs.idx[i - 1]]) != 0)) {
+			strength++
+		}
+		o := owner[hs.idx[i]]
+		sol.strength[o[0]][o[1]] = strength
+	}
+	for p := 0; p < 2; p++ {
+		sol.order[p] = make([]int, len(sol.Combos[p]))
+		for k := range(sol.order[p]) {
+			sol.order[p][k] = k
+		}
+		sort.Stable(riverOrderSlice { sol.order[p], sol.strength[p] })
+	}
+}
+
+/* Returns, for each of player p's combos, the total reach of the other
+ * player's combos that can be held at the same time. A combo of the other
+ * player that shares a card with ours is taken off once for each card, so
+ * an identical combo has to be added back.
+ */
+func (sol *RiverSolution) compatible(p int, reachOpp []float64) []float64 {
+	o := 1 - p
+	var byCard [NUM_CARDS]float64
+	total := 0.0
+	for k := range(reachOpp) {
+		total += reachOpp[k]
+		byCard[sol.cards[o][k][0]] += reachOpp[k]
+		byCard[sol.cards[o][k][1]] += reachOpp[k]
+	}
+	ret := make([]float64, len(sol.Combos[p]))
+	for a := range(ret) {
+		c := sol.cards[p][a]
+		ret[a] = total - byCard[c[0]] - byCard[c[1]]
+		if (sol.same[p][a] != -1) {
+			ret[a] += reachOpp[sol.same[p][a]]
+		}
+	}
+	return ret
+}
+
+/* Returns the value of a showdown for each of player p's combos, weighted
+ * by the reach of the other player's combos.
+ */
+func (sol *RiverSolution) showdownValues(n *riverNode, p int,
+		reachOpp []float64) []float64 {
+	o := 1 - p
+	pot := sol.Config.Pot
+	bet := n.Bets[p]
+	compat := sol.compatible(p, reachOpp)
+	win := make([]float64, len(compat))
+	lose := make([]float64, len(compat))
+	var byCard [NUM_CARDS]float64
+	sum := 0.0
+	j := 0
+	for _, a := range(sol.order[p]) {
+		for ; (j < len(sol.order[o])) &&
+				(sol.strength[o][sol.order[o][j]] < sol.strength[p][a]); j++ {
+			b := sol.order[o][j]
+			sum += reachOpp[b]
+			byCard[sol.cards[o][b][0]] += reachOpp[b]
+			byCard[sol.cards[o][b][1]] += reachOpp[b]
+		}
+		win[a] = sum - byCard[sol.cards[p][a][0]] - byCard[sol.cards[p][a][1]]
+	}
+	byCard = [NUM_CARDS]float64 {}
+	sum = 0.0
+	j = len(sol.order[o]) - 1
+	for i := len(sol.order[p]) - 1; i >= 0; i-- {
+		a := sol.order[p][i]
+		for ; (j >= 0) &&
+				(sol.strength[o][sol.order[o][j]] > sol.strength[p][a]); j-- {
+			b := sol.order[o][j]
+			sum += reachOpp[b]
+			byCard[sol.cards[o][b][0]] += reachOpp[b]
+			byCard[sol.cards[o][b][1]] += reachOpp[b]
+		}
+		lose[a] = sum - byCard[sol.cards[p][a][0]] -
+			byCard[sol.cards[p][a][1]]
+	}
+	ret := make([]float64, len(compat))
+	for a := range(ret) {
+		tie := compat[a] - win[a] - lose[a]
+		ret[a] = win[a] * (pot + bet) - lose[a] * bet + tie * pot / 2.0
+	}
+	return ret
+}
+
+/* Returns the value of an end of the hand for each of player p's combos.
+ * A player's value is their share of the pot, less what they put in on the
+ * river.
+ */
+func (sol *RiverSolution) terminalValues(n *riverNode, p int,
+		reachOpp []float64) []float64 {
+	if (n.Ty == RIVER_SHOWDOWN) {
+		return sol.showdownValues(n, p, reachOpp)
+	}
+	payoff := -n.Bets[p]
+	if (n.Player != p) {
+		payoff = sol.Config.Pot + n.Bets[n.Player]
+	}
+	ret := sol.compatible(p, reachOpp)
+	for a := range(ret) {
+		ret[a] *= payoff
+	}
+	return ret
+}
+
+/* Returns the current strategy of each combo at a node, from its regrets. */
+func (n *riverNode) strategy() [][]float64 {
+	ret := make([][]float64, len(n.regrets))
+	for a := range(n.regrets) {
+		ret[a] = make([]float64, len(n.Actions))
+		total := 0.0
+		for k := range(n.regrets[a]) {
+			total += n.regrets[a][k]
+		}
+		for k := range(ret[a]) {
+			if (total > 0) {
+				ret[a][k] = n.regrets[a][k] / total
+			} else {
+				ret[a][k] = 1.0 / float64(len(n.Actions))
+			}
+		}
+	}
+	return ret
+}
+
+/* Returns the average strategy of each combo at a node. This is what
+ * approaches the equilibrium.
+ */
+func (n *riverNode) avgStrategy() [][]float64 {
+	ret := make([][]float64, len(n.stratSum))
+	for a := range(n.stratSum) {
+		ret[a] = make([]float64, len(n.Actions))
+		total := 0.0
+		for k := range(n.stratSum[a]) {
+			total += n.stratSum[a][k]
+		}
+		for k := range(ret[a]) {
+			if (total > 0) {
+				ret[a][k] = n.stratSum[a][k] / total
+			} else {
+				ret[a][k] = 1.0 / float64(len(n.Actions))
+			}
+		}
+	}
+	return ret
+}
+
+func (sol *RiverSolution) initNode(n *riverNode) {
+	if (n.Ty != RIVER_ACTION) {
+		return
+	}
+	num := len(sol.Combos[n.Player])
+	n.regrets = make([][]float64, num)
+	n.stratSum = make([][]float64, num)
+	for a := 0; a < num; a++ {
+		n.regrets[a] = make([]float64, len(n.Actions))
+		n.stratSum[a] = make([]float64, len(n.Actions))
+	}
+	for k := range(n.Children) {
+		sol.initNode(n.Children[k])
+	}
+}
+
+/* One CFR+ walk of the tree, updating the regrets of player p, and the
+ * strategy sums of the other player. Returns the counterfactual value of
+ * each of p's combos.
+ */
+func (sol *RiverSolution) cfr(n *riverNode, p int, reachOpp []float64,
+		iter int) []float64 {
+	if (n.Ty != RIVER_ACTION) {
+		return sol.terminalValues(n, p, reachOpp)
+	}
+	strat := n.strategy()
+	ret := make([]float64, len(sol.Combos[p]))
+	if (n.Player == p) {
+		vals := make([][]float64, len(n.Actions))
+		for k := range(n.Children) {
+			vals[k] = sol.cfr(n.Children[k], p, reachOpp, iter)
+			for a := range(ret) {
+				ret[a] += strat[a][k] * vals[k][a]
+			}
+		}
+		for a := range(ret) {
+			for k := range(vals) {
+				n.regrets[a][k] = math.Max(0,
+					n.regrets[a][k] + vals[k][a] - ret[a])
+			}
+		}
+		return ret
+	}
+	for a := range(reachOpp) {
+		for k := range(n.Actions) {
+			n.stratSum[a][k] += float64(iter) * reachOpp[a] * strat[a][k]
+		}
+	}
+	next := make([]float64, len(reachOpp))
+	for k := range(n.Children) {
+		for a := range(reachOpp) {
+			next[a] = reachOpp[a] * strat[a][k]
+		}
+		vals := sol.cfr(n.Children[k], p, next, iter)
+		for a := range(ret) {
+			ret[a] += vals[a]
+		}
+	}
+	return ret
+}
+
+/* Walk the tree with the average strategies, returning the counterfactual
+ * value of each of player p's combos. If best is true, player p plays a best
+ * response instead of their average strategy. Otherwise, each of p's nodes
+ * records the expected value of each combo that reaches it.
+ */
+func (sol *RiverSolution) evaluate(n *riverNode, p int, reachOpp []float64,
+		best bool) []float64 {
+	if (n.Ty != RIVER_ACTION) {
+		return sol.terminalValues(n, p, reachOpp)
+	}
+	strat := n.avgStrategy()
+	ret := make([]float64, len(sol.Combos[p]))
+	if (n.Player == p) {
+		for a := range(ret) {
+			ret[a] = math.Inf(-1)
+			if (!best) {
+				ret[a] = 0
+			}
+		}
+		for k := range(n.Children) {
+			vals := sol.evaluate(n.Children[k], p, reachOpp, best)
+			for a := range(ret) {
+				if (best) {
+					ret[a] = math.Max(ret[a], vals[a])
+				} else {
+					ret[a] += strat[a][k] * vals[a]
+				}
+			}
+		}
+		if (!best) {
+			compat := sol.compatible(p, reachOpp)
+			n.ev = make([]float64, len(ret))
+			for a := range(ret) {
+				n.ev[a] = math.NaN()
+				if (compat[a] > 0) {
+					n.ev[a] = ret[a] / compat[a]
+				}
+			}
+		}
+		return ret
+	}
+	next := make([]float64, len(reachOpp))
+	for k := range(n.Children) {
+		for a := range(reachOpp) {
+			next[a] = reachOpp[a] * strat[a][k]
+		}
+		vals := sol.evaluate(n.Children[k], p, next, best)
+		for a := range(ret) {
+			ret[a] += vals[a]
+		}
+	}
+	return ret
+}
+
+/* Record how likely each combo is to reach each node where its player acts,
+ * under the average strategies.
+ */
+func (sol *RiverSolution) setReach(n *riverNode, reach [2][]float64) {
+	if (n.Ty != RIVER_ACTION) {
+		return
+	}
+	p := n.Player
+	n.reach = reach[p]
+	strat := n.avgStrategy()
+	for k := range(n.Children) {
+		next := reach
+		next[p] = make([]float64, len(reach[p]))
+		for a := range(next[p]) {
+			next[p][a] = reach[p][a] * strat[a][k]
+		}
+		sol.setReach(n.Children[k], next)
+	}
+}
+
+/* Returns the average value to player p of the counterfactual values,
+ * across every pair of combos that can be dealt together.
+ */
+func (sol *RiverSolution) average(p int, vals []float64) float64 {
+	compat := sol.compatible(p, sol.weights[1 - p])
+	num := 0.0
+	den := 0.0
+	for a := range(vals) {
+		num += sol.weights[p][a] * vals[a]
+		den += sol.weights[p][a] * compat[a]
+	}
+	return num / den
+}
+
+func SolveRiver(cfg *RiverConfig) (*RiverSolution, error) {
+	err := cfg.Validate()
+	if (err != nil) {
+		return nil, err
+	}
+	sol := &RiverSolution { Config: cfg }
+	for p := 0; p < 2; p++ {
+		sol.Combos[p] = cfg.Ranges[p].Live(cfg.Board)
+		if (len(sol.Combos[p]) == 0) {
+			return nil, fmt.Errorf("the %s range has no combos left once " +
+				"the board is taken into account.", RIVER_PLAYER_NAMES[p])
+		}
+		for k := range(sol.Combos[p]) {
+			c := sol.Combos[p][k]
+			sol.weights[p] = append(sol.weights[p], cfg.Ranges[p].Weight(c))
+			sol.cards[p] = append(sol.cards[p],
+				[2]int { cardId(c[0]), cardId(c[1]) })
+		}
+	}
+	for p := 0; p < 2; p++ {
+		o := 1 - p
+		sol.same[p] = make([]int, len(sol.Combos[p]))
+		for a := range(sol.Combos[p]) {
+			sol.same[p][a] = -1
+			for b := range(sol.Combos[o]) {
+				if (comboId(sol.Combos[p][a][0], sol.Combos[p][a][1]) ==
+						comboId(sol.Combos[o][b][0], sol.Combos[o][b][1])) {
+					sol.same[p][a] = b
+				}
+			}
+		}
+	}
+	compat := sol.compatible(0, sol.weights[1])
+	pairs := 0.0
+	for a := range(compat) {
+		pairs += sol.weights[0][a] * compat[a]
+	}
+	if (pairs == 0) {
+		return nil, fmt.Errorf("the ranges block each other completely.")
+	}
+	sol.rankCombos()
+	sol.Root = sol.build(0, [2]float64 {}, 0, false, "")
+	sol.initNode(sol.Root)
+	for iter := 1; iter <= cfg.Iterations; iter++ {
+		for p := 0; p < 2; p++ {
+			sol.cfr(sol.Root, p, sol.weights[1 - p], iter)
+		}
+	}
+	br := 0.0
+	for p := 0; p < 2; p++ {
+		sol.Ev[p] = sol.average(p, sol.evaluate(sol.Root, p,
+			sol.weights[1 - p], false))
+		br += sol.average(p, sol.evaluate(sol.Root, p, sol.weights[1 - p],
+			true))
+	}
+	/* Whatever the players do, their values add up to the pot. How much
+	 * more each could get by switching to a best response measures how far
+	 * we are from the equilibrium.
+	 */
+	sol.Exploitability = (br - cfg.Pot) / 2.0
+	sol.setReach(sol.Root, sol.weights)
+	return sol, nil
+}
+
+/* Returns the combos of a player, strongest first. */
+func (sol *RiverSolution) byStrength(p int) []int {
+	ret := make([]int, len(sol.order[p]))
+	for i := range(ret) {
+		ret[i] = sol.order[p][len(ret) - 1 - i]
+	}
+	return ret
+}
+
+func (sol *RiverSolution) nodeToStr(n *riverNode) string {
+	if (n.Ty != RIVER_ACTION) {
+		return ""
+	}
+	p := n.Player
+	title := RIVER_PLAYER_NAMES[p] + " to act"
+	if (n.History != "") {
+		title = n.History + ": " + title
+	}
+	ret := ""
+	strat := n.avgStrategy()
+	rows := ""
+	for _, a := range(sol.byStrength(p)) {
+		if ((n.reach[a] <= 1e-9) || (math.IsNaN(n.ev[a]))) {
+			continue
+		}
+		rows += fmt.Sprintf("%-8s %8.2f", comboIdToSolverStr(comboId(
+			sol.Combos[p][a][0], sol.Combos[p][a][1])), n.ev[a])
+		for k := range(n.Actions) {
+			rows += fmt.Sprintf(" %*.1f%%", len(n.Actions[k]) + 1,
+				strat[a][k] * 100.0)
+		}
+		rows += "\n"
+	}
+	if (rows != "") {
+		ret += "\n" + title + "\n"
+		ret += fmt.Sprintf("%-8s %8s", "combo", "ev")
+		for k := range(n.Actions) {
+			ret += fmt.Sprintf(" %*s", len(n.Actions[k]) + 2, n.Actions[k])
+		}
+		ret += "\n" + rows
+	}
+	for k := range(n.Children) {
+		ret += sol.nodeToStr(n.Children[k])
+	}
+	return ret
+}
+
+func (sol *RiverSolution) String() string {
+	cfg := sol.Config
+	ret := fmt.Sprintf("river %s, pot %s, stacks %s\n",
+		cfg.Board.ShortString(), chipsToStr(cfg.Pot),
+		chipsToStr(cfg.Stack))
+	ret += fmt.Sprintf("%d iterations over %d nodes, exploitability " +
+		"%.3f%% of the pot\n", cfg.Iterations, sol.numNodes,
+		sol.Exploitability * 100.0 / cfg.Pot)
+	ret += fmt.Sprintf("oop ev %.2f, ip ev %.2f\n", sol.Ev[0], sol.Ev[1])
+	ret += sol.nodeToStr(sol.Root)
+	return ret
+}
+
+/* Parse bet sizes given as percentages of the pot, like "50,100". */
+func parseBetSizes(str string) ([]float64, error) {
+	var ret []float64
+	if (strings.TrimSpace(str) == "") {
+		return ret, nil
+	}
+	toks := strings.Split(str, ",")
+	for i := range(toks) {
+		pct, err := strconv.ParseFloat(strings.TrimSpace(toks[i]), 64)
+		if ((err != nil) || (pct <= 0)) {
+			return nil, fmt.Errorf("invalid bet size '%s'.", toks[i])
+		}
+		ret = append(ret, pct / 100.0)
+	}
+	return ret, nil
+}
+
+func riverUsage(fs *flag.FlagSet) func() {
+	return func() {
+		fmt.Fprintf(os.Stderr,
+`%s river: solve the betting on the river between two ranges.
+
+Usage:
+%s river [options] -b [board] [oop range] [ip range]
+
+The out of position player acts first. Each player can check, or bet one of
+the sizes given by -bets, in percent of the pot. Facing a bet, a player can
+fold, call, or raise by the same percentages of the pot after calling, up to
+-raises times. The equilibrium is found with counterfactual regret
+minimization, and each combo's strategy and expected value is shown at every
+point where it can act. Expected values are each player's share of the pot,
+less what they put in on the river.
+
+Options:
+`, os.Args[0], os.Args[0])
+		fs.PrintDefaults()
+	}
+}
+
+func riverMain(args []string) {
+	fs := flag.NewFlagSet("river", flag.ExitOnError)
+	fs.Usage = riverUsage(fs)
+	var boardStr = fs.String("b", "", "the board")
+	var pot = fs.Float64("pot", 100, "the size of the pot")
+	var stack = fs.Float64("stack", 100, "what each player has behind")
+	var betStr = fs.String("bets", "50,100", "bet sizes, in percent of the pot")
+	var allIn = fs.Bool("allin", true, "allow moving all-in")
+	var maxRaises = fs.Int("raises", 1, "the most raises after a bet")
+	var iterations = fs.Int("i", DEFAULT_RIVER_ITERATIONS,
+		"number of iterations")
+	fs.Parse(args)
+	if (fs.NArg() != 2) {
+		fs.Usage()
+		os.Exit(1)
+	}
+	board, err := ParseCards(*boardStr, "the board")
+	if (err != nil) {
+		die(err)
+	}
+	bets, err := parseBetSizes(*betStr)
+	if (err != nil) {
+		die(err)
+	}
+	cfg := &RiverConfig { Board: board, Pot: *pot, Stack: *stack,
+		Bets: bets, AllIn: *allIn, MaxRaises: *maxRaises,
+		Iterations: *iterations }
+	for p := 0; p < 2; p++ {
+		cfg.Ranges[p], err = LoadRange(fs.Arg(p))
+		if (err != nil) {
+			die(err)
+		}
+	}
+	sol, err := SolveRiver(cfg)
+	if (err != nil) {
+		die(err)
+	}
+	fmt.Printf("%s", sol.String())
+}
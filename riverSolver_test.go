@@ -0,0 +1,136 @@
+/*
+ * Copyright 2011 Colin Patrick McCabe
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, version 2.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func newTestRiverConfig(t *testing.T, board string, oop string,
+		ip string) *RiverConfig {
+	b, err := ParseCards(board, "the board")
+	if (err != nil) {
+		t.Fatalf("%s", err.Error())
+	}
+	cfg := &RiverConfig { Board: b, Pot: 100, Stack: 100,
+		Bets: []float64 { 1.0 }, Iterations: 2000 }
+	for p, str := range([]string { oop, ip }) {
+		cfg.Ranges[p], err = ParseRange(str)
+		if (err != nil) {
+			t.Fatalf("%s", err.Error())
+		}
+	}
+	return cfg
+}
+
+/* Returns how often the player to act at a node takes an action there,
+ * counting each combo by how likely it is to get there.
+ */
+func riverActionFreq(sol *RiverSolution, n *riverNode, action string) float64 {
+	k := -1
+	for i := range(n.Actions) {
+		if (n.Actions[i] == action) {
+			k = i
+		}
+	}
+	strat := n.avgStrategy()
+	ret := 0.0
+	total := 0.0
+	for a := range(n.reach) {
+		ret += n.reach[a] * strat[a][k]
+		total += n.reach[a]
+	}
+	return ret / total
+}
+
+func TestRiverPolarized(t *testing.T) {
+	/* oop has the nuts or nothing, and ip has a hand which only beats a
+	 * bluff. With a pot sized bet, oop bets every nut hand and half as many
+	 * bluffs, and ip calls half the time.
+	 */
+	cfg := newTestRiverConfig(t, "AS KD 7C 4H 2S", "AA,98o", "KQo")
+	sol, err := SolveRiver(cfg)
+	if (err != nil) {
+		t.Fatalf("%s", err.Error())
+	}
+	if (sol.Exploitability > 0.01 * cfg.Pot) {
+		t.Errorf("expected to be close to the equilibrium, got %f",
+			sol.Exploitability)
+	}
+	if (math.Abs(sol.Ev[0] + sol.Ev[1] - cfg.Pot) > 1e-6) {
+		t.Errorf("expected the values to add up to the pot, got %f and %f",
+			sol.Ev[0], sol.Ev[1])
+	}
+	root := sol.Root
+	strat := root.avgStrategy()
+	bets := 0.0
+	bluffs := 0.0
+	for a := range(sol.Combos[0]) {
+		if (sol.Combos[0][a][0].val == ACE_VAL) {
+			bets += strat[a][1]
+		} else {
+			bluffs += strat[a][1]
+		}
+	}
+	if ((math.Abs(bets - 3) > 0.1) || (math.Abs(bluffs - 1.5) > 0.2)) {
+		t.Errorf("expected oop to bet 3 value combos and 1.5 bluffs, got " +
+			"%f and %f", bets, bluffs)
+	}
+	call := riverActionFreq(sol, root.Children[1], "call")
+	if (math.Abs(call - 0.5) > 0.05) {
+		t.Errorf("expected ip to call half the time, got %f", call)
+	}
+	if (sol.String() == "") {
+		t.Errorf("expected a description of the solution")
+	}
+}
+
+func TestRiverConverges(t *testing.T) {
+	cfg := newTestRiverConfig(t, "KS 7D 2C 9H 3S", "top30%", "top40%")
+	cfg.Bets = []float64 { 0.5, 1.0 }
+	cfg.AllIn = true
+	cfg.MaxRaises = 1
+	cfg.Iterations = 10
+	few, err := SolveRiver(cfg)
+	if (err != nil) {
+		t.Fatalf("%s", err.Error())
+	}
+	cfg.Iterations = 200
+	many, err := SolveRiver(cfg)
+	if (err != nil) {
+		t.Fatalf("%s", err.Error())
+	}
+	if ((many.Exploitability >= few.Exploitability) ||
+			(many.Exploitability > 0.01 * cfg.Pot)) {
+		t.Errorf("expected exploitability to fall with more iterations, " +
+			"got %f and %f", few.Exploitability, many.Exploitability)
+	}
+}
+
+func TestRiverErrors(t *testing.T) {
+	cfg := newTestRiverConfig(t, "AS KD 7C 4H", "AA", "KK")
+	_, err := SolveRiver(cfg)
+	if (err == nil) {
+		t.Errorf("expected an error for a four card board")
+	}
+	cfg = newTestRiverConfig(t, "AS KD 7C 4H 2S", "AhAd", "AhAd")
+	_, err = SolveRiver(cfg)
+	if (err == nil) {
+		t.Errorf("expected an error for ranges which block each other")
+	}
+}
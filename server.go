@@ -0,0 +1,209 @@
+/*
+ * Copyright 2011 Colin Patrick McCabe
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, version 2.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"time"
+)
+
+// The largest request body we are willing to read.
+const MAX_REQUEST_SZ = 64 * 1024
+
+/* An OddsServer answers OddsRequests over HTTP.
+ *
+ * At most cap(sem) requests are calculated at once. Requests that can't get a
+ * slot before their timeout expires are turned away.
+ */
+type OddsServer struct {
+	deck *CardBag
+	numCsp int
+	timeout time.Duration
+	sem chan bool
+	cache *ResultCache
+}
+
+func NewOddsServer(numCsp int, timeout time.Duration,
+		maxConcurrent int, cache *ResultCache) *OddsServer {
+	srv := new(OddsServer)
+	srv.deck = Make52CardBag()
+	srv.numCsp = numCsp
+	srv.timeout = timeout
+	srv.sem = make(chan bool, maxConcurrent)
+	srv.cache = cache
+	return srv
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+func writeJson(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJson(w, status, &errorResponse { err.Error() })
+}
+
+func (srv *OddsServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if (r.Method != "POST") {
+		w.Header().Set("Allow", "POST")
+		writeError(w, http.StatusMethodNotAllowed,
+			fmt.Errorf("expected a POST request, but got %s.", r.Method))
+		return
+	}
+	var req OddsRequest
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, MAX_REQUEST_SZ))
+	dec.DisallowUnknownFields()
+	err := dec.Decode(&req)
+	if (err != nil) {
+		writeError(w, http.StatusBadRequest,
+			fmt.Errorf("unable to parse request: %s", err.Error()))
+		return
+	}
+	sc, err := req.Scenario()
+	if (err != nil) {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	key := sc.CanonicalKey()
+	resp, ok := srv.cache.Get(key)
+	if (ok) {
+		writeJson(w, http.StatusOK, resp)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), srv.timeout)
+	defer cancel()
+	select {
+	case srv.sem <- true:
+		defer func() { <-srv.sem }()
+	case <-ctx.Done():
+		writeError(w, http.StatusServiceUnavailable,
+			fmt.Errorf("the server is too busy to handle this request."))
+		return
+	}
+	resp, err = sc.Run(ctx, srv.deck, srv.numCsp)
+	if (errors.Is(err, context.DeadlineExceeded)) {
+		writeError(w, http.StatusGatewayTimeout,
+			fmt.Errorf("the calculation took longer than %s.", srv.timeout))
+		return
+	} else if (err != nil) {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	err = srv.cache.Put(key, resp)
+	if (err != nil) {
+		log.Printf("failed to store result: %s", err.Error())
+	}
+	writeJson(w, http.StatusOK, resp)
+}
+
+/* Settle a ShowdownRequest POSTed as JSON. This is quick, so unlike odds
+ * requests, it isn't limited or cached.
+ */
+func serveShowdown(w http.ResponseWriter, r *http.Request) {
+	if (r.Method != "POST") {
+		w.Header().Set("Allow", "POST")
+		writeError(w, http.StatusMethodNotAllowed,
+			fmt.Errorf("expected a POST request, but got %s.", r.Method))
+		return
+	}
+	var req ShowdownRequest
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, MAX_REQUEST_SZ))
+	dec.DisallowUnknownFields()
+	err := dec.Decode(&req)
+	if (err != nil) {
+		writeError(w, http.StatusBadRequest,
+			fmt.Errorf("unable to parse request: %s", err.Error()))
+		return
+	}
+	res, err := ResolveShowdown(&req)
+	if (err != nil) {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	writeJson(w, http.StatusOK, res)
+}
+
+func serveUsage(fs *flag.FlagSet) func() {
+	return func() {
+		fmt.Fprintf(os.Stderr,
+`%s serve: answer odds requests over HTTP.
+
+Requests are POSTed to /odds as JSON objects, for example:
+{"hole": "KS QS", "board": "AS 3S 5S", "opponents": ["AD AC"],
+ "game": "holdem", "mode": "montecarlo", "samples": 10000, "seed": 1}
+
+Only "hole" is required. "mode" may be "exact" (the default) or
+"montecarlo". The response holds the chance of making each type of hand.
+
+Showdowns to settle are POSTed to /showdown, for example:
+{"board": "AS 7D 8C 2H 2S", "button": 0, "oddChip": "button",
+ "players": [{"name": "alice", "hole": "KS KD", "contribution": 100},
+             {"name": "bob", "hole": "QH QC", "contribution": 40},
+             {"name": "carol", "contribution": 20, "folded": true}]}
+
+The response lists each pot, who won it and with what, and what each player
+won in total.
+
+Options:
+`, os.Args[0])
+		fs.PrintDefaults()
+	}
+}
+
+func serveMain(args []string) {
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	fs.Usage = serveUsage(fs)
+	var listen = fs.String("listen", ":8080", "the address to listen on")
+	var numCsp = fs.Int("g", 3, "number of goprocs per request")
+	var timeout = fs.Duration("timeout", 30 * time.Second,
+		"the longest we will spend on a single request")
+	var maxConcurrent = fs.Int("max-concurrent", 4,
+		"the most requests we will calculate at once")
+	var cacheSize = fs.Int("cache-size", DEFAULT_CACHE_SZ,
+		"the number of results to keep in memory (0 disables the cache)")
+	var cacheFile = fs.String("cache-file", "",
+		"a file in which to keep every result across restarts")
+	fs.Parse(args)
+	if (*maxConcurrent < 1) {
+		die(fmt.Errorf("-max-concurrent must be at least 1."))
+	}
+	cache, err := OpenResultCache(*cacheSize, *cacheFile)
+	if (err != nil) {
+		die(err)
+	}
+	defer cache.Close()
+
+	mux := http.NewServeMux()
+	mux.Handle("/odds", NewOddsServer(*numCsp, *timeout, *maxConcurrent,
+		cache))
+	mux.HandleFunc("/showdown", serveShowdown)
+	log.Printf("listening on %s", *listen)
+	log.Fatal(http.ListenAndServe(*listen, mux))
+}
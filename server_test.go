@@ -0,0 +1,110 @@
+/*
+ * Copyright 2011 Colin Patrick McCabe
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, version 2.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func postOdds(t *testing.T, srv *OddsServer, body string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest("POST", "/odds", strings.NewReader(body))
+	w := httptest.NewRecorder()
+	srv.ServeHTTP(w, r)
+	return w
+}
+
+func TestServer1(t *testing.T) {
+	srv := NewOddsServer(2, 10 * time.Second, 1, nil)
+	w := postOdds(t, srv, `{"hole": "KS QS", "board": "AS 3S 5S"}`)
+	if (w.Code != http.StatusOK) {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var resp OddsResponse
+	err := json.Unmarshal(w.Body.Bytes(), &resp)
+	if (err != nil) {
+		t.Fatalf("failed to parse response: %s", err.Error())
+	}
+	expected := "99.81% chance of a flush\n0.19% chance of a straight flush\n"
+	if (resp.Text != expected) {
+		t.Errorf("expected:%s. got: %s\n", expected, resp.Text)
+	}
+	if ((resp.Mode != MODE_EXACT) || (resp.Total != 1081)) {
+		t.Errorf("expected an exact result over 1081 boards, got %s " +
+			"over %d", resp.Mode, resp.Total)
+	}
+}
+
+func TestServerBadRequests(t *testing.T) {
+	srv := NewOddsServer(2, 10 * time.Second, 1, nil)
+	bad := []string {
+		`{"hole": "KS QX"}`,
+		`{"hole": "KS QS", "board": "KS 3S 5S"}`,
+		`{"hole": "KS QS", "board": "AS 3S"}`,
+		`{"hole": "KS QS", "opponents": ["QS 2C"]}`,
+		`{"hole": "KS QS", "game": "omaha"}`,
+		`{"hole": "KS QS", "mode": "guess"}`,
+		`{"hole": "KS QS", "nonsense": 1}`,
+		`not json`,
+	}
+	for i := range(bad) {
+		w := postOdds(t, srv, bad[i])
+		if (w.Code != http.StatusBadRequest) {
+			t.Errorf("expected status 400 for %s, got %d", bad[i], w.Code)
+		}
+	}
+}
+
+func TestServerTimeout(t *testing.T) {
+	srv := NewOddsServer(2, time.Millisecond, 1, nil)
+	w := postOdds(t, srv, `{"hole": "KS QS"}`)
+	if (w.Code != http.StatusGatewayTimeout) {
+		t.Errorf("expected status 504, got %d", w.Code)
+	}
+}
+
+func TestServerShowdown(t *testing.T) {
+	body := `{"board": "AS 7D 8C 2H 3S", "players": [
+		{"name": "alice", "hole": "KS KD", "contribution": 100},
+		{"name": "bob", "contribution": 50, "folded": true}]}`
+	r := httptest.NewRequest("POST", "/showdown", strings.NewReader(body))
+	w := httptest.NewRecorder()
+	serveShowdown(w, r)
+	if (w.Code != http.StatusOK) {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var res ShowdownResult
+	err := json.Unmarshal(w.Body.Bytes(), &res)
+	if (err != nil) {
+		t.Fatalf("failed to parse response: %s", err.Error())
+	}
+	if ((len(res.Payouts) != 2) || (res.Payouts[0].Won != 150)) {
+		t.Errorf("expected alice to win 150, got %s", w.Body.String())
+	}
+
+	r = httptest.NewRequest("POST", "/showdown",
+		strings.NewReader(`{"board": "AS 7D 8C 2H 3S", "players": []}`))
+	w = httptest.NewRecorder()
+	serveShowdown(w, r)
+	if (w.Code != http.StatusBadRequest) {
+		t.Errorf("expected status 400, got %d: %s", w.Code, w.Body.String())
+	}
+}
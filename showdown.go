@@ -0,0 +1,453 @@
+/*
+ * Copyright 2011 Colin Patrick McCabe
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, version 2.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+/* Rules for who gets the odd chips when a pot can't be split evenly.
+ *
+ * ODD_CHIP_BUTTON gives them out one at a time to the winners, starting with
+ * the first winner to the left of the button.
+ *
+ * ODD_CHIP_SUIT gives them out one at a time to the winners, starting with
+ * the winner holding the highest hole card. Cards of the same value are
+ * ranked by suit: spades, then hearts, then diamonds, then clubs.
+ */
+const (
+	ODD_CHIP_BUTTON = "button"
+	ODD_CHIP_SUIT = "suit"
+)
+
+/* A player at showdown. Seats are in order around the table. Folded players
+ * may have put chips in the pot, but can't win any of it, and their hole
+ * cards aren't needed.
+ */
+type ShowdownPlayer struct {
+	Name string `json:"name"`
+	Hole string `json:"hole"`
+	Contribution int64 `json:"contribution"`
+	Folded bool `json:"folded"`
+}
+
+/* A showdown to settle. Button is the index of the player on the button. */
+type ShowdownRequest struct {
+	Board string `json:"board"`
+	Button int `json:"button"`
+	OddChip string `json:"oddChip"`
+	Players []ShowdownPlayer `json:"players"`
+}
+
+/* A winner of one pot, and the best five cards they won it with. */
+type PotWinner struct {
+	Name string `json:"name"`
+	Amount int64 `json:"amount"`
+	Hand string `json:"hand"`
+	Description string `json:"description"`
+	Cards string `json:"cards"`
+}
+
+type PotResult struct {
+	Name string `json:"name"`
+	Amount int64 `json:"amount"`
+	Eligible []string `json:"eligible"`
+	Winners []PotWinner `json:"winners"`
+}
+
+/* How much each player won in total. Net is what they won, less what they
+ * put in.
+ */
+type Payout struct {
+	Name string `json:"name"`
+	Won int64 `json:"won"`
+	Net int64 `json:"net"`
+}
+
+type ShowdownResult struct {
+	Pots []*PotResult `json:"pots"`
+	Payouts []Payout `json:"payouts"`
+}
+
+/* Everything we know about the players once their cards are parsed. The
+ * ranks from holeRank decide who wins. Each winner's best hand is only made
+ * when we need to describe it.
+ */
+type showdownTable struct {
+	players []ShowdownPlayer
+	holes []CardSlice
+	ranks []int
+	best []*Hand
+	button int
+	oddChip string
+}
+
+func (req *ShowdownRequest) validate() (*showdownTable, CardSlice, error) {
+	// Copy the players, so that filling in their names doesn't change the
+	// request.
+	sd := &showdownTable {
+		players: append([]ShowdownPlayer {}, req.Players...),
+		button: req.Button, oddChip: req.OddChip }
+	if (sd.oddChip == "") {
+		sd.oddChip = ODD_CHIP_BUTTON
+	}
+	if ((sd.oddChip != ODD_CHIP_BUTTON) && (sd.oddChip != ODD_CHIP_SUIT)) {
+		return nil, nil, fmt.Errorf("the odd chip rule must be '%s' or " +
+			"'%s', not '%s'.", ODD_CHIP_BUTTON, ODD_CHIP_SUIT, sd.oddChip)
+	}
+	board, err := ParseCards(req.Board, "the board")
+	if (err != nil) {
+		return nil, nil, err
+	}
+	if (len(board) != BOARD_MAX) {
+		return nil, nil, fmt.Errorf("the board must have %d cards at " +
+			"showdown, but it has %d.", BOARD_MAX, len(board))
+	}
+	if (len(sd.players) < 2) {
+		return nil, nil, fmt.Errorf("there must be at least two players.")
+	}
+	if ((sd.button < 0) || (sd.button >= len(sd.players))) {
+		return nil, nil, fmt.Errorf("the button must be at one of the %d " +
+			"seats.", len(sd.players))
+	}
+	known := append(CardSlice {}, board...)
+	names := make(map[string] bool)
+	sd.holes = make([]CardSlice, len(sd.players))
+	sd.ranks = make([]int, len(sd.players))
+	sd.best = make([]*Hand, len(sd.players))
+	var buf [SPREAD_MAX]*Card
+	numLive := 0
+	for i := range(sd.players) {
+		p := &sd.players[i]
+		if (p.Name == "") {
+			p.Name = fmt.Sprintf("seat %d", i + 1)
+		}
+		if (names[p.Name]) {
+			return nil, nil, fmt.Errorf("more than one player is called " +
+				"'%s'.", p.Name)
+		}
+		names[p.Name] = true
+		if (p.Contribution < 0) {
+			return nil, nil, fmt.Errorf("%s can't put a negative amount in " +
+				"the pot.", p.Name)
+		}
+		if (p.Folded) {
+			continue
+		}
+		numLive++
+		sd.holes[i], err = ParseCards(p.Hole, "the hole cards of " + p.Name)
+		if (err != nil) {
+			return nil, nil, err
+		}
+		if (len(sd.holes[i]) != HOLE_SZ) {
+			return nil, nil, fmt.Errorf("%s must have %d hole cards, not %d.",
+				p.Name, HOLE_SZ, len(sd.holes[i]))
+		}
+		known = append(known, sd.holes[i]...)
+		sd.ranks[i] = holeRank(sd.holes[i], board, buf[:0])
+	}
+	if (numLive == 0) {
+		return nil, nil, fmt.Errorf("everyone has folded.")
+	}
+	dupe := known.HasDuplicates()
+	if (dupe != nil) {
+		return nil, nil, fmt.Errorf("The card %s appears more than once! " +
+			"That is not possible.", dupe)
+	}
+	return sd, board, nil
+}
+
+/* Ranks suits for the odd chip: clubs, diamonds, hearts, then spades. */
+func oddChipSuitRank(suit int) int {
+	switch (suit) {
+	case CLUBS:
+		return 0
+	case DIAMONDS:
+		return 1
+	case HEARTS:
+		return 2
+	}
+	return 3
+}
+
+/* Returns true if card a beats card b for the odd chip. */
+func oddChipCardBeats(a *Card, b *Card) bool {
+	if (a.val != b.val) {
+		return a.val > b.val
+	}
+	return oddChipSuitRank(a.suit) > oddChipSuitRank(b.suit)
+}
+
+func (sd *showdownTable) highHoleCard(i int) *Card {
+	h := sd.holes[i]
+	if (oddChipCardBeats(h[1], h[0])) {
+		return h[1]
+	}
+	return h[0]
+}
+
+/* Sort the winners of a pot into the order they get odd chips in. */
+func (sd *showdownTable) oddChipOrder(winners []int) {
+	n := len(sd.players)
+	sort.Slice(winners, func(a, b int) bool {
+		if (sd.oddChip == ODD_CHIP_SUIT) {
+			return oddChipCardBeats(sd.highHoleCard(winners[a]),
+				sd.highHoleCard(winners[b]))
+		}
+		da := (winners[a] - sd.button - 1 + n) % n
+		db := (winners[b] - sd.button - 1 + n) % n
+		return da < db
+	})
+}
+
+func sameInts(a []int, b []int) bool {
+	if (len(a) != len(b)) {
+		return false
+	}
+	for i := range(a) {
+		if (a[i] != b[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func minInt64(a int64, b int64) int64 {
+	if (a < b) {
+		return a
+	}
+	return b
+}
+
+/* Divide the chips into a main pot and side pots. Each pot is contested by
+ * the players who haven't folded and who put in at least as much as
+ * everyone else who contributed to it. Chips that nobody left in the hand
+ * could have matched go into the pot below them.
+ */
+func (sd *showdownTable) makePots() ([]*PotResult, [][]int) {
+	var levels []int64
+	seen := make(map[int64] bool)
+	for i := range(sd.players) {
+		c := sd.players[i].Contribution
+		if ((c > 0) && (!seen[c])) {
+			seen[c] = true
+			levels = append(levels, c)
+		}
+	}
+	sort.Slice(levels, func(a, b int) bool { return levels[a] < levels[b] })
+
+	var pots []*PotResult
+	var eligible [][]int
+	prev := int64(0)
+	for l := range(levels) {
+		amount := int64(0)
+		var elig []int
+		for i := range(sd.players) {
+			p := &sd.players[i]
+			amount += minInt64(p.Contribution, levels[l]) -
+				minInt64(p.Contribution, prev)
+			if ((!p.Folded) && (p.Contribution >= levels[l])) {
+				elig = append(elig, i)
+			}
+		}
+		prev = levels[l]
+		last := len(pots) - 1
+		if ((last >= 0) && ((len(elig) == 0) ||
+				sameInts(elig, eligible[last]))) {
+			pots[last].Amount += amount
+			continue
+		}
+		pots = append(pots, &PotResult { Amount: amount })
+		eligible = append(eligible, elig)
+	}
+	/* If only folded players put anything in at the lowest levels, nobody
+	 * can win that pot on its own. Those chips go to the next pot up.
+	 */
+	for ; (len(pots) > 1) && (eligible[0] == nil); {
+		pots[1].Amount += pots[0].Amount
+		pots = pots[1:]
+		eligible = eligible[1:]
+	}
+	for i := range(pots) {
+		if (i == 0) {
+			pots[i].Name = "main pot"
+		} else {
+			pots[i].Name = fmt.Sprintf("side pot %d", i)
+		}
+		for j := range(eligible[i]) {
+			pots[i].Eligible = append(pots[i].Eligible,
+				sd.players[eligible[i][j]].Name)
+		}
+	}
+	return pots, eligible
+}
+
+/* Settle a showdown. Each pot goes to the best hand among the players
+ * eligible for it. Tied players split the pot, and the odd chips are given
+ * out according to the odd chip rule.
+ */
+func ResolveShowdown(req *ShowdownRequest) (*ShowdownResult, error) {
+	sd, board, err := req.validate()
+	if (err != nil) {
+		return nil, err
+	}
+	pots, eligible := sd.makePots()
+	won := make([]int64, len(sd.players))
+	for p := range(pots) {
+		elig := eligible[p]
+		if (elig == nil) {
+			return nil, fmt.Errorf("nobody left in the hand put anything " +
+				"in the pot.")
+		}
+		var winners []int
+		for j := range(elig) {
+			i := elig[j]
+			if (len(winners) == 0) {
+				winners = []int { i }
+				continue
+			}
+			r := sd.ranks[winners[0]]
+			if (sd.ranks[i] > r) {
+				winners = []int { i }
+			} else if (sd.ranks[i] == r) {
+				winners = append(winners, i)
+			}
+		}
+		sd.oddChipOrder(winners)
+		share := pots[p].Amount / int64(len(winners))
+		odd := pots[p].Amount % int64(len(winners))
+		for j := range(winners) {
+			i := winners[j]
+			amount := share
+			if (int64(j) < odd) {
+				amount++
+			}
+			won[i] += amount
+			if (sd.best[i] == nil) {
+				sd.best[i] = bestHandWith(sd.holes[i], board)
+			}
+			pots[p].Winners = append(pots[p].Winners, PotWinner {
+				sd.players[i].Name, amount, HandTyToStr(sd.best[i].ty),
+				sd.best[i].Describe(), sd.best[i].BestFive().ShortString() })
+		}
+	}
+	ret := &ShowdownResult { Pots: pots }
+	for i := range(sd.players) {
+		ret.Payouts = append(ret.Payouts, Payout { sd.players[i].Name,
+			won[i], won[i] - sd.players[i].Contribution })
+	}
+	return ret, nil
+}
+
+func (res *ShowdownResult) String() string {
+	ret := ""
+	for p := range(res.Pots) {
+		pot := res.Pots[p]
+		ret += fmt.Sprintf("%s: %d (%s)\n", pot.Name, pot.Amount,
+			strings.Join(pot.Eligible, ", "))
+		for w := range(pot.Winners) {
+			pw := pot.Winners[w]
+			ret += fmt.Sprintf("  %s wins %d with %s: %s\n", pw.Name,
+				pw.Amount, uncapitalize(pw.Description), pw.Cards)
+		}
+	}
+	ret += "payouts:\n"
+	for i := range(res.Payouts) {
+		ret += fmt.Sprintf("  %-12s won %6d, net %+d\n", res.Payouts[i].Name,
+			res.Payouts[i].Won, res.Payouts[i].Net)
+	}
+	return ret
+}
+
+/* Parse a player given on the command line, like "alice:AS KS:100" or
+ * "bob:fold:50".
+ */
+func parseShowdownPlayer(str string) (ShowdownPlayer, error) {
+	var p ShowdownPlayer
+	parts := strings.Split(str, ":")
+	if (len(parts) != 3) {
+		return p, fmt.Errorf("can't understand the player '%s'. Expected " +
+			"name:cards:contribution.", str)
+	}
+	p.Name = strings.TrimSpace(parts[0])
+	p.Hole = strings.TrimSpace(parts[1])
+	if (strings.ToLower(p.Hole) == "fold") {
+		p.Folded = true
+		p.Hole = ""
+	}
+	var err error
+	p.Contribution, err = strconv.ParseInt(strings.TrimSpace(parts[2]), 10, 64)
+	if (err != nil) {
+		return p, fmt.Errorf("can't understand the contribution of '%s'.",
+			str)
+	}
+	return p, nil
+}
+
+func showdownUsage(fs *flag.FlagSet) func() {
+	return func() {
+		fmt.Fprintf(os.Stderr,
+`%s showdown: settle the pot at showdown.
+
+Usage:
+%s showdown -b [board] [options] [player] [player] ...
+
+Players are given in seat order, as name:cards:contribution, where
+contribution is everything the player put in the pot during the hand. Use
+"fold" instead of cards for players who folded. For example:
+%s showdown -b "AS 7D 8C 2H 2S" "alice:KS KD:100" "bob:QH QC:40" \
+    "carol:fold:20"
+
+Side pots are worked out from the contributions. Ties split the pot, and the
+odd chips are given out according to -odd-chip.
+
+Options:
+`, os.Args[0], os.Args[0], os.Args[0])
+		fs.PrintDefaults()
+	}
+}
+
+func showdownMain(args []string) {
+	fs := flag.NewFlagSet("showdown", flag.ExitOnError)
+	fs.Usage = showdownUsage(fs)
+	var board = fs.String("b", "", "the board")
+	var button = fs.Int("button", 0, "the seat of the button, counting " +
+		"from 0")
+	var oddChip = fs.String("odd-chip", ODD_CHIP_BUTTON, "who gets odd " +
+		"chips: '" + ODD_CHIP_BUTTON + "' for the first winner left of the " +
+		"button, or '" + ODD_CHIP_SUIT + "' for the highest card by suit")
+	fs.Parse(args)
+	req := &ShowdownRequest { Board: *board, Button: *button,
+		OddChip: *oddChip }
+	for i := 0; i < fs.NArg(); i++ {
+		p, err := parseShowdownPlayer(fs.Arg(i))
+		if (err != nil) {
+			die(err)
+		}
+		req.Players = append(req.Players, p)
+	}
+	res, err := ResolveShowdown(req)
+	if (err != nil) {
+		die(err)
+	}
+	fmt.Printf("%s", res.String())
+}
@@ -0,0 +1,152 @@
+/*
+ * Copyright 2011 Colin Patrick McCabe
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, version 2.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package main
+
+import (
+	"testing"
+)
+
+func resolve(t *testing.T, req *ShowdownRequest) *ShowdownResult {
+	res, err := ResolveShowdown(req)
+	if (err != nil) {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	return res
+}
+
+func expectPayouts(t *testing.T, res *ShowdownResult, won ...int64) {
+	total := int64(0)
+	for i := range(res.Payouts) {
+		total += res.Payouts[i].Won
+		if (res.Payouts[i].Won != won[i]) {
+			t.Errorf("expected %s to win %d, got %d:\n%s",
+				res.Payouts[i].Name, won[i], res.Payouts[i].Won, res)
+		}
+	}
+	for i := range(res.Pots) {
+		total -= res.Pots[i].Amount
+	}
+	if (total != 0) {
+		t.Errorf("expected every chip to be paid out:\n%s", res)
+	}
+}
+
+func TestSidePots(t *testing.T) {
+	// carol is all in for 30 with the best hand, bob is all in for 60 with
+	// the next best, and alice covers them both.
+	res := resolve(t, &ShowdownRequest { Board: "AS 7D 8C 2H 3S",
+		Players: []ShowdownPlayer {
+			{ "alice", "KS KD", 100, false },
+			{ "bob", "QH QC", 60, false },
+			{ "carol", "AH AC", 30, false },
+			{ "dave", "", 10, true },
+		} })
+	if (len(res.Pots) != 3) {
+		t.Fatalf("expected 3 pots, got:\n%s", res)
+	}
+	// The main pot is 30 from each live player, plus dave's 10.
+	if ((res.Pots[0].Amount != 100) || (res.Pots[1].Amount != 60) ||
+			(res.Pots[2].Amount != 40)) {
+		t.Errorf("expected pots of 100, 60 and 40, got:\n%s", res)
+	}
+	if ((len(res.Pots[2].Eligible) != 1) ||
+			(res.Pots[2].Winners[0].Name != "alice")) {
+		t.Errorf("expected alice to get back her uncalled 40, got:\n%s", res)
+	}
+	expectPayouts(t, res, 100, 0, 100, 0)
+	if ((res.Pots[0].Winners[0].Name != "carol") ||
+			(res.Pots[0].Winners[0].Hand != HandTyToStr(THREE_OF_A_KIND))) {
+		t.Errorf("expected carol to win with three of a kind, got:\n%s", res)
+	}
+	if (len(res.Pots[0].Winners[0].Cards) != 14) {
+		t.Errorf("expected five cards, got '%s'", res.Pots[0].Winners[0].Cards)
+	}
+}
+
+func TestSplitPots(t *testing.T) {
+	// Everyone plays the straight on the board, and the 101 chips don't
+	// split evenly.
+	players := []ShowdownPlayer {
+		{ "a", "2C 4D", 33, false },
+		{ "b", "2D 5H", 33, false },
+		{ "c", "9C 9D", 33, false },
+		{ "d", "", 2, true },
+	}
+	res := resolve(t, &ShowdownRequest { Board: "AS KD QC JH 10S",
+		Button: 1, Players: players })
+	// The first two winners left of the button get the odd chips.
+	expectPayouts(t, res, 34, 33, 34, 0)
+
+	res = resolve(t, &ShowdownRequest { Board: "AS KD QC JH 10S",
+		Button: 2, Players: players })
+	expectPayouts(t, res, 34, 34, 33, 0)
+
+	// c has the highest hole card, then b, whose 5 beats a's 4.
+	res = resolve(t, &ShowdownRequest { Board: "AS KD QC JH 10S",
+		Button: 1, OddChip: ODD_CHIP_SUIT, Players: players })
+	expectPayouts(t, res, 33, 34, 34, 0)
+
+	// With cards of the same value, the suit decides.
+	res = resolve(t, &ShowdownRequest { Board: "AS KD QC JH 10S",
+		OddChip: ODD_CHIP_SUIT, Players: []ShowdownPlayer {
+			{ "a", "9H 2C", 5, false },
+			{ "b", "9S 2D", 5, false },
+		} })
+	if ((len(res.Pots[0].Winners) != 2) ||
+			(res.Pots[0].Winners[0].Name != "b")) {
+		t.Errorf("expected b to get the odd chip with the 9 of spades:\n%s",
+			res)
+	}
+
+	// Players without names are named after their seats, but only in the
+	// result.
+	req := &ShowdownRequest { Board: "AS KD QC JH 10S", Players:
+		[]ShowdownPlayer { { "", "9H 2C", 5, false },
+			{ "", "9S 2D", 5, false } } }
+	res = resolve(t, req)
+	if ((res.Payouts[0].Name != "seat 1") || (req.Players[0].Name != "")) {
+		t.Errorf("expected seat 1 to be named in the result but not in " +
+			"the request, got '%s' and '%s'", res.Payouts[0].Name,
+			req.Players[0].Name)
+	}
+}
+
+func TestShowdownErrors(t *testing.T) {
+	bad := []*ShowdownRequest {
+		{ Board: "AS KD QC JH", Players: []ShowdownPlayer {
+			{ "a", "2C 4D", 10, false }, { "b", "2D 5H", 10, false } } },
+		{ Board: "AS KD QC JH 10S", Players: []ShowdownPlayer {
+			{ "a", "2C 4D", 10, false } } },
+		{ Board: "AS KD QC JH 10S", Players: []ShowdownPlayer {
+			{ "a", "2C 4D", 10, false }, { "b", "2C 5H", 10, false } } },
+		{ Board: "AS KD QC JH 10S", Players: []ShowdownPlayer {
+			{ "a", "2C 4D", 10, false }, { "a", "2D 5H", 10, false } } },
+		{ Board: "AS KD QC JH 10S", Players: []ShowdownPlayer {
+			{ "a", "", 10, true }, { "b", "", 10, true } } },
+		{ Board: "AS KD QC JH 10S", OddChip: "random",
+			Players: []ShowdownPlayer {
+				{ "a", "2C 4D", 10, false }, { "b", "2D 5H", 10, false } } },
+		{ Board: "AS KD QC JH 10S", Button: 2, Players: []ShowdownPlayer {
+			{ "a", "2C 4D", 10, false }, { "b", "2D 5H", 10, false } } },
+	}
+	for i := range(bad) {
+		_, err := ResolveShowdown(bad[i])
+		if (err == nil) {
+			t.Errorf("expected an error from request %d", i)
+		}
+	}
+}
@@ -0,0 +1,234 @@
+/*
+ * Copyright 2011 Colin Patrick McCabe
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, version 2.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"math"
+	"math/rand"
+	"os"
+)
+
+/* How many standard errors either side of the mean a 95% confidence
+ * interval covers.
+ */
+const CONFIDENCE_95 = 1.96
+
+/* Settings for a simulation. Every hand is played with each player starting
+ * on Stack chips, so the hands are independent of each other. The button
+ * moves one seat each hand.
+ */
+type SimConfig struct {
+	Game GameConfig
+	Stack int64
+	Hands int
+	Seed int64
+	NumWorkers int
+}
+
+/* Chips won by one player over a number of hands. Sums are kept in chips,
+ * rather than big blinds, so that the result doesn't depend on the order
+ * the hands were played in.
+ */
+type simTotals struct {
+	sum []int64
+	sumSq []int64
+}
+
+func newSimTotals(n int) *simTotals {
+	return &simTotals { make([]int64, n), make([]int64, n) }
+}
+
+func (st *simTotals) add(rhs *simTotals) {
+	for i := range(st.sum) {
+		st.sum[i] += rhs.sum[i]
+		st.sumSq[i] += rhs.sumSq[i]
+	}
+}
+
+type SimPlayerResult struct {
+	Name string
+	BbPer100 float64
+	Confidence float64
+}
+
+type SimResult struct {
+	Hands int
+	Players []SimPlayerResult
+}
+
+/* Returns the seed for hand number n. Neighboring seeds are mixed up, so
+ * that runs with seeds 1 and 2 don't share any hands.
+ */
+func handSeed(seed int64, n int) int64 {
+	x := uint64(seed) * 0x9E3779B97F4A7C15 + uint64(n)
+	x = (x ^ (x >> 30)) * 0xBF58476D1CE4E5B9
+	x = (x ^ (x >> 27)) * 0x94D049BB133111EB
+	return int64(x ^ (x >> 31))
+}
+
+func simWorker(cfg *SimConfig, names []string, players []Player, w int,
+		totals *simTotals) error {
+	n := len(players)
+	stacks := make([]int64, n)
+	for h := w; h < cfg.Hands; h += cfg.NumWorkers {
+		rng := rand.New(rand.NewSource(handSeed(cfg.Seed, h)))
+		for i := range(stacks) {
+			stacks[i] = cfg.Stack
+		}
+		g, err := NewGame(cfg.Game, names, stacks, h % n, ShuffledDeck(rng))
+		if (err != nil) {
+			return err
+		}
+		err = PlayHand(g, players, rng)
+		if (err != nil) {
+			return err
+		}
+		for i := range(g.Seats) {
+			net := g.Seats[i].Stack - cfg.Stack
+			totals.sum[i] += net
+			totals.sumSq[i] += net * net
+		}
+	}
+	return nil
+}
+
+/* Play the bots against each other, and work out how many big blinds each
+ * one wins per 100 hands. The result is the same whatever the number of
+ * workers.
+ */
+func Simulate(cfg *SimConfig, names []string,
+		players []Player) (*SimResult, error) {
+	if (len(names) != len(players)) {
+		return nil, fmt.Errorf("there are %d names, but %d players.",
+			len(names), len(players))
+	}
+	if (cfg.Hands < 2) {
+		return nil, fmt.Errorf("at least two hands must be played.")
+	}
+	if (cfg.NumWorkers < 1) {
+		cfg.NumWorkers = 1
+	}
+	totals := newSimTotals(len(players))
+	workerTotals := make([]*simTotals, cfg.NumWorkers)
+	errs := make([]error, cfg.NumWorkers)
+	done := make(chan bool)
+	for w := 0; w < cfg.NumWorkers; w++ {
+		workerTotals[w] = newSimTotals(len(players))
+		go func(w int) {
+			errs[w] = simWorker(cfg, names, players, w, workerTotals[w])
+			done <- true
+		}(w)
+	}
+	for w := 0; w < cfg.NumWorkers; w++ {
+		<-done
+	}
+	for w := 0; w < cfg.NumWorkers; w++ {
+		if (errs[w] != nil) {
+			return nil, errs[w]
+		}
+		totals.add(workerTotals[w])
+	}
+	res := &SimResult { Hands: cfg.Hands }
+	n := float64(cfg.Hands)
+	bb := float64(cfg.Game.BigBlind)
+	for i := range(players) {
+		mean := float64(totals.sum[i]) / n
+		variance := (float64(totals.sumSq[i]) - mean * mean * n) / (n - 1)
+		stdErr := math.Sqrt(math.Max(variance, 0) / n)
+		res.Players = append(res.Players, SimPlayerResult { names[i],
+			mean * 100.0 / bb, CONFIDENCE_95 * stdErr * 100.0 / bb })
+	}
+	return res, nil
+}
+
+func (res *SimResult) String() string {
+	ret := fmt.Sprintf("%d hands\n", res.Hands)
+	ret += fmt.Sprintf("%-20s %10s %12s\n", "player", "bb/100", "95% conf.")
+	for i := range(res.Players) {
+		p := res.Players[i]
+		ret += fmt.Sprintf("%-20s %10.2f %12s\n", p.Name, p.BbPer100,
+			fmt.Sprintf("+/- %.2f", p.Confidence))
+	}
+	return ret
+}
+
+func simulateUsage(fs *flag.FlagSet) func() {
+	return func() {
+		fmt.Fprintf(os.Stderr,
+`%s simulate: play bots against each other.
+
+Usage:
+%s simulate [options] [bot] [bot] ...
+
+Each bot takes a seat at the table. The bots are:
+call              never folds and never raises
+random            folds, calls, or raises at random
+equity:[t]        raises the pot with at least t equity against random
+                  hands, and calls when its equity beats the pot odds.
+                  t is 0.6 if not given.
+
+Every hand starts with the same stacks, and the button moves round the table.
+The results are shown in big blinds won per 100 hands, with a 95%%
+confidence interval. The same seed always plays the same hands.
+
+Options:
+`, os.Args[0], os.Args[0])
+		fs.PrintDefaults()
+	}
+}
+
+func simulateMain(args []string) {
+	fs := flag.NewFlagSet("simulate", flag.ExitOnError)
+	fs.Usage = simulateUsage(fs)
+	var hands = fs.Int("n", 10000, "number of hands to play")
+	var seed = fs.Int64("s", 1, "random seed")
+	var sb = fs.Int64("sb", 1, "small blind")
+	var bb = fs.Int64("bb", 2, "big blind")
+	var ante = fs.Int64("ante", 0, "ante")
+	var stack = fs.Int64("stack", 200, "chips each player starts a hand with")
+	var numWorkers = fs.Int("g", 3, "number of goprocs")
+	fs.Parse(args)
+	if (fs.NArg() < 2) {
+		fs.Usage()
+		os.Exit(1)
+	}
+	var names []string
+	var players []Player
+	seen := make(map[string] int)
+	for i := 0; i < fs.NArg(); i++ {
+		p, err := NewBot(fs.Arg(i))
+		if (err != nil) {
+			die(err)
+		}
+		name := fs.Arg(i)
+		seen[name]++
+		if (seen[name] > 1) {
+			name = fmt.Sprintf("%s #%d", name, seen[name])
+		}
+		names = append(names, name)
+		players = append(players, p)
+	}
+	cfg := &SimConfig { GameConfig { SmallBlind: *sb, BigBlind: *bb,
+		Ante: *ante }, *stack, *hands, *seed, *numWorkers }
+	res, err := Simulate(cfg, names, players)
+	if (err != nil) {
+		die(err)
+	}
+	fmt.Printf("%s", res.String())
+}
@@ -0,0 +1,108 @@
+/*
+ * Copyright 2011 Colin Patrick McCabe
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, version 2.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package main
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func newTestBots(t *testing.T, strs ...string) []Player {
+	var ret []Player
+	for i := range(strs) {
+		p, err := NewBot(strs[i])
+		if (err != nil) {
+			t.Fatalf("%s", err.Error())
+		}
+		ret = append(ret, p)
+	}
+	return ret
+}
+
+func TestNewBot(t *testing.T) {
+	bots := []string { "call", "random", "equity", "equity:0.75" }
+	for i := range(bots) {
+		_, err := NewBot(bots[i])
+		if (err != nil) {
+			t.Errorf("unexpected error for '%s': %s", bots[i], err.Error())
+		}
+	}
+	bad := []string { "", "foo", "call:1", "equity:2", "equity:x" }
+	for i := range(bad) {
+		_, err := NewBot(bad[i])
+		if (err == nil) {
+			t.Errorf("expected an error for '%s'", bad[i])
+		}
+	}
+}
+
+func TestEquityBot(t *testing.T) {
+	bot := &EquityBot { Threshold: 0.6 }
+	rng := rand.New(rand.NewSource(1))
+	g := newTestGame(t, TEST_GAME_CFG, []int64 { 1000, 1000 }, 0,
+		"AS 2C AH 7D 3S KS 9C 4D 4H AD 5C 6C JH")
+	/* alice moves all-in, and bob has aces. */
+	act(t, g, ACTION_RAISE, 1000)
+	a := bot.Act(g.View(), rng)
+	if (a.Ty != ACTION_CALL) {
+		t.Errorf("expected aces to call an all-in, got %s", a)
+	}
+	g = newTestGame(t, TEST_GAME_CFG, []int64 { 1000, 1000 }, 0,
+		"2C AS 7D AH 3S KS 9C 4D 4H AD 5C 6C JH")
+	/* This time, bob has 72 offsuit. */
+	act(t, g, ACTION_RAISE, 1000)
+	a = bot.Act(g.View(), rng)
+	if (a.Ty != ACTION_FOLD) {
+		t.Errorf("expected 72 offsuit to fold to an all-in, got %s", a)
+	}
+}
+
+func TestSimulate(t *testing.T) {
+	names := []string { "call", "random", "equity" }
+	players := newTestBots(t, names...)
+	cfg := &SimConfig { GameConfig { SmallBlind: 1, BigBlind: 2 }, 200,
+		300, 1, 1 }
+	res1, err := Simulate(cfg, names, players)
+	if (err != nil) {
+		t.Fatalf("%s", err.Error())
+	}
+	/* Chips only move between the players. */
+	total := 0.0
+	for i := range(res1.Players) {
+		total += res1.Players[i].BbPer100
+		if (res1.Players[i].Confidence <= 0) {
+			t.Errorf("expected a confidence interval for %s",
+				res1.Players[i].Name)
+		}
+	}
+	if (math.Abs(total) > 1e-6) {
+		t.Errorf("expected the winnings to add up to 0, got %f", total)
+	}
+	/* The number of workers must not change the result. */
+	cfg.NumWorkers = 3
+	res3, err := Simulate(cfg, names, players)
+	if (err != nil) {
+		t.Fatalf("%s", err.Error())
+	}
+	for i := range(res1.Players) {
+		if (res1.Players[i] != res3.Players[i]) {
+			t.Errorf("expected the same result with 1 or 3 workers, got " +
+				"%v and %v", res1.Players[i], res3.Players[i])
+		}
+	}
+}
@@ -0,0 +1,173 @@
+/*
+ * Copyright 2011 Colin Patrick McCabe
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, version 2.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package main
+
+import (
+	"fmt"
+)
+
+/* One possible turn card, and everything that could follow it on the river.
+ */
+type TurnBranch struct {
+	Card *Card
+	Hand *Hand
+	Showdown int
+	River ResultSet
+	RiverShowdowns [SHOWDOWN_WIN + 1]int64
+}
+
+/* A StreetTree follows the hand from the flop through every turn card, and
+ * then through every river card that could follow it.
+ *
+ * Showdown results are only meaningful when we know the hole cards of at least
+ * one opponent.
+ */
+type StreetTree struct {
+	HasOpponents bool
+	Branches []*TurnBranch
+}
+
+func BuildStreetTree(sc *Scenario, deck *CardBag) (*StreetTree, error) {
+	if (len(sc.Board) != 3) {
+		return nil, fmt.Errorf("the street-by-street tree starts from the " +
+			"flop, but the board has %d cards.", len(sc.Board))
+	}
+	tree := new(StreetTree)
+	tree.HasOpponents = (len(sc.Opponents) > 0)
+	future := sc.Future(deck)
+	board := make(CardSlice, BOARD_MAX)
+	copy(board, sc.Board)
+	for t := 0; t < future.Len(); t++ {
+		br := new(TurnBranch)
+		br.Card = future.Get(uint(t))
+		board[3] = br.Card
+		br.Hand = bestHandWith(sc.Hole, board[:4])
+		br.Showdown = sc.showdownWith(br.Hand, board[:4])
+		for r := 0; r < future.Len(); r++ {
+			if (r == t) {
+				continue
+			}
+			board[4] = future.Get(uint(r))
+			h := bestHandWith(sc.Hole, board)
+			br.River.AddHand(h)
+			if (tree.HasOpponents) {
+				br.RiverShowdowns[sc.showdownWith(h, board)]++
+			}
+		}
+		tree.Branches = append(tree.Branches, br)
+	}
+	return tree, nil
+}
+
+/* Returns how likely we are to have each type of hand after the turn. */
+func (tree *StreetTree) TurnResults() *ResultSet {
+	res := new(ResultSet)
+	for i := range(tree.Branches) {
+		res.AddHand(tree.Branches[i].Hand)
+	}
+	return res
+}
+
+/* Returns how likely we are to be behind, tied, or ahead after the turn. */
+func (tree *StreetTree) TurnShowdowns() [SHOWDOWN_WIN + 1]float64 {
+	var ret [SHOWDOWN_WIN + 1]float64
+	for i := range(tree.Branches) {
+		ret[tree.Branches[i].Showdown] += 100.0 / float64(len(tree.Branches))
+	}
+	return ret
+}
+
+/* Returns how likely we are to make each type of hand by the river. */
+func (tree *StreetTree) RiverResults() *ResultSet {
+	res := new(ResultSet)
+	for i := range(tree.Branches) {
+		res.MergeResultSet(&tree.Branches[i].River)
+	}
+	return res
+}
+
+func handTyToShortStr(ty int) string {
+	switch (ty) {
+	case HIGH_CARD:
+		return "nothing"
+	case PAIR:
+		return "pair"
+	case TWO_PAIR:
+		return "2pair"
+	case THREE_OF_A_KIND:
+		return "trips"
+	case STRAIGHT:
+		return "straight"
+	case FLUSH:
+		return "flush"
+	case FULL_HOUSE:
+		return "boat"
+	case FOUR_OF_A_KIND:
+		return "quads"
+	case STRAIGHT_FLUSH:
+		return "sflush"
+	}
+	panic(fmt.Sprintf("unexpected hand type %d", ty))
+}
+
+func (tree *StreetTree) String() string {
+	ret := "after the turn:\n" + tree.TurnResults().String()
+	if (tree.HasOpponents) {
+		s := tree.TurnShowdowns()
+		ret += fmt.Sprintf("%03.2f%% ahead, %03.2f%% tied, %03.2f%% behind\n",
+			s[SHOWDOWN_WIN], s[SHOWDOWN_TIE], s[SHOWDOWN_LOSS])
+	}
+
+	// Only show the types of hand that can actually happen.
+	river := tree.RiverResults()
+	var tys []int
+	for ty := HIGH_CARD; ty < MAX_HANDS; ty++ {
+		if (river.handTyCnt[ty] > 0) {
+			tys = append(tys, ty)
+		}
+	}
+	ret += "by turn card:\n"
+	ret += fmt.Sprintf("%-5s %-7s %-9s", "turn", "prob", "now")
+	for i := range(tys) {
+		ret += fmt.Sprintf(" %8s", handTyToShortStr(tys[i]))
+	}
+	if (tree.HasOpponents) {
+		ret += fmt.Sprintf(" %8s %8s", "win", "tie")
+	}
+	ret += "\n"
+	for i := range(tree.Branches) {
+		br := tree.Branches[i]
+		now := handTyToShortStr(br.Hand.ty)
+		if (tree.HasOpponents) {
+			now += fmt.Sprintf("/%c", showdownToStr(br.Showdown)[0])
+		}
+		ret += fmt.Sprintf("%-5s %6.2f%% %-9s", br.Card.ShortString(),
+			100.0 / float64(len(tree.Branches)), now)
+		total := float64(br.River.Total())
+		for j := range(tys) {
+			ret += fmt.Sprintf(" %7.2f%%",
+				float64(br.River.handTyCnt[tys[j]]) * 100.0 / total)
+		}
+		if (tree.HasOpponents) {
+			ret += fmt.Sprintf(" %7.2f%% %7.2f%%",
+				float64(br.RiverShowdowns[SHOWDOWN_WIN]) * 100.0 / total,
+				float64(br.RiverShowdowns[SHOWDOWN_TIE]) * 100.0 / total)
+		}
+		ret += "\n"
+	}
+	return ret
+}
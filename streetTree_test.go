@@ -0,0 +1,55 @@
+/*
+ * Copyright 2011 Colin Patrick McCabe
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, version 2.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package main
+
+import (
+	"context"
+	"testing"
+)
+
+func TestStreetTree1(t *testing.T) {
+	sc := makeScenario(t, &OddsRequest { Hole: "KS QS", Board: "AS 3S 5D" })
+	deck := Make52CardBag()
+	tree, err := BuildStreetTree(sc, deck)
+	if (err != nil) {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	if (len(tree.Branches) != 47) {
+		t.Errorf("expected 47 turn cards, got %d", len(tree.Branches))
+	}
+
+	// Every final board shows up twice in the tree: once for each order in
+	// which its last two cards could be dealt.
+	exact, err := CalcOddsSerial(context.Background(), sc, deck,
+		NewCardSliceProcessor(nil))
+	if (err != nil) {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	river := tree.RiverResults()
+	for ty := HIGH_CARD; ty < MAX_HANDS; ty++ {
+		if (river.handTyCnt[ty] != 2 * exact.handTyCnt[ty]) {
+			t.Errorf("expected %d %s boards, got %d", 2 * exact.handTyCnt[ty],
+				HandTyToStr(ty), river.handTyCnt[ty])
+		}
+	}
+
+	sc = makeScenario(t, &OddsRequest { Hole: "KS QS", Board: "AS 3S 5D 7C" })
+	_, err = BuildStreetTree(sc, deck)
+	if (err == nil) {
+		t.Errorf("expected an error when the turn has already been dealt")
+	}
+}
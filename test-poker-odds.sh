@@ -20,9 +20,10 @@ trap cleanup INT TERM EXIT
 
 "${poker_odds}" -a "KS QS" -b "AS 3S 5S" > "${tmp}"
 cat << EOF >  "${tmp2}"
+your hand: a flush
 results:
-99.91% chance of a flush
-0.09% chance of a straight flush
+99.81% chance of a flush
+0.19% chance of a straight flush
 EOF
 diff "${tmp2}" "${tmp}" || die "unexpected result from test 1"
 
@@ -31,27 +32,48 @@ grep -q "100.00% chance of a straight flush" "${tmp}" || die "example 2 failed"
 
 "${poker_odds}" -a 'KC JC' -b '2S 3S 4S 5S' > "${tmp}"
 cat << EOF >  "${tmp2}"
+your hand: two overcards
 results:
-39.13% chance of nothing
+32.61% chance of nothing
 34.78% chance of a pair
-6.52% chance of a straight
-17.39% chance of a flush
-2.17% chance of a straight flush
+13.04% chance of a straight
+15.22% chance of a flush
+4.35% chance of a straight flush
 EOF
 diff "${tmp2}" "${tmp}" || die "unexpected result from test 3"
 
 "${poker_odds}" -b 'KD KC 5H' -a 'KS QS' > "${tmp}"
 cat << EOF >  "${tmp2}"
+your hand: trips, good kicker
 results:
-95.74% chance of three of a kind
+66.60% chance of three of a kind
+29.14% chance of a full house
 4.26% chance of four of a kind
 EOF
 diff "${tmp2}" "${tmp}" || die "unexpected result from test 4"
 
 "${poker_odds}" -b "AS 7D 3D 4D" -a "KS QS" > "${tmp}"
 cat << EOF >  "${tmp2}"
+your hand: nothing
 results:
 60.87% chance of nothing
 39.13% chance of a pair
 EOF
 diff "${tmp2}" "${tmp}" || die "unexpected result from test 5"
+
+"${poker_odds}" -a 'KC JC' -b '2S 3S 4S 5S' -pot 100 -bet 50 \
+	-draw straight > "${tmp}"
+cat << EOF >  "${tmp2}"
+your hand: two overcards
+results:
+32.61% chance of nothing
+34.78% chance of a pair
+13.04% chance of a straight
+15.22% chance of a flush
+4.35% chance of a straight flush
+pot odds:
+calling 50 to win 150 needs 25.00% equity
+chance of making a straight or better: 32.61%
+calling is worth 15.22 more than folding
+EOF
+diff "${tmp2}" "${tmp}" || die "unexpected result from test 6"
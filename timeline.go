@@ -0,0 +1,315 @@
+/*
+ * Copyright 2011 Colin Patrick McCabe
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, version 2.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"math"
+	"os"
+)
+
+/* One moment in a hand: the board so far, and who is still in. Holes[i] is
+ * nil if we don't know player i's cards, in which case they are treated as
+ * holding a random hand.
+ */
+type HandState struct {
+	Board CardSlice
+	Holes []CardSlice
+	InHand []bool
+}
+
+/* Work out the equity of every player whose cards we know, at each of a
+ * sequence of moments in a hand. Returns equities[state][player], which is
+ * NaN for players who aren't in the hand or whose cards we don't know.
+ */
+func CalcEquityTimeline(states []HandState, samples int, seed int64,
+		deck *CardBag) ([][]float64, error) {
+	var ret [][]float64
+	for s := range(states) {
+		st := &states[s]
+		eqs := make([]float64, len(st.Holes))
+		numRandom := 0
+		for i := range(st.Holes) {
+			eqs[i] = math.NaN()
+			if ((st.InHand[i]) && (st.Holes[i] == nil)) {
+				numRandom++
+			}
+		}
+		for i := range(st.Holes) {
+			if ((!st.InHand[i]) || (st.Holes[i] == nil)) {
+				continue
+			}
+			sc := &Scenario { Game: GAME_HOLDEM, Hole: st.Holes[i],
+				Board: st.Board, Samples: samples, Seed: seed }
+			for j := range(st.Holes) {
+				if ((j != i) && (st.InHand[j]) && (st.Holes[j] != nil)) {
+					sc.Opponents = append(sc.Opponents, st.Holes[j])
+				}
+			}
+			err := sc.Validate()
+			if (err != nil) {
+				return nil, err
+			}
+			eq, err := CalcEquity(sc, numRandom, deck)
+			if (err != nil) {
+				return nil, err
+			}
+			eqs[i] = eq.Value()
+		}
+		ret = append(ret, eqs)
+	}
+	return ret, nil
+}
+
+/* How each player's equity went over the streets of a hand, ready to be
+ * written as JSON. Equity has one entry per street, which is null once the
+ * player has folded, and for players whose cards we never saw.
+ */
+type TimelinePlayer struct {
+	Name string `json:"name"`
+	Hole string `json:"hole"`
+	Equity []*float64 `json:"equity"`
+}
+
+type HandTimeline struct {
+	Hand string `json:"hand"`
+	Streets []string `json:"streets"`
+	Boards []string `json:"boards"`
+	Players []*TimelinePlayer `json:"players"`
+}
+
+/* Returns the street each player folded on, or -1 if they didn't fold. */
+func (hh *HandHistory) foldStreets() []int {
+	ret := make([]int, len(hh.Players))
+	for i := range(hh.Players) {
+		ret[i] = -1
+		for j := range(hh.Actions) {
+			a := &hh.Actions[j]
+			if ((a.Player == hh.Players[i].Name) && (a.Verb == "folds")) {
+				ret[i] = a.Street
+			}
+		}
+	}
+	return ret
+}
+
+/* Returns whether each player was dealt in. Everyone dealt in posts or acts
+ * before the hand ends, so a player we never hear from sat the hand out.
+ */
+func (hh *HandHistory) dealtIn() []bool {
+	ret := make([]bool, len(hh.Players))
+	for i := range(hh.Players) {
+		for j := range(hh.Actions) {
+			if (hh.Actions[j].Player == hh.Players[i].Name) {
+				ret[i] = true
+				break
+			}
+		}
+	}
+	return ret
+}
+
+/* Returns the states of a hand at the start of each street that was dealt,
+ * before anyone acted on it.
+ */
+func (hh *HandHistory) streetStates() ([]string, []HandState) {
+	folds := hh.foldStreets()
+	dealt := hh.dealtIn()
+	var names []string
+	var states []HandState
+	numBoard := []int { 0, 3, 4, 5 }
+	for s := STREET_PREFLOP; s <= STREET_RIVER; s++ {
+		if (len(hh.Board) < numBoard[s]) {
+			break
+		}
+		st := HandState { Board: hh.Board[:numBoard[s]] }
+		numIn := 0
+		for i := range(hh.Players) {
+			in := (dealt[i] && ((folds[i] == -1) || (folds[i] >= s)))
+			st.Holes = append(st.Holes, hh.Players[i].Hole)
+			st.InHand = append(st.InHand, in)
+			if (in) {
+				numIn++
+			}
+		}
+		if (numIn < 2) {
+			break
+		}
+		names = append(names, StreetToStr(s))
+		states = append(states, st)
+	}
+	return names, states
+}
+
+/* Work out the equity timeline for a hand. Returns nil if we don't know
+ * anybody's hole cards.
+ */
+func NewHandTimeline(hh *HandHistory, samples int, seed int64,
+		deck *CardBag) (*HandTimeline, error) {
+	known := false
+	for i := range(hh.Players) {
+		if (hh.Players[i].Hole != nil) {
+			known = true
+		}
+	}
+	if (!known) {
+		return nil, nil
+	}
+	names, states := hh.streetStates()
+	eqs, err := CalcEquityTimeline(states, samples, seed, deck)
+	if (err != nil) {
+		return nil, fmt.Errorf("hand #%s: %s", hh.Id, err.Error())
+	}
+	tl := &HandTimeline { Hand: hh.Id, Streets: names }
+	for s := range(states) {
+		tl.Boards = append(tl.Boards, states[s].Board.ShortString())
+	}
+	for i := range(hh.Players) {
+		p := &TimelinePlayer { Name: hh.Players[i].Name,
+			Hole: hh.Players[i].Hole.ShortString() }
+		for s := range(eqs) {
+			var e *float64
+			if (!math.IsNaN(eqs[s][i])) {
+				e = new(float64)
+				*e = eqs[s][i]
+			}
+			p.Equity = append(p.Equity, e)
+		}
+		tl.Players = append(tl.Players, p)
+	}
+	return tl, nil
+}
+
+/* Returns the street where the player's equity changed the most, and by how
+ * much, or -1 if it never changed.
+ */
+func (p *TimelinePlayer) biggestSwing() (int, float64) {
+	best := -1
+	swing := 0.0
+	for s := 1; s < len(p.Equity); s++ {
+		if ((p.Equity[s] == nil) || (p.Equity[s - 1] == nil)) {
+			continue
+		}
+		d := *p.Equity[s] - *p.Equity[s - 1]
+		if (math.Abs(d) > math.Abs(swing)) {
+			best = s
+			swing = d
+		}
+	}
+	return best, swing
+}
+
+func (tl *HandTimeline) String() string {
+	ret := fmt.Sprintf("hand #%s\n", tl.Hand)
+	ret += fmt.Sprintf("%-16s %-6s", "player", "hole")
+	for s := range(tl.Streets) {
+		ret += fmt.Sprintf(" %9s", tl.Streets[s])
+	}
+	ret += "  biggest swing\n"
+	for i := range(tl.Players) {
+		p := tl.Players[i]
+		if (p.Hole == "") {
+			continue
+		}
+		ret += fmt.Sprintf("%-16s %-6s", p.Name, p.Hole)
+		for s := range(p.Equity) {
+			if (p.Equity[s] == nil) {
+				ret += fmt.Sprintf(" %9s", "-")
+			} else {
+				ret += fmt.Sprintf(" %8.2f%%", *p.Equity[s] * 100.0)
+			}
+		}
+		s, swing := p.biggestSwing()
+		if (s != -1) {
+			ret += fmt.Sprintf("  %+.2f%% on the %s", swing * 100.0,
+				tl.Streets[s])
+		}
+		ret += "\n"
+	}
+	if (len(tl.Boards) > 1) {
+		ret += fmt.Sprintf("board: %s\n", tl.Boards[len(tl.Boards) - 1])
+	}
+	return ret
+}
+
+func timelineUsage(fs *flag.FlagSet) func() {
+	return func() {
+		fmt.Fprintf(os.Stderr,
+`%s timeline: show how equity changed street by street in each hand.
+
+Usage:
+%s timeline [options] [file] [file] ...
+
+Reads hand histories written by PokerStars. For every hand where we know
+someone's hole cards, their equity is worked out at the start of each street
+that was dealt. Players still in the hand whose cards we never saw are treated
+as holding random hands. The output is a table for each hand, or with -json,
+a list of timelines that can be plotted.
+
+Options:
+`, os.Args[0], os.Args[0])
+		fs.PrintDefaults()
+	}
+}
+
+func timelineMain(args []string) {
+	fs := flag.NewFlagSet("timeline", flag.ExitOnError)
+	fs.Usage = timelineUsage(fs)
+	var asJson = fs.Bool("json", false, "write the timelines as JSON")
+	var handId = fs.String("hand", "", "only show the hand with this number")
+	var samples = fs.Int("m", 0, "number of Monte Carlo samples")
+	var seed = fs.Int64("s", 1, "Monte Carlo random seed")
+	fs.Parse(args)
+	if (fs.NArg() < 1) {
+		fs.Usage()
+		os.Exit(1)
+	}
+	hands, err := loadHandHistories(fs.Args())
+	if (err != nil) {
+		die(err)
+	}
+	deck := Make52CardBag()
+	timelines := []*HandTimeline {}
+	for i := range(hands) {
+		if ((*handId != "") && (hands[i].Id != *handId)) {
+			continue
+		}
+		tl, err := NewHandTimeline(hands[i], *samples, *seed, deck)
+		if (err != nil) {
+			die(err)
+		}
+		if (tl != nil) {
+			timelines = append(timelines, tl)
+		}
+	}
+	if (*asJson) {
+		buf, err := json.MarshalIndent(timelines, "", "  ")
+		if (err != nil) {
+			die(err)
+		}
+		fmt.Printf("%s\n", buf)
+		return
+	}
+	for i := range(timelines) {
+		if (i > 0) {
+			fmt.Printf("\n")
+		}
+		fmt.Printf("%s", timelines[i].String())
+	}
+}
@@ -0,0 +1,92 @@
+/*
+ * Copyright 2011 Colin Patrick McCabe
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, version 2.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestHandTimeline(t *testing.T) {
+	hands := parseTestHistories(t)
+	deck := Make52CardBag()
+	tl, err := NewHandTimeline(hands[0], 2000, 1, deck)
+	if ((err != nil) || (tl == nil)) {
+		t.Fatalf("expected a timeline, got %v", err)
+	}
+	if (len(tl.Streets) != 4) {
+		t.Fatalf("expected 4 streets, got %v", tl.Streets)
+	}
+	alice := tl.Players[0]
+	bob := tl.Players[1]
+	carol := tl.Players[2]
+	for s := range(carol.Equity) {
+		if (carol.Equity[s] != nil) {
+			t.Errorf("expected no equity for carol, whose cards we never saw")
+		}
+	}
+	/* From the flop on, alice and bob are the only players left. */
+	for s := 1; s < 4; s++ {
+		if ((alice.Equity[s] == nil) || (bob.Equity[s] == nil)) {
+			t.Fatalf("expected equities for alice and bob on the %s",
+				tl.Streets[s])
+		}
+		if (math.Abs(*alice.Equity[s] + *bob.Equity[s] - 1.0) > 1e-6) {
+			t.Errorf("expected equities on the %s to add up to 1, got %f " +
+				"and %f", tl.Streets[s], *alice.Equity[s], *bob.Equity[s])
+		}
+	}
+	if (*bob.Equity[3] != 1.0) {
+		t.Errorf("expected bob to win on the river, got %f", *bob.Equity[3])
+	}
+	s, swing := alice.biggestSwing()
+	if ((s != STREET_FLOP) || (swing > -0.2)) {
+		t.Errorf("expected alice's biggest swing to be the flop, got %d " +
+			"(%f)", s, swing)
+	}
+
+	/* Only alice's cards are known, and she folds on the flop. */
+	tl, err = NewHandTimeline(hands[1], 2000, 1, deck)
+	if ((err != nil) || (tl == nil)) {
+		t.Fatalf("expected a timeline, got %v", err)
+	}
+	if ((len(tl.Streets) != 2) || (tl.Boards[1] != "QS 8H 4C")) {
+		t.Errorf("expected the preflop and flop, got %v", tl.Boards)
+	}
+	if ((tl.Players[0].Equity[0] == nil) || (tl.Players[0].Equity[1] == nil)) {
+		t.Errorf("expected equities for alice against random hands")
+	}
+
+	/* A seat that never posts or acts wasn't dealt in, and mustn't count
+	 * as another random opponent. */
+	hh := hands[0]
+	hh.Players = append(hh.Players, &HHPlayer { Seat: 4, Name: "dave",
+		Stack: 200 })
+	tl, err = NewHandTimeline(hh, 2000, 1, deck)
+	if ((err != nil) || (tl == nil)) {
+		t.Fatalf("expected a timeline, got %v", err)
+	}
+	alice = tl.Players[0]
+	bob = tl.Players[1]
+	if (math.Abs(*alice.Equity[1] + *bob.Equity[1] - 1.0) > 1e-6) {
+		t.Errorf("expected equities on the flop to add up to 1 with dave " +
+			"sitting out, got %f and %f", *alice.Equity[1], *bob.Equity[1])
+	}
+	if (tl.Players[3].Equity[0] != nil) {
+		t.Errorf("expected no equity for dave, who wasn't dealt in")
+	}
+}